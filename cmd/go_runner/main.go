@@ -11,10 +11,23 @@ import (
 	"time"
 
 	"go_runner/internal/api"
+	"go_runner/internal/apikeys"
+	"go_runner/internal/artifacts"
+	"go_runner/internal/audit"
 	"go_runner/internal/config"
+	"go_runner/internal/credentials"
 	"go_runner/internal/executor"
+	"go_runner/internal/gocache"
+	"go_runner/internal/oidc"
+	"go_runner/internal/provenance"
+	"go_runner/internal/ratelimit"
 	"go_runner/internal/repository"
+	"go_runner/internal/sessions"
 	"go_runner/internal/storage"
+	"go_runner/internal/toolchain"
+	"go_runner/internal/users"
+	"go_runner/internal/vulndb"
+	"go_runner/internal/watcher"
 )
 
 func main() {
@@ -39,18 +52,143 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Initialize the artifact store that builds install into and executions run from
+	artifactStore := artifacts.NewLocalStore(cfg.Storage.BinaryPath)
+	if err := artifactStore.Init(); err != nil {
+		logger.Error("Failed to initialize artifact store", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Initialize credential store for private repositories
+	credStore, err := credentials.NewFileStore(cfg.Storage.CredentialsPath, cfg.Storage.CredentialsKey)
+	if err != nil {
+		logger.Error("Failed to initialize credential store", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// Initialize the shared Go module and build cache
+	buildCache := gocache.NewCache(cfg.Cache)
+	if err := buildCache.Init(); err != nil {
+		logger.Error("Failed to initialize build cache", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	// Initialize services
-	gitManager := repository.NewGitManager(cfg.Storage.RepoPath)
-	binaryExecutor := executor.NewExecutor(cfg.Storage.BinaryPath, cfg.Executor)
+	toolchains := toolchain.NewManager(cfg.Toolchain)
+	builderOpts := []repository.Option{
+		repository.WithToolchains(toolchains),
+		repository.WithBuildCache(buildCache),
+		repository.WithGitTimeout(cfg.Git.Timeout),
+	}
+	var gitManager api.GitManager
+	if cfg.Git.Backend == "go-git" {
+		gitManager = repository.NewGoGitManager(cfg.Storage.RepoPath, builderOpts...)
+	} else {
+		gitManager = repository.NewGitManager(cfg.Storage.RepoPath, builderOpts...)
+	}
+	binaryExecutor := executor.NewExecutor(artifactStore, cfg.Executor)
+
+	userStore, err := users.NewFileStore(cfg.Storage.UsersPath)
+	if err != nil {
+		logger.Error("Failed to initialize user store", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	sessionStore, err := sessions.NewFileStore(cfg.Storage.SessionsPath, cfg.Auth.SessionIdle, cfg.Auth.SessionMaxAge)
+	if err != nil {
+		logger.Error("Failed to initialize session store", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	auditLog, err := audit.Open(cfg.Storage.AuditPath)
+	if err != nil {
+		logger.Error("Failed to open audit log", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	defer auditLog.Close()
+
+	quotaStore, err := ratelimit.NewQuotaStore(cfg.Storage.QuotasPath)
+	if err != nil {
+		logger.Error("Failed to initialize quota store", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
 	// Initialize API server
-	apiServer := api.NewServer(cfg.Server, store, gitManager, binaryExecutor)
+	serverOpts := []api.Option{
+		api.WithAdminToken(cfg.Auth.AdminToken),
+		api.WithUsers(userStore),
+		api.WithSessions(sessionStore),
+		api.WithAuditLog(auditLog),
+		api.WithRateLimits(
+			ratelimit.NewLimiter(cfg.RateLimit.KeyPerMinute, cfg.RateLimit.KeyBurst),
+			ratelimit.NewLimiter(cfg.RateLimit.IPPerMinute, cfg.RateLimit.IPBurst),
+		),
+		api.WithBinaryRateLimit(ratelimit.NewLimiter(cfg.RateLimit.BinaryPerMinute, cfg.RateLimit.BinaryBurst)),
+		api.WithQuotas(quotaStore, cfg.RateLimit.Quota),
+		api.WithArtifactStore(artifactStore),
+		api.WithBuildCache(buildCache),
+		api.WithCredentials(credStore),
+		api.WithWebhookSecret(cfg.Webhook.Secret),
+		api.WithMaxUploadSize(int64(cfg.Upload.MaxSizeMB) << 20),
+		api.WithRepoSchemes(cfg.Git.Schemes...),
+	}
+	if cfg.Auth.QueryToken {
+		logger.Warn("Accepting the admin token in query strings; it will appear in access logs")
+		serverOpts = append(serverOpts, api.WithQueryToken())
+	}
+	if cfg.OIDC.Issuer != "" {
+		provider, err := oidc.NewProvider(cfg.OIDC)
+		if err != nil {
+			logger.Error("Failed to configure single sign-on", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("Single sign-on enabled", slog.String("issuer", cfg.OIDC.Issuer))
+		serverOpts = append(serverOpts, api.WithOIDC(provider))
+	}
+	if cfg.Auth.APIKeys {
+		keyStore, err := apikeys.NewFileStore(cfg.Storage.APIKeysPath)
+		if err != nil {
+			logger.Error("Failed to initialize API key store", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		serverOpts = append(serverOpts, api.WithAPIKeys(keyStore))
+	} else {
+		logger.Warn("API keys are disabled; execution requires signing in")
+	}
+	if cfg.Signing.KeyPath != "" {
+		signer, err := provenance.LoadOrCreateSigner(cfg.Signing.KeyPath)
+		if err != nil {
+			logger.Error("Failed to load signing key", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("Signing build provenance", slog.String("key_id", signer.KeyID()))
+		serverOpts = append(serverOpts, api.WithSigner(signer))
+	}
+	if cfg.Vuln.DBPath != "" {
+		db, err := vulndb.Open(cfg.Vuln.DBPath)
+		if err != nil {
+			logger.Error("Failed to open vulnerability database", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("Checking builds for known vulnerabilities",
+			slog.Time("database_modified", db.Modified()),
+			slog.Bool("block", cfg.Vuln.Block))
+		serverOpts = append(serverOpts, api.WithVulnDB(db, cfg.Vuln.Block))
+	}
+	apiServer := api.NewServer(cfg.Server, store, gitManager, binaryExecutor, serverOpts...)
+
+	// Poll upstream branches of binaries with auto_build enabled
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	branchWatcher := watcher.NewWatcher(cfg.Watcher, store, gitManager, credStore, apiServer.EnqueueBuild)
+	go branchWatcher.Run(watchCtx)
 
 	// Start server in goroutine
 	go func() {
 		logger.Info("Starting server",
 			slog.String("host", cfg.Server.Host),
-			slog.Int("port", cfg.Server.Port))
+			slog.Int("port", cfg.Server.Port),
+			slog.Bool("tls", cfg.Server.TLS.Enabled()),
+			slog.Bool("client_certificates", cfg.Server.TLS.ClientCAFile != ""))
 
 		if err := apiServer.Start(); err != nil && err != http.ErrServerClosed {
 			logger.Error("Server failed to start", slog.String("error", err.Error()))
@@ -64,6 +202,7 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	stopWatching()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -0,0 +1,178 @@
+// internal/api/apikeys.go
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go_runner/internal/apikeys"
+	"go_runner/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxRotationGrace bounds how long a rotated key keeps working
+const maxRotationGrace = 7 * 24 * time.Hour
+
+type apiKeyRequest struct {
+	Name      string        `json:"name"`
+	Team      string        `json:"team,omitempty"`
+	Scopes    []string      `json:"scopes,omitempty"`
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"`
+	Quota     *models.Quota `json:"quota,omitempty"`
+}
+
+type rotateAPIKeyRequest struct {
+	GracePeriod int `json:"grace_period,omitempty"` // seconds the previous key stays valid
+}
+
+// issuedAPIKey is returned once, when a key is created or rotated
+type issuedAPIKey struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+// listAPIKeysHandler returns all API keys without their hashes
+func (s *Server) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		s.respondError(w, http.StatusNotFound, "API keys are disabled")
+		return
+	}
+
+	keys, err := s.apiKeys.List()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to list API keys")
+		return
+	}
+
+	redacted := make([]*models.APIKey, 0, len(keys))
+	for _, key := range keys {
+		redacted = append(redacted, key.Redacted())
+	}
+	s.respondJSON(w, http.StatusOK, redacted)
+}
+
+// createAPIKeyHandler issues a new API key. The key is only returned here.
+func (s *Server) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		s.respondError(w, http.StatusNotFound, "API keys are disabled")
+		return
+	}
+
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		s.respondError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		s.respondError(w, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+	if err := models.ValidateScopes(req.Scopes); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid scopes: "+err.Error())
+		return
+	}
+	if err := req.Quota.Validate(); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid quota: "+err.Error())
+		return
+	}
+
+	key, token, err := s.apiKeys.Create(&models.APIKey{
+		Name:      req.Name,
+		Team:      req.Team,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		Quota:     req.Quota,
+	})
+	if err != nil {
+		slog.Error("Failed to create API key", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	auditFromContext(r.Context()).setTarget(key.ID)
+
+	s.respondJSON(w, http.StatusCreated, issuedAPIKey{APIKey: key.Redacted(), Key: token})
+}
+
+// getAPIKeyHandler returns an API key without its hash
+func (s *Server) getAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		s.respondError(w, http.StatusNotFound, "API keys are disabled")
+		return
+	}
+
+	key, err := s.apiKeys.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, key.Redacted())
+}
+
+// revokeAPIKeyHandler permanently disables an API key
+func (s *Server) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		s.respondError(w, http.StatusNotFound, "API keys are disabled")
+		return
+	}
+
+	key, err := s.apiKeys.Revoke(chi.URLParam(r, "id"))
+	if errors.Is(err, apikeys.ErrKeyNotFound) {
+		s.respondError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to revoke API key", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, key.Redacted())
+}
+
+// rotateAPIKeyHandler replaces the secret of an API key, optionally keeping
+// the previous one valid for a grace period
+func (s *Server) rotateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		s.respondError(w, http.StatusNotFound, "API keys are disabled")
+		return
+	}
+
+	var req rotateAPIKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	grace := time.Duration(req.GracePeriod) * time.Second
+	if grace < 0 || grace > maxRotationGrace {
+		s.respondError(w, http.StatusBadRequest, "grace_period must be between 0 and 604800 seconds")
+		return
+	}
+
+	key, token, err := s.apiKeys.Rotate(chi.URLParam(r, "id"), grace)
+	switch {
+	case errors.Is(err, apikeys.ErrKeyNotFound):
+		s.respondError(w, http.StatusNotFound, "API key not found")
+		return
+	case errors.Is(err, apikeys.ErrKeyRevoked):
+		s.respondError(w, http.StatusConflict, "API key has been revoked")
+		return
+	case err != nil:
+		slog.Error("Failed to rotate API key", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to rotate API key")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, issuedAPIKey{APIKey: key.Redacted(), Key: token})
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/apikeys"
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+)
+
+func TestAPIKeyHandlers_Lifecycle(t *testing.T) {
+	mockStorage := new(MockStorage)
+	store, err := apikeys.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil, WithAPIKeys(store))
+	adminSession := loginAdmin(t, server)
+
+	admin := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		adminSession.authorize(req)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	execute := func(token string) int {
+		req, _ := http.NewRequest("GET", "/api/v1/execute/exec1", nil)
+		req.Header.Set("X-API-Key", token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	rr := admin("POST", "/api/v1/apikeys", map[string]string{})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = admin("POST", "/api/v1/apikeys", map[string]string{"name": "ci"})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var issued struct {
+		models.APIKey
+		Key string `json:"key"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &issued))
+	assert.NotEmpty(t, issued.Key)
+	assert.Empty(t, issued.Hash)
+	assert.Empty(t, issued.Salt)
+	mockStorage.On("GetExecution", "exec1").Return(&models.ExecutionResult{ID: "exec1", APIKeyID: issued.ID}, nil)
+
+	assert.Equal(t, http.StatusOK, execute(issued.Key))
+	assert.Equal(t, http.StatusUnauthorized, execute("not-a-key"))
+	assert.Equal(t, http.StatusUnauthorized, execute(""))
+
+	// Listing shows last use but never the secret or its hash
+	rr = admin("GET", "/api/v1/apikeys", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), issued.Key)
+	assert.NotContains(t, rr.Body.String(), `"hash"`)
+	var listed []models.APIKey
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.NotNil(t, listed[0].LastUsedAt)
+
+	// Rotation without a grace period retires the old key at once
+	rr = admin("POST", "/api/v1/apikeys/"+issued.ID+"/rotate", nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var rotated struct {
+		Key string `json:"key"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rotated))
+	assert.Equal(t, http.StatusUnauthorized, execute(issued.Key))
+	assert.Equal(t, http.StatusOK, execute(rotated.Key))
+
+	rr = admin("POST", "/api/v1/apikeys/"+issued.ID+"/rotate", map[string]int{"grace_period": -1})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = admin("DELETE", "/api/v1/apikeys/"+issued.ID, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, http.StatusUnauthorized, execute(rotated.Key))
+
+	rr = admin("POST", "/api/v1/apikeys/"+issued.ID+"/rotate", nil)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	rr = admin("DELETE", "/api/v1/apikeys/missing", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Key management itself needs the admin token
+	req, _ := http.NewRequest("GET", "/api/v1/apikeys", nil)
+	req.Header.Set("X-API-Key", rotated.Key)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestAPIKeyMiddleware_Disabled(t *testing.T) {
+	mockStorage := new(MockStorage)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
+	adminSession := loginAdmin(t, server)
+	mockStorage.On("GetExecution", "exec1").Return(&models.ExecutionResult{ID: "exec1"}, nil)
+
+	// Any X-API-Key used to be accepted; now the admin token is required
+	req, _ := http.NewRequest("GET", "/api/v1/execute/exec1", nil)
+	req.Header.Set("X-API-Key", "anything")
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req, _ = http.NewRequest("GET", "/api/v1/execute/exec1", nil)
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	req, _ = http.NewRequest("GET", "/api/v1/apikeys", nil)
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockExecutor := new(MockExecutor)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, mockExecutor)
+
+	nightly, nightlyToken := issueTestAPIKey(t, server, &models.APIKey{Name: "nightly", Team: "a", Scopes: []string{"execute:tag=nightly", "read:executions"}})
+	_, teammateToken := issueTestAPIKey(t, server, &models.APIKey{Name: "teammate", Team: "a", Scopes: []string{"read:executions"}})
+	_, otherToken := issueTestAPIKey(t, server, &models.APIKey{Name: "other", Team: "b", Scopes: []string{"read:executions"}})
+	_, builderToken := issueTestAPIKey(t, server, &models.APIKey{Name: "builder", Scopes: []string{"execute:b2", "build:b2"}})
+
+	do := func(method, path, token string, body interface{}) int {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("X-API-Key", token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	labelled := &models.Binary{ID: "b1", Labels: []string{"nightly"}, Status: "ready", SHA256: "digest"}
+	prebuilt := &models.Binary{ID: "b2", SourceType: models.SourcePrebuilt, Status: "ready", SHA256: "digest"}
+	mockStorage.On("GetBinary", "b1").Return(labelled, nil)
+	mockStorage.On("GetBinary", "b2").Return(prebuilt, nil)
+
+	// Executions are attributed to the key and its team
+	mockExecutor.On("Execute", mock.Anything, "digest", mock.Anything, mock.Anything).
+		Return(&models.ExecutionResult{ID: "exec1", Status: "completed"}, nil).Once()
+	mockStorage.On("SaveExecution", mock.MatchedBy(func(r *models.ExecutionResult) bool {
+		return r.APIKeyID == nightly.ID && r.Team == "a"
+	})).Return(nil).Once()
+	assert.Equal(t, http.StatusOK, do("POST", "/api/v1/execute", nightlyToken, models.ExecutionRequest{BinaryID: "b1"}))
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/v1/execute", nightlyToken, models.ExecutionRequest{BinaryID: "b2"}))
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/v1/execute", builderToken, models.ExecutionRequest{BinaryID: "b1"}))
+
+	// Only the key's team sees the execution, and only with read:executions
+	mockStorage.On("GetExecution", "exec1").Return(&models.ExecutionResult{ID: "exec1", APIKeyID: nightly.ID, Team: "a"}, nil)
+	assert.Equal(t, http.StatusOK, do("GET", "/api/v1/execute/exec1", nightlyToken, nil))
+	assert.Equal(t, http.StatusOK, do("GET", "/api/v1/execute/exec1", teammateToken, nil))
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/v1/execute/exec1", otherToken, nil))
+	assert.Equal(t, http.StatusForbidden, do("GET", "/api/v1/execute/exec1", builderToken, nil))
+
+	// The same goes for stopping a running execution
+	server.executions["run1"] = nightly
+	mockExecutor.On("StopExecution", "run1").Return(nil).Once()
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/api/v1/execute/run1", otherToken, nil))
+	assert.Equal(t, http.StatusOK, do("DELETE", "/api/v1/execute/run1", teammateToken, nil))
+
+	// build:<id> opens the build endpoint of that binary only; prebuilt
+	// binaries answer 409 once the request gets through
+	assert.Equal(t, http.StatusConflict, do("POST", "/api/v1/binaries/b2/build", builderToken, nil))
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/v1/binaries/b1/build", builderToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/api/v1/binaries/b2/build", "", nil))
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/api/v1/binaries/b2", builderToken, nil))
+
+	mockExecutor.AssertExpectations(t)
+	mockStorage.AssertExpectations(t)
+}
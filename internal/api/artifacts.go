@@ -0,0 +1,86 @@
+// internal/api/artifacts.go
+package api
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"go_runner/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// listArtifactsHandler returns the downloadable artifacts of a binary's active version
+func (s *Server) listArtifactsHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	binary, err := s.storage.GetBinary(id)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Binary not found")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, binary.Artifacts)
+}
+
+// artifactPath is where an artifact of binary lives in the artifact store.
+// Artifacts recorded before build IDs were stored belong to the last build.
+func (s *Server) artifactPath(binary *models.Binary, artifact models.Artifact) string {
+	buildID := artifact.BuildID
+	if buildID == "" && binary.LastBuild != nil {
+		buildID = binary.LastBuild.ID
+	}
+	if buildID == "" {
+		return ""
+	}
+	return filepath.Join(s.artifacts.ArtifactDir(binary.ID, buildID), artifact.Target.String())
+}
+
+// downloadArtifactHandler streams a cross-compiled artifact
+func (s *Server) downloadArtifactHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	goos := chi.URLParam(r, "goos")
+	goarch := chi.URLParam(r, "goarch")
+
+	binary, err := s.storage.GetBinary(id)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Binary not found")
+		return
+	}
+
+	for _, artifact := range binary.Artifacts {
+		if artifact.GOOS != goos || artifact.GOARCH != goarch {
+			continue
+		}
+
+		f, err := os.Open(s.artifactPath(binary, artifact))
+		if err != nil {
+			s.respondError(w, http.StatusNotFound, "Artifact file missing")
+			return
+		}
+		defer f.Close()
+
+		stat, err := f.Stat()
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, "Failed to read artifact")
+			return
+		}
+
+		filename := fmt.Sprintf("%s-%s", binary.Name, artifact.Target)
+		if goos == "windows" {
+			filename += ".exe"
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		w.Header().Set("X-Checksum-Sha256", artifact.SHA256)
+		w.Header().Set("ETag", `"`+artifact.SHA256+`"`)
+		http.ServeContent(w, r, filename, stat.ModTime(), f)
+		return
+	}
+
+	s.respondError(w, http.StatusNotFound, "Artifact not found")
+}
@@ -0,0 +1,280 @@
+// internal/api/audit.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_runner/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Audit queries return this many events unless asked for more
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 10000
+)
+
+// auditActions names the audited routes, by method and route pattern
+var auditActions = map[string]string{
+	"POST /login":                       "auth.login",
+	"POST /logout":                      "auth.logout",
+	"GET /login/oidc/callback":          "auth.sso_login",
+	"POST /api/v1/hooks/{provider}":     "webhook.receive",
+	"POST /api/v1/binaries":             "binary.create",
+	"PUT /api/v1/binaries/{id}":         "binary.update",
+	"DELETE /api/v1/binaries/{id}":      "binary.delete",
+	"POST /api/v1/binaries/{id}/upload": "binary.upload",
+	"POST /api/v1/binaries/{id}/build":  "binary.build",
+	"POST /api/v1/credentials":          "credential.create",
+	"PUT /api/v1/credentials/{id}":      "credential.update",
+	"DELETE /api/v1/credentials/{id}":   "credential.delete",
+	"POST /api/v1/apikeys":              "apikey.create",
+	"DELETE /api/v1/apikeys/{id}":       "apikey.revoke",
+	"POST /api/v1/apikeys/{id}/rotate":  "apikey.rotate",
+	"POST /api/v1/users":                "user.create",
+	"PUT /api/v1/users/{id}":            "user.update",
+	"DELETE /api/v1/users/{id}":         "user.delete",
+	"DELETE /api/v1/sessions/{id}":      "session.revoke",
+	"DELETE /api/v1/cache":              "cache.purge",
+	"POST /api/v1/execute":              "execution.start",
+	"DELETE /api/v1/execute/{id}":       "execution.stop",
+}
+
+// auditRecord collects what authentication and handlers learn about a
+// request, for its audit event. Its methods do nothing on nil, so callers
+// need not know whether the request is audited.
+type auditRecord struct {
+	actor     string
+	actorID   string
+	actorType string
+	target    string
+	details   map[string]string
+}
+
+func auditFromContext(ctx context.Context) *auditRecord {
+	rec, _ := ctx.Value(auditContextKey).(*auditRecord)
+	return rec
+}
+
+// setUser records the user acting
+func (a *auditRecord) setUser(user *models.User) {
+	if a == nil || user == nil {
+		return
+	}
+	a.actor, a.actorID = user.Username, user.ID
+	switch {
+	case user == adminTokenUser:
+		a.actorType = models.ActorAdminToken
+	case strings.HasPrefix(user.ID, "oidc:"):
+		a.actorType = models.ActorSSO
+	case strings.HasPrefix(user.ID, "cert:"):
+		a.actorType = models.ActorCert
+	default:
+		a.actorType = models.ActorUser
+	}
+}
+
+// setAPIKey records the API key acting
+func (a *auditRecord) setAPIKey(key *models.APIKey) {
+	if a == nil || key == nil {
+		return
+	}
+	a.actor, a.actorID, a.actorType = key.Name, key.ID, models.ActorAPIKey
+}
+
+// setSession records the user a session belongs to
+func (a *auditRecord) setSession(sess *models.Session) {
+	if a == nil || sess == nil {
+		return
+	}
+	if sess.UserID == "" {
+		a.setActor(models.ActorAdminToken, sess.Username)
+		return
+	}
+	a.setUser(&models.User{ID: sess.UserID, Username: sess.Username})
+}
+
+// setActor records an actor that is neither a user nor an API key
+func (a *auditRecord) setActor(actorType, actor string) {
+	if a == nil {
+		return
+	}
+	a.actor, a.actorID, a.actorType = actor, "", actorType
+}
+
+// setTarget records what was acted on, for routes without it in the path
+func (a *auditRecord) setTarget(target string) {
+	if a == nil {
+		return
+	}
+	a.target = target
+}
+
+// setDetail records more about the action, such as an execution's exit code
+func (a *auditRecord) setDetail(key, value string) {
+	if a == nil {
+		return
+	}
+	if a.details == nil {
+		a.details = make(map[string]string)
+	}
+	a.details[key] = value
+}
+
+// auditMiddleware records an event for every request that changes
+// something, and for single sign-on, once the handler has responded
+func (s *Server) auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.audit == nil || (safeMethod(r.Method) && r.URL.Path != "/login/oidc/callback") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &auditRecord{}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), auditContextKey, rec)))
+		s.recordAudit(r, ww.Status(), rec)
+	})
+}
+
+func (s *Server) recordAudit(r *http.Request, status int, rec *auditRecord) {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.RoutePattern() == "" {
+		return // no route matched
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	pattern := rctx.RoutePattern()
+	action, ok := auditActions[r.Method+" "+pattern]
+	if !ok {
+		action = strings.ToLower(r.Method) + " " + pattern
+	}
+	target := rec.target
+	if target == "" {
+		target = rctx.URLParam("id")
+	}
+	actorType := rec.actorType
+	if actorType == "" {
+		actorType = models.ActorAnonymous
+	}
+
+	event := &models.AuditEvent{
+		Actor:     rec.actor,
+		ActorID:   rec.actorID,
+		ActorType: actorType,
+		Action:    action,
+		Target:    target,
+		Details:   rec.details,
+		RequestID: middleware.GetReqID(r.Context()),
+		IP:        clientIP(r),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Status:    status,
+		Outcome:   auditOutcome(status),
+	}
+	if err := s.audit.Append(event); err != nil {
+		slog.Error("Failed to write audit event",
+			slog.String("action", action),
+			slog.String("error", err.Error()))
+	}
+}
+
+func auditOutcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return models.OutcomeDenied
+	case status >= 400:
+		return models.OutcomeFailure
+	default:
+		return models.OutcomeSuccess
+	}
+}
+
+// listAuditHandler returns audit events matching the query parameters
+// actor, action, target, outcome, since, until and limit, as JSON or, with
+// format=jsonl, as one event per line for export
+func (s *Server) listAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil {
+		s.respondError(w, http.StatusNotFound, "Audit log is not configured")
+		return
+	}
+
+	q := r.URL.Query()
+	jsonl := q.Get("format") == "jsonl" || r.Header.Get("Accept") == "application/x-ndjson"
+	filter := models.AuditFilter{
+		Actor:   q.Get("actor"),
+		Action:  q.Get("action"),
+		Target:  q.Get("target"),
+		Outcome: q.Get("outcome"),
+	}
+	for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.respondError(w, http.StatusBadRequest, name+" must be an RFC 3339 time")
+				return
+			}
+			*dst = t
+		}
+	}
+	// Exports are complete unless limited; listings show the latest events
+	if !jsonl {
+		filter.Limit = defaultAuditLimit
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			s.respondError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxAuditLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	events, err := s.audit.Query(filter)
+	if err != nil {
+		slog.Error("Failed to read audit log", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to read audit log")
+		return
+	}
+
+	if jsonl {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+		for _, e := range events {
+			enc.Encode(e)
+		}
+		return
+	}
+	if events == nil {
+		events = []*models.AuditEvent{}
+	}
+	s.respondJSON(w, http.StatusOK, events)
+}
+
+// verifyAuditHandler checks the hash chain of the whole audit log
+func (s *Server) verifyAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil {
+		s.respondError(w, http.StatusNotFound, "Audit log is not configured")
+		return
+	}
+
+	result, err := s.audit.Verify()
+	if err != nil {
+		slog.Error("Failed to verify audit log", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to verify audit log")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, result)
+}
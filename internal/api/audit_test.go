@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go_runner/internal/audit"
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	log, err := audit.Open(t.TempDir())
+	require.NoError(t, err)
+	defer log.Close()
+	userStore, err := users.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	mockStorage := new(MockStorage)
+	mockExecutor := new(MockExecutor)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, mockExecutor, WithAuditLog(log), WithUsers(userStore))
+
+	adminSession := loginAdmin(t, server)
+	require.NoError(t, userStore.Create(&models.User{Username: "vera", Role: models.RoleViewer}, "vera password"))
+	viewer := login(t, server, url.Values{"username": {"vera"}, "password": {"vera password"}})
+
+	do := func(sess *testSession, method, path string, body []byte, header ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+		req.RemoteAddr = "192.0.2.7:40000"
+		if sess != nil {
+			sess.authorize(req)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	query := func(params string) []models.AuditEvent {
+		rr := do(adminSession, "GET", "/api/v1/audit?"+params, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var events []models.AuditEvent
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &events))
+		return events
+	}
+
+	// An admin creates a binary
+	mockStorage.On("SaveBinary", mock.AnythingOfType("*models.Binary")).Return(nil).Once()
+	rr := do(adminSession, "POST", "/api/v1/binaries", []byte(`{"name":"tool","repo_url":"https://example.com/tool.git","branch":"main"}`))
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var created models.Binary
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+
+	events := query("action=binary.create")
+	require.Len(t, events, 1)
+	e := events[0]
+	assert.Equal(t, adminTokenUser.Username, e.Actor)
+	assert.Equal(t, models.ActorAdminToken, e.ActorType)
+	assert.Equal(t, created.ID, e.Target)
+	assert.Equal(t, models.OutcomeSuccess, e.Outcome)
+	assert.Equal(t, http.StatusCreated, e.Status)
+	assert.Equal(t, "192.0.2.7", e.IP)
+	assert.NotEmpty(t, e.RequestID)
+
+	// A viewer may neither delete binaries nor read the log, and both attempts are recorded
+	assert.Equal(t, http.StatusForbidden, do(viewer, "DELETE", "/api/v1/binaries/"+created.ID, nil).Code)
+	assert.Equal(t, http.StatusForbidden, do(viewer, "GET", "/api/v1/audit", nil).Code)
+	events = query("actor=vera&outcome=denied")
+	require.Len(t, events, 1)
+	assert.Equal(t, "binary.delete", events[0].Action)
+	assert.Equal(t, created.ID, events[0].Target)
+	assert.Equal(t, models.ActorUser, events[0].ActorType)
+
+	// Executions record the key, the binary and how the run ended
+	binary := &models.Binary{ID: created.ID, Status: "ready"}
+	result := &models.ExecutionResult{ID: "exec1", Status: "failed", ExitCode: 3}
+	mockStorage.On("GetBinary", created.ID).Return(binary, nil).Once()
+	mockExecutor.On("Execute", mock.Anything, binary.SHA256, mock.Anything, mock.Anything).Return(result, nil).Once()
+	mockStorage.On("SaveExecution", result).Return(nil).Once()
+	rr = do(nil, "POST", "/api/v1/execute", []byte(`{"binary_id":"`+created.ID+`"}`), "X-API-Key", newTestAPIKey(t, server))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	events = query("action=execution.")
+	require.Len(t, events, 1)
+	assert.Equal(t, "test", events[0].Actor)
+	assert.Equal(t, models.ActorAPIKey, events[0].ActorType)
+	assert.Equal(t, created.ID, events[0].Target)
+	assert.Equal(t, map[string]string{"execution_id": "exec1", "status": "failed", "exit_code": "3"}, events[0].Details)
+
+	// Reads are not recorded; sign-ins are
+	assert.Empty(t, query("action=get"))
+	assert.Len(t, query("action=auth.login"), 2)
+	assert.Len(t, query("limit=2"), 2)
+	assert.Equal(t, http.StatusBadRequest, do(adminSession, "GET", "/api/v1/audit?since=yesterday", nil).Code)
+
+	// The export is the whole log, one event per line
+	rr = do(adminSession, "GET", "/api/v1/audit?format=jsonl", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+	lines := 0
+	for scanner := bufio.NewScanner(rr.Body); scanner.Scan(); lines++ {
+		var e models.AuditEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		assert.Equal(t, int64(lines+1), e.Seq)
+	}
+	assert.Equal(t, 5, lines)
+
+	rr = do(adminSession, "GET", "/api/v1/audit/verify", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var verification models.AuditVerification
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &verification))
+	assert.True(t, verification.Valid)
+	assert.Equal(t, int64(5), verification.Events)
+
+	mockStorage.AssertExpectations(t)
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestAuditLog_NotConfigured(t *testing.T) {
+	server := NewServer(config.ServerConfig{}, nil, nil, nil)
+	adminSession := loginAdmin(t, server)
+
+	req, _ := http.NewRequest("GET", "/api/v1/audit", nil)
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,334 @@
+// internal/api/build.go
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go_runner/internal/models"
+	"go_runner/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// defaultBinaryPath is the artifact store used when WithArtifactStore is not given
+const defaultBinaryPath = "./data/binaries"
+
+// checkoutPath names the working copy of a binary below the repository path
+func checkoutPath(id string) string {
+	return "repo_" + id
+}
+
+// EnqueueBuild builds the binary in the background and reports whether a new
+// build was started. If one is already running, a single follow-up build is
+// queued instead, so pushes landing mid-build are still picked up. The build
+// takes over binary, which must be the caller's own copy from storage; its
+// progress is only published through UpdateBinary.
+func (s *Server) EnqueueBuild(binary *models.Binary) bool {
+	s.buildMu.Lock()
+	if _, running := s.running[binary.ID]; running {
+		s.running[binary.ID] = true
+		s.buildMu.Unlock()
+		return false
+	}
+	s.running[binary.ID] = false
+	s.buildMu.Unlock()
+
+	binary.Status = "building"
+	s.storage.UpdateBinary(binary)
+
+	go func() {
+		for {
+			s.runBuild(binary)
+
+			s.buildMu.Lock()
+			if !s.running[binary.ID] {
+				delete(s.running, binary.ID)
+				s.buildMu.Unlock()
+				return
+			}
+			s.running[binary.ID] = false
+			s.buildMu.Unlock()
+
+			// Pick up changes made to the binary since the last build
+			next, err := s.storage.GetBinary(binary.ID)
+			if err != nil {
+				s.buildMu.Lock()
+				delete(s.running, binary.ID)
+				s.buildMu.Unlock()
+				return
+			}
+			binary = next
+			binary.Status = "building"
+			s.storage.UpdateBinary(binary)
+		}
+	}()
+	return true
+}
+
+// runBuild builds the binary and records the outcome. A failed build leaves
+// the previously built version active.
+func (s *Server) runBuild(binary *models.Binary) {
+	if err := s.buildBinary(s.builds, binary); err != nil {
+		slog.Error("Failed to build binary",
+			slog.String("id", binary.ID),
+			slog.String("error", err.Error()))
+		if binary.BinaryPath != "" {
+			binary.Status = "ready"
+		} else {
+			binary.Status = "failed"
+		}
+	} else {
+		binary.Status = "ready"
+		binary.LastBuilt = time.Now()
+	}
+	s.storage.UpdateBinary(binary)
+}
+
+func (s *Server) buildBinary(ctx context.Context, binary *models.Binary) (err error) {
+	build := &models.BuildRecord{
+		ID:        uuid.New().String(),
+		Status:    "building",
+		StartedAt: time.Now(),
+	}
+	binary.LastBuild = build
+
+	defer func() {
+		build.FinishedAt = time.Now()
+		if err != nil {
+			build.Status = "failed"
+			build.Error = err.Error()
+		} else {
+			build.Status = "succeeded"
+		}
+	}()
+
+	cred, err := s.resolveCredential(binary)
+	if err != nil {
+		return fmt.Errorf("failed to load credential: %w", err)
+	}
+
+	repoPath := checkoutPath(binary.ID)
+	var commitHash string
+	if binary.SourceType == models.SourceArchive {
+		// Uploaded sources are versioned by the checksum of the archive
+		if binary.Upload == nil {
+			return fmt.Errorf("no source archive has been uploaded")
+		}
+		if err := s.git.ExtractSource(repoPath, s.artifacts.UploadPath(binary.ID)); err != nil {
+			return fmt.Errorf("failed to extract source archive: %w", err)
+		}
+		commitHash = binary.Upload.SHA256
+		build.SourceSHA256 = commitHash
+	} else {
+		// Clone or update repository
+		var cloneOpts models.CloneOptions
+		if binary.CloneOptions != nil {
+			cloneOpts = *binary.CloneOptions
+		}
+		if err := s.git.CloneOrUpdate(ctx, binary.RepoURL, binary.Branch, repoPath, binary.BuildPath, cloneOpts, cred); err != nil {
+			return fmt.Errorf("failed to clone/update repo: %w", err)
+		}
+
+		// Get commit hash for version
+		commitHash, err = s.git.GetCommitHash(ctx, repoPath)
+		if err != nil {
+			return fmt.Errorf("failed to get commit hash: %w", err)
+		}
+		build.Commit = commitHash
+	}
+	shortCommit := commitHash[:8]
+
+	// Resolve build options, injecting build metadata into ldflags
+	var opts models.BuildOptions
+	if binary.BuildOptions != nil {
+		opts = *binary.BuildOptions
+	}
+	build.Options, err = opts.Expand(map[string]string{
+		"COMMIT":       commitHash,
+		"SHORT_COMMIT": shortCommit,
+		"BRANCH":       binary.Branch,
+		"BUILD_TIME":   build.StartedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("invalid build options: %w", err)
+	}
+
+	// Pick the Go toolchain
+	tc, err := s.git.ResolveToolchain(repoPath, binary.BuildPath, binary.GoVersion)
+	if err != nil {
+		return fmt.Errorf("failed to resolve go toolchain: %w", err)
+	}
+	build.Toolchain = tc
+
+	// Run the quality gates before anything of the active version is replaced
+	if binary.Gates != nil {
+		build.Gates = s.git.RunGates(ctx, repoPath, binary.BuildPath, tc, build.Options, *binary.Gates)
+		if failed := failedGates(build.Gates); len(failed) > 0 {
+			return fmt.Errorf("quality gates failed: %s", strings.Join(failed, ", "))
+		}
+	}
+
+	// Cross-compile the target matrix into a directory private to this build
+	artifactDir := s.artifacts.ArtifactDir(binary.ID, build.ID)
+	for _, target := range binary.Targets {
+		artifact, err := s.buildArtifact(repoPath, binary.BuildPath, artifactDir, target, tc, build.Options)
+		if err != nil {
+			os.RemoveAll(artifactDir)
+			return err
+		}
+		artifact.BuildID = build.ID
+		build.Artifacts = append(build.Artifacts, *artifact)
+	}
+
+	// Build the binary last, next to the active version so it can be
+	// inspected before replacing it
+	outputPath := s.artifacts.BinaryPath(binary.ID)
+	stagingPath := outputPath + ".new"
+	if err := s.git.BuildGoBinary(repoPath, binary.BuildPath, stagingPath, tc, build.Options); err != nil {
+		os.RemoveAll(artifactDir)
+		return fmt.Errorf("failed to build binary: %w", err)
+	}
+	defer removeStaged(stagingPath)
+
+	sum, _, err := fileSHA256(stagingPath)
+	if err != nil {
+		os.RemoveAll(artifactDir)
+		return fmt.Errorf("failed to checksum binary: %w", err)
+	}
+	build.SHA256 = sum
+
+	if err := s.inspectBinary(binary, build, stagingPath); err != nil {
+		os.RemoveAll(artifactDir)
+		return err
+	}
+
+	if err := s.writeProvenance(stagingPath, s.buildProvenance(binary, build)); err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+	if err := installBinary(stagingPath, outputPath); err != nil {
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+
+	// Drop artifacts of the previous version once the new set is complete
+	for _, old := range binary.Artifacts {
+		if old.BuildID != "" && old.BuildID != build.ID {
+			os.RemoveAll(s.artifacts.ArtifactDir(binary.ID, old.BuildID))
+		}
+	}
+
+	binary.BinaryPath = outputPath
+	binary.SHA256 = sum
+	binary.Version = shortCommit
+	binary.Commit = build.Commit
+	binary.Artifacts = build.Artifacts
+	return nil
+}
+
+// installBinary moves a staged executable into place, followed by the
+// provenance and SBOM written next to it, so the metadata never describes a
+// version that was not installed. A version without an SBOM drops the old one.
+func installBinary(stagingPath, outputPath string) error {
+	if err := os.Rename(stagingPath, outputPath); err != nil {
+		return err
+	}
+	for _, meta := range []func(string) string{provenancePath, sbomPath} {
+		err := os.Rename(meta(stagingPath), meta(outputPath))
+		if os.IsNotExist(err) {
+			os.Remove(meta(outputPath))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeStaged removes a staged executable and its metadata, if still there
+func removeStaged(stagingPath string) {
+	os.Remove(stagingPath)
+	os.Remove(provenancePath(stagingPath))
+	os.Remove(sbomPath(stagingPath))
+}
+
+// buildArtifact cross-compiles a single target and checksums the result
+func (s *Server) buildArtifact(repoPath, buildPath, artifactDir string, target models.Target, tc *models.Toolchain, opts models.BuildOptions) (*models.Artifact, error) {
+	opts.GOOS = target.GOOS
+	opts.GOARCH = target.GOARCH
+	// The race detector is only available for native builds
+	opts.Race = false
+
+	outputPath := filepath.Join(artifactDir, target.String())
+	if err := s.git.BuildGoBinary(repoPath, buildPath, outputPath, tc, opts); err != nil {
+		return nil, fmt.Errorf("failed to build %s: %w", target, err)
+	}
+
+	sum, size, err := fileSHA256(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum %s: %w", target, err)
+	}
+
+	return &models.Artifact{
+		Target: target,
+		Path:   outputPath,
+		SHA256: sum,
+		Size:   size,
+	}, nil
+}
+
+// failedGates returns the names of the gates that did not pass
+func failedGates(results []models.GateResult) []string {
+	var failed []string
+	for _, r := range results {
+		if !r.Passed() {
+			failed = append(failed, r.Name)
+		}
+	}
+	return failed
+}
+
+// gitErrorStatus maps typed git errors to an HTTP status and message
+func gitErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, repository.ErrAuthFailed):
+		return http.StatusFailedDependency, "Repository authentication failed"
+	case errors.Is(err, repository.ErrRepoNotFound):
+		return http.StatusUnprocessableEntity, "Repository not found"
+	case errors.Is(err, repository.ErrRefNotFound):
+		return http.StatusUnprocessableEntity, "Branch not found in repository"
+	case errors.Is(err, repository.ErrNetworkUnreachable):
+		return http.StatusBadGateway, "Repository is unreachable"
+	case errors.Is(err, repository.ErrTimeout):
+		return http.StatusGatewayTimeout, "Repository did not respond in time"
+	default:
+		slog.Error("Git operation failed", slog.String("error", err.Error()))
+		return http.StatusBadGateway, "Git operation failed"
+	}
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 and size of a file
+func fileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
@@ -0,0 +1,48 @@
+// internal/api/cache.go
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go_runner/internal/gocache"
+)
+
+// cacheStatsHandler reports the size of the shared module and build caches
+func (s *Server) cacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.cache == nil {
+		s.respondError(w, http.StatusNotFound, "Build cache is not configured")
+		return
+	}
+
+	stats, err := s.cache.Stats()
+	if err != nil {
+		slog.Error("Failed to read cache stats", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to read cache stats")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, stats)
+}
+
+// purgeCacheHandler empties the module cache, build cache or both (?target=mod|build|all)
+func (s *Server) purgeCacheHandler(w http.ResponseWriter, r *http.Request) {
+	if s.cache == nil {
+		s.respondError(w, http.StatusNotFound, "Build cache is not configured")
+		return
+	}
+
+	target := r.URL.Query().Get("target")
+	if err := s.cache.Purge(target); err != nil {
+		if errors.Is(err, gocache.ErrInvalidTarget) {
+			s.respondError(w, http.StatusBadRequest, "target must be one of mod, build, all")
+			return
+		}
+		slog.Error("Failed to purge cache", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to purge cache")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]string{"message": "Cache purged"})
+}
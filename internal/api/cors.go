@@ -0,0 +1,47 @@
+// internal/api/cors.go
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"go_runner/internal/config"
+
+	"github.com/go-chi/cors"
+)
+
+// corsMiddleware applies the API policy to the execution endpoints and the
+// admin policy to everything else. A policy without origins adds no CORS
+// headers, so browsers keep those routes same-origin.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	// Session cookies only reach the admin routes, and only from listed origins
+	admin := corsHandler(s.config.CORS.Admin, true, "Link")(next)
+	api := corsHandler(s.config.CORS.API, false, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After")(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if executionPath(r.URL.Path) {
+			api.ServeHTTP(w, r)
+			return
+		}
+		admin.ServeHTTP(w, r)
+	})
+}
+
+func corsHandler(policy config.CORSPolicy, credentials bool, exposed ...string) func(http.Handler) http.Handler {
+	if len(policy.AllowedOrigins) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return cors.Handler(cors.Options{
+		AllowedOrigins:   policy.AllowedOrigins,
+		AllowedMethods:   policy.AllowedMethods,
+		AllowedHeaders:   policy.AllowedHeaders,
+		ExposedHeaders:   exposed,
+		AllowCredentials: credentials,
+		MaxAge:           int(policy.MaxAge.Seconds()),
+	})
+}
+
+// executionPath reports whether path belongs to the execution API
+func executionPath(path string) bool {
+	return path == "/api/v1/execute" || strings.HasPrefix(path, "/api/v1/execute/")
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go_runner/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func preflight(server *Server, path, origin, method string) http.Header {
+	req, _ := http.NewRequest("OPTIONS", path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	return rr.Header()
+}
+
+func TestCORS_SameOriginByDefault(t *testing.T) {
+	server := NewServer(config.ServerConfig{}, nil, nil, nil)
+
+	assert.Empty(t, preflight(server, "/api/v1/binaries", "https://evil.example.com", "DELETE").Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, preflight(server, "/api/v1/execute", "https://evil.example.com", "POST").Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_Policies(t *testing.T) {
+	server := NewServer(config.ServerConfig{CORS: config.CORSConfig{
+		Admin: config.CORSPolicy{
+			AllowedOrigins: []string{"https://ops.example.com"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "X-CSRF-Token"},
+			MaxAge:         10 * time.Minute,
+		},
+		API: config.CORSPolicy{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST"},
+			AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+		},
+	}}, nil, nil, nil)
+
+	// Listed origins may use the admin routes with cookies
+	h := preflight(server, "/api/v1/binaries", "https://ops.example.com", "DELETE")
+	assert.Equal(t, "https://ops.example.com", h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", h.Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", h.Get("Access-Control-Max-Age"))
+	assert.Empty(t, preflight(server, "/api/v1/binaries", "https://evil.example.com", "DELETE").Get("Access-Control-Allow-Origin"))
+
+	// Any origin may execute, but never with cookies
+	h = preflight(server, "/api/v1/execute", "https://evil.example.com", "POST")
+	assert.Equal(t, "*", h.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, h.Get("Access-Control-Allow-Credentials"))
+	assert.Empty(t, preflight(server, "/api/v1/execute/abc", "https://evil.example.com", "DELETE").Get("Access-Control-Allow-Origin"), "method not allowed by the API policy")
+
+	// The execution API exposes its rate limit headers
+	req, _ := http.NewRequest("GET", "/api/v1/execute/abc", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Contains(t, rr.Header().Get("Access-Control-Expose-Headers"), "Ratelimit-Remaining")
+}
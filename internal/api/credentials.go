@@ -0,0 +1,171 @@
+// internal/api/credentials.go
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go_runner/internal/credentials"
+	"go_runner/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// listCredentialsHandler returns all credentials without their secrets
+func (s *Server) listCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.creds == nil {
+		s.respondError(w, http.StatusNotFound, "Credentials are not configured")
+		return
+	}
+
+	creds, err := s.creds.List()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to list credentials")
+		return
+	}
+
+	redacted := make([]*models.Credential, 0, len(creds))
+	for _, cred := range creds {
+		redacted = append(redacted, cred.Redacted())
+	}
+	s.respondJSON(w, http.StatusOK, redacted)
+}
+
+// createCredentialHandler stores a new credential
+func (s *Server) createCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	if s.creds == nil {
+		s.respondError(w, http.StatusNotFound, "Credentials are not configured")
+		return
+	}
+
+	var cred models.Credential
+	if !s.decodeStrictBody(w, r, maxCredentialBody, &cred) {
+		return
+	}
+
+	if errs := validateCredential(&cred); len(errs) > 0 {
+		s.respondValidation(w, errs)
+		return
+	}
+
+	cred.ID = uuid.New().String()
+	auditFromContext(r.Context()).setTarget(cred.ID)
+	if err := s.creds.Save(&cred); err != nil {
+		slog.Error("Failed to save credential", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to save credential")
+		return
+	}
+
+	s.respondJSON(w, http.StatusCreated, cred.Redacted())
+}
+
+// getCredentialHandler returns a credential without its secret
+func (s *Server) getCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	if s.creds == nil {
+		s.respondError(w, http.StatusNotFound, "Credentials are not configured")
+		return
+	}
+
+	cred, err := s.creds.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Credential not found")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, cred.Redacted())
+}
+
+// updateCredentialHandler replaces a credential. An empty secret keeps the stored one.
+func (s *Server) updateCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	if s.creds == nil {
+		s.respondError(w, http.StatusNotFound, "Credentials are not configured")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	existing, err := s.creds.Get(id)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Credential not found")
+		return
+	}
+
+	var cred models.Credential
+	if !s.decodeStrictBody(w, r, maxCredentialBody, &cred) {
+		return
+	}
+
+	cred.ID = id
+	if cred.Secret == "" && cred.Type == existing.Type {
+		cred.Secret = existing.Secret
+	}
+	if errs := validateCredential(&cred); len(errs) > 0 {
+		s.respondValidation(w, errs)
+		return
+	}
+
+	if err := s.creds.Save(&cred); err != nil {
+		slog.Error("Failed to save credential", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to save credential")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, cred.Redacted())
+}
+
+// deleteCredentialHandler removes a credential that no binary references
+func (s *Server) deleteCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	if s.creds == nil {
+		s.respondError(w, http.StatusNotFound, "Credentials are not configured")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	binaries, err := s.storage.ListBinaries()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to list binaries")
+		return
+	}
+	for _, binary := range binaries {
+		if binary.CredentialID == id {
+			s.respondError(w, http.StatusConflict, "Credential is used by binary "+binary.ID)
+			return
+		}
+	}
+
+	if err := s.creds.Delete(id); err != nil {
+		if errors.Is(err, credentials.ErrCredentialNotFound) {
+			s.respondError(w, http.StatusNotFound, "Credential not found")
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "Failed to delete credential")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]string{"message": "Credential deleted successfully"})
+}
+
+// resolveCredential loads the credential a binary clones with, if any
+func (s *Server) resolveCredential(binary *models.Binary) (*models.Credential, error) {
+	if binary.CredentialID == "" {
+		return nil, nil
+	}
+	if s.creds == nil {
+		return nil, errors.New("binary references a credential but credentials are not configured")
+	}
+	return s.creds.Get(binary.CredentialID)
+}
+
+// mayAttachCredential reports whether user may save updated, whose previous
+// version is old (nil when creating it). Credentials are only visible to those
+// who manage them, so attaching one, or pointing a binary that has one at
+// another repository, needs that permission; otherwise the secret could be
+// sent to a host the caller controls.
+func mayAttachCredential(user *models.User, old, updated *models.Binary) bool {
+	if updated.CredentialID == "" || user == nil || user.Can(models.PermManageCredentials) {
+		return true
+	}
+	return old != nil && old.CredentialID == updated.CredentialID && old.RepoURL == updated.RepoURL
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go_runner/internal/config"
+	"go_runner/internal/credentials"
+	"go_runner/internal/models"
+	"go_runner/internal/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialsHandlers_RedactSecrets(t *testing.T) {
+	const secret = "ghp_supersecrettoken"
+	mockStorage := new(MockStorage)
+	store, err := credentials.NewFileStore(t.TempDir(), "store passphrase")
+	require.NoError(t, err)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil, WithCredentials(store))
+	adminSession := loginAdmin(t, server)
+
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		adminSession.authorize(req)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/api/v1/credentials", models.Credential{Name: "github", Type: models.CredentialToken, Secret: secret})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.NotContains(t, rr.Body.String(), secret)
+	var created models.Credential
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+
+	for _, path := range []string{"/api/v1/credentials", "/api/v1/credentials/" + created.ID} {
+		rr = do("GET", path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, path)
+		assert.Contains(t, rr.Body.String(), "github", path)
+		assert.NotContains(t, rr.Body.String(), secret, path)
+		assert.NotContains(t, rr.Body.String(), `"secret"`, path)
+	}
+
+	// Updates without a secret keep the stored one, which is still not returned
+	rr = do("PUT", "/api/v1/credentials/"+created.ID, models.Credential{Name: "github-ci", Type: models.CredentialToken})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.NotContains(t, rr.Body.String(), secret)
+	stored, err := store.Get(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, secret, stored.Secret)
+	assert.Equal(t, "github-ci", stored.Name)
+
+	// Invalid credentials get the same field errors as binaries
+	fields := invalidFields(t, do("POST", "/api/v1/credentials", models.Credential{Name: "nosecret", Type: models.CredentialToken}))
+	assert.Equal(t, "is required", fields["secret"])
+	fields = invalidFields(t, do("POST", "/api/v1/credentials", models.Credential{Type: models.CredentialSSH, Secret: "not a key"}))
+	assert.Equal(t, "is required", fields["name"])
+	assert.Equal(t, "must be a PEM private key", fields["secret"])
+	assert.Equal(t, "is required to pin the host key", fields["known_hosts"])
+	fields = invalidFields(t, do("PUT", "/api/v1/credentials/"+created.ID, models.Credential{Name: "github", Type: "kerberos"}))
+	assert.Equal(t, "must be one of token, basic, ssh", fields["type"])
+
+	// Unknown fields are refused rather than dropped, and bodies are limited
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/v1/credentials", map[string]string{"name": "github", "type": "token", "secert": secret}).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, do("POST", "/api/v1/credentials", models.Credential{Name: "big", Type: models.CredentialToken, Secret: strings.Repeat("x", maxCredentialBody)}).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/v1/credentials/missing", nil).Code)
+
+	// Credentials in use cannot be deleted
+	mockStorage.On("ListBinaries").Return([]*models.Binary{{ID: "b1", CredentialID: created.ID}}, nil).Once()
+	assert.Equal(t, http.StatusConflict, do("DELETE", "/api/v1/credentials/"+created.ID, nil).Code)
+	mockStorage.On("ListBinaries").Return([]*models.Binary{}, nil).Once()
+	assert.Equal(t, http.StatusOK, do("DELETE", "/api/v1/credentials/"+created.ID, nil).Code)
+}
+
+func TestBinaryHandlers_AttachCredentialNeedsManagePermission(t *testing.T) {
+	mockStorage := new(MockStorage)
+	credStore, err := credentials.NewFileStore(t.TempDir(), "")
+	require.NoError(t, err)
+	userStore, err := users.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil, WithCredentials(credStore), WithUsers(userStore))
+	adminSession := loginAdmin(t, server)
+
+	do := func(sess *testSession, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(body)
+		req, _ := http.NewRequest(method, path, &buf)
+		sess.authorize(req)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	require.NoError(t, credStore.Save(&models.Credential{ID: "deploy", Name: "deploy", Type: models.CredentialToken, Secret: "ghp_token"}))
+	require.Equal(t, http.StatusCreated, do(adminSession, "POST", "/api/v1/users", map[string]string{"username": "alice", "password": "alice password", "role": "maintainer"}).Code)
+	aliceSession := login(t, server, url.Values{"username": {"alice"}, "password": {"alice password"}})
+
+	// Maintainers cannot attach a credential, known or not
+	for _, id := range []string{"deploy", "missing"} {
+		rr := do(aliceSession, "POST", "/api/v1/binaries", models.Binary{Name: "tool", RepoURL: "https://evil.example/tool.git", Branch: "main", CredentialID: id})
+		assert.Equal(t, http.StatusForbidden, rr.Code, id)
+	}
+	mockStorage.AssertNotCalled(t, "SaveBinary", mock.Anything)
+
+	// Nor send one that an admin attached to another repository
+	stored := &models.Binary{ID: "1", Name: "tool", Owner: "alice", RepoURL: "https://github.com/org/tool.git", Branch: "main", CredentialID: "deploy"}
+	mockStorage.On("GetBinary", "1").Return(stored, nil)
+	moved := *stored
+	moved.RepoURL = "https://evil.example/tool.git"
+	assert.Equal(t, http.StatusForbidden, do(aliceSession, "PUT", "/api/v1/binaries/1", moved).Code)
+	mockStorage.AssertNotCalled(t, "UpdateBinary", mock.Anything)
+
+	// Other changes, and dropping the credential, are fine
+	mockStorage.On("UpdateBinary", mock.AnythingOfType("*models.Binary")).Return(nil)
+	renamed := *stored
+	renamed.Name = "renamed"
+	assert.Equal(t, http.StatusOK, do(aliceSession, "PUT", "/api/v1/binaries/1", renamed).Code)
+	moved.CredentialID = ""
+	assert.Equal(t, http.StatusOK, do(aliceSession, "PUT", "/api/v1/binaries/1", moved).Code)
+
+	// Admins manage credentials and may attach them
+	mockStorage.On("SaveBinary", mock.AnythingOfType("*models.Binary")).Return(nil)
+	rr := do(adminSession, "POST", "/api/v1/binaries", models.Binary{Name: "tool", RepoURL: "https://github.com/org/tool.git", Branch: "main", CredentialID: "deploy"})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+}
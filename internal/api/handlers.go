@@ -2,13 +2,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log/slog"
 	"net/http"
-	"path/filepath"
+	"strconv"
 	"time"
 
+	"go_runner/internal/executor"
 	"go_runner/internal/models"
 
 	"github.com/go-chi/chi/v5"
@@ -43,15 +45,35 @@ func (s *Server) listBinariesHandler(w http.ResponseWriter, r *http.Request) {
 
 // createBinaryHandler creates a new binary
 func (s *Server) createBinaryHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.Binary
+	if !s.decodeBody(w, r, maxBinaryBody, &req) {
+		return
+	}
 	var binary models.Binary
-	if err := json.NewDecoder(r.Body).Decode(&binary); err != nil {
-		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+	binary.ApplySettings(&req)
+
+	// Checked first, so the validation errors do not reveal which credentials exist
+	if !mayAttachCredential(userFromContext(r.Context()), nil, &binary) {
+		s.respondError(w, http.StatusForbidden, "Only credential managers can attach a credential")
+		return
+	}
+	if errs := s.validateBinary(&binary); len(errs) > 0 {
+		s.respondValidation(w, errs)
 		return
 	}
 
+	// Maintainers own what they create; admins may assign any owner
+	if user := userFromContext(r.Context()); user != nil && !user.Can(models.PermWriteAllBinaries) {
+		binary.Owner = user.Username
+	}
+
 	// Generate ID
 	binary.ID = uuid.New().String()
 	binary.Status = "pending"
+	auditFromContext(r.Context()).setTarget(binary.ID)
+	if binary.SourceType == "" {
+		binary.SourceType = models.SourceGit
+	}
 
 	// Save binary
 	if err := s.storage.SaveBinary(&binary); err != nil {
@@ -80,13 +102,33 @@ func (s *Server) getBinaryHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) updateBinaryHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	var binary models.Binary
-	if err := json.NewDecoder(r.Body).Decode(&binary); err != nil {
-		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+	var req models.Binary
+	if !s.decodeBody(w, r, maxBinaryBody, &req) {
+		return
+	}
+
+	stored, err := s.storage.GetBinary(id)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Binary not found")
 		return
 	}
+	binary := *stored
+	binary.ApplySettings(&req)
+
+	if !mayAttachCredential(userFromContext(r.Context()), stored, &binary) {
+		s.respondError(w, http.StatusForbidden, "Only credential managers can attach a credential or change the repository it is sent to")
+		return
+	}
+	if errs := s.validateBinary(&binary); len(errs) > 0 {
+		s.respondValidation(w, errs)
+		return
+	}
+
+	// requireBinaryOwner checked the caller owns it, so only admins can reassign it
+	if user := userFromContext(r.Context()); user != nil && !user.Can(models.PermWriteAllBinaries) {
+		binary.Owner = user.Username
+	}
 
-	binary.ID = id
 	if err := s.storage.UpdateBinary(&binary); err != nil {
 		s.respondError(w, http.StatusInternalServerError, "Failed to update binary")
 		return
@@ -99,11 +141,30 @@ func (s *Server) updateBinaryHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) deleteBinaryHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	// A running build would recreate the files removed below
+	s.buildMu.Lock()
+	_, building := s.running[id]
+	s.buildMu.Unlock()
+	if building {
+		s.respondError(w, http.StatusConflict, "Binary is being built")
+		return
+	}
+
 	if err := s.storage.DeleteBinary(id); err != nil {
 		s.respondError(w, http.StatusNotFound, "Binary not found")
 		return
 	}
 
+	// The record is gone, so leftover files are only logged
+	if err := s.artifacts.Remove(id); err != nil {
+		slog.Error("Failed to remove binary files", slog.String("id", id), slog.String("error", err.Error()))
+	}
+	if s.git != nil {
+		if err := s.git.RemoveCheckout(checkoutPath(id)); err != nil {
+			slog.Error("Failed to remove checkout", slog.String("id", id), slog.String("error", err.Error()))
+		}
+	}
+
 	s.respondJSON(w, http.StatusOK, map[string]string{"message": "Binary deleted successfully"})
 }
 
@@ -117,62 +178,62 @@ func (s *Server) buildBinaryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update status to building
-	binary.Status = "building"
-	s.storage.UpdateBinary(binary)
-
-	// Build in background
-	go func() {
-		if err := s.buildBinary(binary); err != nil {
-			slog.Error("Failed to build binary",
-				slog.String("id", binary.ID),
-				slog.String("error", err.Error()))
-			binary.Status = "failed"
-		} else {
-			binary.Status = "ready"
-			binary.LastBuilt = time.Now()
-		}
-		s.storage.UpdateBinary(binary)
-	}()
-
-	s.respondJSON(w, http.StatusAccepted, map[string]string{
-		"message": "Build started",
-		"id":      binary.ID,
-	})
-}
-
-func (s *Server) buildBinary(binary *models.Binary) error {
-	// Clone or update repository
-	repoPath := fmt.Sprintf("repo_%s", binary.ID)
-	if err := s.git.CloneOrUpdate(binary.RepoURL, binary.Branch, repoPath); err != nil {
-		return fmt.Errorf("failed to clone/update repo: %w", err)
+	switch {
+	case binary.SourceType == models.SourcePrebuilt:
+		s.respondError(w, http.StatusConflict, "Prebuilt binaries are not built; upload a new executable instead")
+		return
+	case binary.SourceType == models.SourceArchive && binary.Upload == nil:
+		s.respondError(w, http.StatusConflict, "No source archive has been uploaded")
+		return
+	case binary.SourceType == models.SourceArchive:
+		// Rebuild from the stored archive
+		s.respondBuildQueued(w, binary)
+		return
 	}
 
-	// Get commit hash for version
-	commitHash, err := s.git.GetCommitHash(repoPath)
+	// Check the branch is reachable before accepting the build, so that
+	// credential, ref and network problems surface as a meaningful status
+	cred, err := s.resolveCredential(binary)
 	if err != nil {
-		return fmt.Errorf("failed to get commit hash: %w", err)
+		s.respondError(w, http.StatusFailedDependency, "Failed to load credential")
+		return
 	}
-	binary.Version = commitHash[:8]
-
-	// Build the binary
-	outputPath := filepath.Join("./data/binaries", binary.ID)
-	if err := s.git.BuildGoBinary(repoPath, binary.BuildPath, outputPath); err != nil {
-		return fmt.Errorf("failed to build binary: %w", err)
+	if _, err := s.git.RemoteHead(r.Context(), binary.RepoURL, binary.Branch, cred); err != nil {
+		status, message := gitErrorStatus(err)
+		s.respondError(w, status, message)
+		return
 	}
 
-	binary.BinaryPath = outputPath
-	return nil
+	s.respondBuildQueued(w, binary)
+}
+
+// respondBuildQueued starts a build of the binary and reports whether it had
+// to wait for a running one
+func (s *Server) respondBuildQueued(w http.ResponseWriter, binary *models.Binary) {
+	message := "Build started"
+	if !s.EnqueueBuild(binary) {
+		message = "Build queued behind the running build"
+	}
+	s.respondJSON(w, http.StatusAccepted, map[string]string{
+		"message": message,
+		"id":      binary.ID,
+	})
 }
 
 // executeBinaryHandler executes a binary
 func (s *Server) executeBinaryHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.ExecutionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+	if !s.decodeBody(w, r, maxExecutionBody, &req) {
+		return
+	}
+	if errs := validateExecution(&req); len(errs) > 0 {
+		s.respondValidation(w, errs)
 		return
 	}
 
+	rec := auditFromContext(r.Context())
+	rec.setTarget(req.BinaryID)
+
 	// Get binary
 	binary, err := s.storage.GetBinary(req.BinaryID)
 	if err != nil {
@@ -180,18 +241,60 @@ func (s *Server) executeBinaryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	key := apiKeyFromContext(r.Context())
+	if key != nil && !key.CanExecute(binary) {
+		s.respondError(w, http.StatusForbidden, "API key is not allowed to execute this binary")
+		return
+	}
+
+	if !s.limitBinary(w, binary.ID) {
+		return
+	}
+
 	if binary.Status != "ready" {
 		s.respondError(w, http.StatusBadRequest, "Binary is not ready for execution")
 		return
 	}
 
+	if err := s.verifyProvenance(binary); err != nil {
+		slog.Error("Refusing to execute binary",
+			slog.String("id", binary.ID),
+			slog.String("error", err.Error()))
+		s.respondError(w, http.StatusConflict, "Binary failed provenance verification")
+		return
+	}
+
+	if !s.reserveQuota(w, key) {
+		return
+	}
+
 	// Execute binary
-	result, err := s.executor.Execute(r.Context(), binary.BinaryPath, &req, nil)
+	result, err := s.runExecution(r.Context(), binary.SHA256, &req, key)
+	if err != nil {
+		s.releaseQuota(key)
+	}
+	if errors.Is(err, executor.ErrDigestMismatch) || errors.Is(err, executor.ErrNoDigest) {
+		slog.Error("Refusing to execute binary",
+			slog.String("id", binary.ID),
+			slog.String("error", err.Error()))
+		s.respondError(w, http.StatusConflict, "Binary failed integrity verification")
+		return
+	}
 	if err != nil {
 		slog.Error("Failed to execute binary", slog.String("error", err.Error()))
 		s.respondError(w, http.StatusInternalServerError, "Failed to execute binary")
 		return
 	}
+	if key != nil {
+		result.APIKeyID = key.ID
+		result.APIKeyName = key.Name
+		result.Team = key.Team
+	}
+	s.recordCPU(key, result)
+
+	rec.setDetail("execution_id", result.ID)
+	rec.setDetail("status", result.Status)
+	rec.setDetail("exit_code", strconv.Itoa(result.ExitCode))
 
 	// Save execution result
 	if err := s.storage.SaveExecution(result); err != nil {
@@ -205,8 +308,15 @@ func (s *Server) executeBinaryHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) getExecutionHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	key := apiKeyFromContext(r.Context())
+	if key != nil && !key.HasScope(models.ScopeReadExecutions) {
+		s.respondError(w, http.StatusForbidden, "API key is not allowed to read executions")
+		return
+	}
+
 	result, err := s.storage.GetExecution(id)
-	if err != nil {
+	// Executions of other keys are reported as missing
+	if err != nil || (key != nil && !key.CanAccess(result.APIKeyID, result.Team)) {
 		s.respondError(w, http.StatusNotFound, "Execution not found")
 		return
 	}
@@ -218,6 +328,16 @@ func (s *Server) getExecutionHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) stopExecutionHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	if key := apiKeyFromContext(r.Context()); key != nil {
+		s.execMu.Lock()
+		owner, running := s.executions[id]
+		s.execMu.Unlock()
+		if !running || owner == nil || !key.CanAccess(owner.ID, owner.Team) {
+			s.respondError(w, http.StatusNotFound, "Execution not found or already stopped")
+			return
+		}
+	}
+
 	if err := s.executor.StopExecution(id); err != nil {
 		s.respondError(w, http.StatusNotFound, "Execution not found or already stopped")
 		return
@@ -226,6 +346,30 @@ func (s *Server) stopExecutionHandler(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, http.StatusOK, map[string]string{"message": "Execution stopped"})
 }
 
+// runExecution executes req, recording while it runs that key started it
+func (s *Server) runExecution(ctx context.Context, digest string, req *models.ExecutionRequest, key *models.APIKey) (*models.ExecutionResult, error) {
+	started := make(chan string, 1)
+	tracked := make(chan string, 1)
+	go func() {
+		defer close(tracked)
+		for id := range started {
+			s.execMu.Lock()
+			s.executions[id] = key
+			s.execMu.Unlock()
+			tracked <- id
+		}
+	}()
+
+	result, err := s.executor.Execute(ctx, digest, req, started)
+	close(started)
+	if id, ok := <-tracked; ok {
+		s.execMu.Lock()
+		delete(s.executions, id)
+		s.execMu.Unlock()
+	}
+	return result, err
+}
+
 // Helper functions
 func (s *Server) respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
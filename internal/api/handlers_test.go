@@ -4,15 +4,25 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/apikeys"
+	"go_runner/internal/artifacts"
 	"go_runner/internal/config"
 	"go_runner/internal/models"
+	"go_runner/internal/provenance"
+	"go_runner/internal/repository"
 	"go_runner/internal/storage"
 )
 
@@ -66,28 +76,55 @@ type MockGitManager struct {
 	mock.Mock
 }
 
-func (m *MockGitManager) CloneOrUpdate(repoURL, branch, targetPath string) error {
-	args := m.Called(repoURL, branch, targetPath)
+func (m *MockGitManager) CloneOrUpdate(ctx context.Context, repoURL, branch, targetPath, buildPath string, opts models.CloneOptions, cred *models.Credential) error {
+	args := m.Called(repoURL, branch, targetPath, buildPath, opts, cred)
 	return args.Error(0)
 }
 
-func (m *MockGitManager) GetCommitHash(repoPath string) (string, error) {
+func (m *MockGitManager) RemoteHead(ctx context.Context, repoURL, branch string, cred *models.Credential) (string, error) {
+	args := m.Called(repoURL, branch, cred)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockGitManager) GetCommitHash(ctx context.Context, repoPath string) (string, error) {
 	args := m.Called(repoPath)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockGitManager) BuildGoBinary(repoPath, buildPath, outputPath string) error {
-	args := m.Called(repoPath, buildPath, outputPath)
+func (m *MockGitManager) ExtractSource(repoPath, archivePath string) error {
+	args := m.Called(repoPath, archivePath)
+	return args.Error(0)
+}
+
+func (m *MockGitManager) ResolveToolchain(repoPath, buildPath, goVersion string) (*models.Toolchain, error) {
+	args := m.Called(repoPath, buildPath, goVersion)
+	tc, _ := args.Get(0).(*models.Toolchain)
+	return tc, args.Error(1)
+}
+
+func (m *MockGitManager) BuildGoBinary(repoPath, buildPath, outputPath string, tc *models.Toolchain, opts models.BuildOptions) error {
+	args := m.Called(repoPath, buildPath, outputPath, tc, opts)
+	return args.Error(0)
+}
+
+func (m *MockGitManager) RemoveCheckout(repoPath string) error {
+	args := m.Called(repoPath)
 	return args.Error(0)
 }
 
+func (m *MockGitManager) RunGates(ctx context.Context, repoPath, buildPath string, tc *models.Toolchain, opts models.BuildOptions, gates models.Gates) []models.GateResult {
+	args := m.Called(repoPath, buildPath, tc, opts, gates)
+	results, _ := args.Get(0).([]models.GateResult)
+	return results
+}
+
 // MockExecutor is a mock implementation of the Executor interface
 type MockExecutor struct {
 	mock.Mock
 }
 
-func (m *MockExecutor) Execute(ctx context.Context, binaryPath string, req *models.ExecutionRequest, started chan<- string) (*models.ExecutionResult, error) {
-	args := m.Called(ctx, binaryPath, req, started)
+func (m *MockExecutor) Execute(ctx context.Context, digest string, req *models.ExecutionRequest, started chan<- string) (*models.ExecutionResult, error) {
+	args := m.Called(ctx, digest, req, started)
 	return args.Get(0).(*models.ExecutionResult), args.Error(1)
 }
 
@@ -111,28 +148,75 @@ func TestHealthHandler(t *testing.T) {
 	assert.Equal(t, "ok", response["status"])
 }
 
-// getAdminCookie simulates a login and returns the admin_token cookie
-func getAdminCookie(t *testing.T, server *Server) *http.Cookie {
-	// Set a temporary ADMIN_TOKEN for the test
-	t.Setenv("ADMIN_TOKEN", "test-admin-token")
+// newTestAPIKey gives server an API key store and returns a valid key
+func newTestAPIKey(t *testing.T, server *Server) string {
+	t.Helper()
+	_, token := issueTestAPIKey(t, server, &models.APIKey{Name: "test"})
+	return token
+}
+
+// issueTestAPIKey creates a key from spec, giving server a store if it has none
+func issueTestAPIKey(t *testing.T, server *Server, spec *models.APIKey) (*models.APIKey, string) {
+	t.Helper()
+	if server.apiKeys == nil {
+		store, err := apikeys.NewFileStore(t.TempDir())
+		require.NoError(t, err)
+		server.apiKeys = store
+	}
+	key, token, err := server.apiKeys.Create(spec)
+	require.NoError(t, err)
+	return key, token
+}
+
+// testSession is a signed-in admin UI session
+type testSession struct {
+	cookie *http.Cookie
+	csrf   string
+}
+
+// authorize adds the session cookie and its CSRF token to req
+func (s *testSession) authorize(req *http.Request) {
+	req.AddCookie(s.cookie)
+	req.Header.Set("X-CSRF-Token", s.csrf)
+}
 
-	// Simulate login request
-	form := bytes.NewBufferString("token=test-admin-token")
-	req, _ := http.NewRequest("POST", "/login", form)
+// loginAdmin simulates a login with the admin token
+func loginAdmin(t *testing.T, server *Server) *testSession {
+	// Set a temporary admin token for the test
+	server.adminToken = "test-admin-token"
+	return login(t, server, url.Values{"token": {"test-admin-token"}})
+}
+
+// login posts form to /login and returns the session it starts
+func login(t *testing.T, server *Server, form url.Values) *testSession {
+	t.Helper()
+	req, _ := http.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	rr := httptest.NewRecorder()
 	server.router.ServeHTTP(rr, req)
 
-	assert.Equal(t, http.StatusSeeOther, rr.Code) // Should redirect after successful login
+	require.Equal(t, http.StatusSeeOther, rr.Code, rr.Body.String()) // Should redirect after successful login
 
-	// Find the admin_token cookie
+	// Find the session cookie
+	sess := &testSession{}
 	for _, cookie := range rr.Result().Cookies() {
-		if cookie.Name == "admin_token" {
-			return cookie
+		if cookie.Name == sessionCookie {
+			sess.cookie = cookie
 		}
 	}
-	t.Fatal("admin_token cookie not found after login")
-	return nil
+	require.NotNil(t, sess.cookie, "session cookie not found after login")
+
+	// The CSRF token is handed out by the current session endpoint
+	req, _ = http.NewRequest("GET", "/api/v1/sessions/current", nil)
+	req.AddCookie(sess.cookie)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var current models.Session
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &current))
+	require.NotEmpty(t, current.CSRFToken)
+	sess.csrf = current.CSRFToken
+	return sess
 }
 
 func TestListBinariesHandler(t *testing.T) {
@@ -140,13 +224,13 @@ func TestListBinariesHandler(t *testing.T) {
 	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
 
 	// Get admin cookie
-	adminCookie := getAdminCookie(t, server)
+	adminSession := loginAdmin(t, server)
 
 	binaries := []*models.Binary{{ID: "1", Name: "test"}}
 	mockStorage.On("ListBinaries").Return(binaries, nil)
 
 	req, _ := http.NewRequest("GET", "/api/v1/binaries", nil)
-	req.AddCookie(adminCookie) // Add the admin cookie to the request
+	adminSession.authorize(req) // Add the admin cookie to the request
 	rr := httptest.NewRecorder()
 	server.router.ServeHTTP(rr, req)
 
@@ -159,14 +243,14 @@ func TestCreateBinaryHandler(t *testing.T) {
 	mockStorage := new(MockStorage)
 	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
 
-	adminCookie := getAdminCookie(t, server)
+	adminSession := loginAdmin(t, server)
 
-	binary := &models.Binary{Name: "test"}
+	binary := &models.Binary{Name: "test", RepoURL: "https://github.com/example/test.git", Branch: "main"}
 	mockStorage.On("SaveBinary", mock.AnythingOfType("*models.Binary")).Return(nil)
 
 	body, _ := json.Marshal(binary)
 	req, _ := http.NewRequest("POST", "/api/v1/binaries", bytes.NewBuffer(body))
-	req.AddCookie(adminCookie)
+	adminSession.authorize(req)
 	rr := httptest.NewRecorder()
 	server.router.ServeHTTP(rr, req)
 
@@ -175,17 +259,61 @@ func TestCreateBinaryHandler(t *testing.T) {
 	mockStorage.AssertExpectations(t)
 }
 
+func TestCreateBinaryHandler_InvalidBuildOptions(t *testing.T) {
+	mockStorage := new(MockStorage)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
+
+	adminSession := loginAdmin(t, server)
+
+	binary := &models.Binary{
+		Name:         "test",
+		BuildOptions: &models.BuildOptions{LDFlags: "-X main.version=1 -extldflags=-static"},
+	}
+
+	body, _ := json.Marshal(binary)
+	req, _ := http.NewRequest("POST", "/api/v1/binaries", bytes.NewBuffer(body))
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "not allowed")
+	mockStorage.AssertNotCalled(t, "SaveBinary", mock.Anything)
+}
+
+func TestCreateBinaryHandler_InvalidCloneOptions(t *testing.T) {
+	mockStorage := new(MockStorage)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
+
+	adminSession := loginAdmin(t, server)
+
+	binary := &models.Binary{
+		Name:         "test",
+		CloneOptions: &models.CloneOptions{Sparse: true, SparsePaths: []string{"../outside"}},
+	}
+
+	body, _ := json.Marshal(binary)
+	req, _ := http.NewRequest("POST", "/api/v1/binaries", bytes.NewBuffer(body))
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "inside the repository")
+	mockStorage.AssertNotCalled(t, "SaveBinary", mock.Anything)
+}
+
 func TestGetBinaryHandler(t *testing.T) {
 	mockStorage := new(MockStorage)
 	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
 
-	adminCookie := getAdminCookie(t, server)
+	adminSession := loginAdmin(t, server)
 
 	binary := &models.Binary{ID: "1", Name: "test"}
 	mockStorage.On("GetBinary", "1").Return(binary, nil)
 
 	req, _ := http.NewRequest("GET", "/api/v1/binaries/1", nil)
-	req.AddCookie(adminCookie)
+	adminSession.authorize(req)
 	rr := httptest.NewRecorder()
 
 	server.router.ServeHTTP(rr, req)
@@ -199,12 +327,12 @@ func TestGetBinaryHandler_NotFound(t *testing.T) {
 	mockStorage := new(MockStorage)
 	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
 
-	adminCookie := getAdminCookie(t, server)
+	adminSession := loginAdmin(t, server)
 
 	mockStorage.On("GetBinary", "nonexistent").Return(&models.Binary{}, storage.ErrBinaryNotFound)
 
 	req, _ := http.NewRequest("GET", "/api/v1/binaries/nonexistent", nil)
-	req.AddCookie(adminCookie)
+	adminSession.authorize(req)
 	rr := httptest.NewRecorder()
 
 	server.router.ServeHTTP(rr, req)
@@ -218,32 +346,50 @@ func TestUpdateBinaryHandler(t *testing.T) {
 	mockStorage := new(MockStorage)
 	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
 
-	adminCookie := getAdminCookie(t, server)
+	adminSession := loginAdmin(t, server)
 
-	binary := &models.Binary{ID: "1", Name: "updated"}
+	stored := &models.Binary{
+		ID:         "1",
+		Name:       "test",
+		BinaryPath: "/data/binaries/1",
+		SHA256:     "abc123",
+		Status:     "ready",
+		Artifacts:  []models.Artifact{{Target: models.Target{GOOS: "linux", GOARCH: "amd64"}, BuildID: "b1", SHA256: "def456"}},
+	}
+	mockStorage.On("GetBinary", "1").Return(stored, nil)
 	mockStorage.On("UpdateBinary", mock.AnythingOfType("*models.Binary")).Return(nil)
 
-	body, _ := json.Marshal(binary)
-	req, _ := http.NewRequest("PUT", "/api/v1/binaries/1", bytes.NewBuffer(body))
-	req.AddCookie(adminCookie)
+	// Build results in the body are ignored; only the settings are applied
+	body := `{"name":"updated","repo_url":"https://github.com/example/test.git","branch":"main",
+		"status":"pending","sha256":"evil","binary_path":"/bin/sh",
+		"artifacts":[{"goos":"linux","goarch":"amd64","build_id":"../..","path":"/etc/passwd"}]}`
+	req, _ := http.NewRequest("PUT", "/api/v1/binaries/1", strings.NewReader(body))
+	adminSession.authorize(req)
 	rr := httptest.NewRecorder()
 
 	server.router.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 	mockStorage.AssertExpectations(t)
+	updated := mockStorage.Calls[len(mockStorage.Calls)-1].Arguments.Get(0).(*models.Binary)
+	assert.Equal(t, "updated", updated.Name)
+	assert.Equal(t, "https://github.com/example/test.git", updated.RepoURL)
+	assert.Equal(t, "ready", updated.Status)
+	assert.Equal(t, "abc123", updated.SHA256)
+	assert.Equal(t, "/data/binaries/1", updated.BinaryPath)
+	assert.Equal(t, stored.Artifacts, updated.Artifacts)
 }
 
 func TestDeleteBinaryHandler(t *testing.T) {
 	mockStorage := new(MockStorage)
 	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
 
-	adminCookie := getAdminCookie(t, server)
+	adminSession := loginAdmin(t, server)
 
 	mockStorage.On("DeleteBinary", "1").Return(nil)
 
 	req, _ := http.NewRequest("DELETE", "/api/v1/binaries/1", nil)
-	req.AddCookie(adminCookie)
+	adminSession.authorize(req)
 	rr := httptest.NewRecorder()
 
 	server.router.ServeHTTP(rr, req)
@@ -252,12 +398,58 @@ func TestDeleteBinaryHandler(t *testing.T) {
 	mockStorage.AssertExpectations(t)
 }
 
+func TestDeleteBinaryHandler_RemovesFiles(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockGit := new(MockGitManager)
+	store := artifacts.NewLocalStore(t.TempDir())
+	server := NewServer(config.ServerConfig{}, mockStorage, mockGit, nil, WithArtifactStore(store))
+
+	adminSession := loginAdmin(t, server)
+
+	for _, path := range []string{store.BinaryPath("1"), provenancePath(store.BinaryPath("1")), store.UploadPath("1")} {
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0644))
+	}
+	mockStorage.On("DeleteBinary", "1").Return(nil).Once()
+	mockGit.On("RemoveCheckout", "repo_1").Return(nil).Once()
+
+	// Not while a build could write new files
+	server.running["1"] = false
+	req, _ := http.NewRequest("DELETE", "/api/v1/binaries/1", nil)
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.FileExists(t, store.BinaryPath("1"))
+
+	delete(server.running, "1")
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockStorage.AssertExpectations(t)
+	mockGit.AssertExpectations(t)
+	assert.NoFileExists(t, store.BinaryPath("1"))
+	assert.NoFileExists(t, provenancePath(store.BinaryPath("1")))
+	assert.NoFileExists(t, store.UploadPath("1"))
+}
+
+// waitForBuilds waits until no build is running, after which the binaries
+// they were given can be inspected
+func waitForBuilds(t *testing.T, server *Server) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		server.buildMu.Lock()
+		defer server.buildMu.Unlock()
+		return len(server.running) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
 func TestBuildBinaryHandler(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockGit := new(MockGitManager)
 	server := NewServer(config.ServerConfig{}, mockStorage, mockGit, nil)
 
-	adminCookie := getAdminCookie(t, server)
+	adminSession := loginAdmin(t, server)
 
 	binary := &models.Binary{
 		ID:        "1",
@@ -268,24 +460,252 @@ func TestBuildBinaryHandler(t *testing.T) {
 	}
 	mockStorage.On("GetBinary", "1").Return(binary, nil).Once()
 	mockStorage.On("UpdateBinary", mock.AnythingOfType("*models.Binary")).Return(nil).Twice()
-	mockGit.On("CloneOrUpdate", binary.RepoURL, binary.Branch, mock.AnythingOfType("string")).Return(nil).Once()
+	mockGit.On("RemoteHead", binary.RepoURL, binary.Branch, (*models.Credential)(nil)).Return("abcdef123456", nil).Once()
+	mockGit.On("CloneOrUpdate", binary.RepoURL, binary.Branch, mock.AnythingOfType("string"), binary.BuildPath, models.CloneOptions{}, (*models.Credential)(nil)).Return(nil).Once()
 	mockGit.On("GetCommitHash", mock.AnythingOfType("string")).Return("abcdef123456", nil).Once()
-	mockGit.On("BuildGoBinary", mock.AnythingOfType("string"), binary.BuildPath, mock.AnythingOfType("string")).Return(nil).Once()
+	mockGit.On("ResolveToolchain", mock.AnythingOfType("string"), binary.BuildPath, "").Return(&models.Toolchain{Version: "go1.22.0", Source: "cache"}, nil).Once()
+	mockGit.On("BuildGoBinary", mock.AnythingOfType("string"), binary.BuildPath, mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("models.BuildOptions")).Return(nil).Once()
 
 	req, _ := http.NewRequest("POST", "/api/v1/binaries/1/build", nil)
-	req.AddCookie(adminCookie)
+	adminSession.authorize(req)
 	rr := httptest.NewRecorder()
 
 	server.router.ServeHTTP(rr, req)
 
-	// Give the goroutine a moment to execute
-	time.Sleep(100 * time.Millisecond)
-
 	assert.Equal(t, http.StatusAccepted, rr.Code)
+	waitForBuilds(t, server)
 	mockStorage.AssertExpectations(t)
 	mockGit.AssertExpectations(t)
 }
 
+func TestBuildBinaryHandler_GateFailureKeepsPreviousVersion(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockGit := new(MockGitManager)
+	server := NewServer(config.ServerConfig{}, mockStorage, mockGit, nil)
+
+	adminSession := loginAdmin(t, server)
+
+	gates := models.Gates{Vet: true, Test: true}
+	binary := &models.Binary{
+		ID:         "1",
+		RepoURL:    "http://example.com/repo.git",
+		Branch:     "main",
+		BuildPath:  ".",
+		Gates:      &gates,
+		BinaryPath: "./data/binaries/1",
+		Version:    "11111111",
+		Commit:     "1111111111111111",
+		Status:     "ready",
+	}
+	mockStorage.On("GetBinary", "1").Return(binary, nil).Once()
+	mockStorage.On("UpdateBinary", mock.AnythingOfType("*models.Binary")).Return(nil).Twice()
+	mockGit.On("RemoteHead", binary.RepoURL, binary.Branch, (*models.Credential)(nil)).Return("abcdef123456", nil).Once()
+	mockGit.On("CloneOrUpdate", binary.RepoURL, binary.Branch, mock.AnythingOfType("string"), binary.BuildPath, models.CloneOptions{}, (*models.Credential)(nil)).Return(nil).Once()
+	mockGit.On("GetCommitHash", mock.AnythingOfType("string")).Return("abcdef123456", nil).Once()
+	mockGit.On("ResolveToolchain", mock.AnythingOfType("string"), binary.BuildPath, "").Return(nil, nil).Once()
+	mockGit.On("RunGates", mock.AnythingOfType("string"), binary.BuildPath, (*models.Toolchain)(nil), mock.AnythingOfType("models.BuildOptions"), gates).Return([]models.GateResult{
+		{Name: "vet", Status: "passed"},
+		{Name: "test", Status: "failed", ExitCode: 1, Output: "--- FAIL: TestSomething"},
+	}).Once()
+
+	req, _ := http.NewRequest("POST", "/api/v1/binaries/1/build", nil)
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	waitForBuilds(t, server)
+
+	mockGit.AssertExpectations(t)
+	mockGit.AssertNotCalled(t, "BuildGoBinary", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, "ready", binary.Status)
+	assert.Equal(t, "11111111", binary.Version)
+	assert.Equal(t, "failed", binary.LastBuild.Status)
+	assert.Equal(t, "abcdef123456", binary.LastBuild.Commit)
+	assert.Contains(t, binary.LastBuild.Error, "quality gates failed: test")
+	assert.Len(t, binary.LastBuild.Gates, 2)
+}
+
+func TestBuildBinaryHandler_ExpandedLDFlags(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockGit := new(MockGitManager)
+	server := NewServer(config.ServerConfig{}, mockStorage, mockGit, nil)
+
+	adminSession := loginAdmin(t, server)
+
+	// Stored before branches were validated
+	binary := &models.Binary{
+		ID:           "1",
+		RepoURL:      "http://example.com/repo.git",
+		Branch:       "x -extld=/tmp/evil",
+		BuildPath:    ".",
+		BuildOptions: &models.BuildOptions{LDFlags: "-X main.branch=${BRANCH}"},
+		Status:       "pending",
+	}
+	mockStorage.On("GetBinary", "1").Return(binary, nil).Once()
+	mockStorage.On("UpdateBinary", mock.AnythingOfType("*models.Binary")).Return(nil).Twice()
+	mockGit.On("RemoteHead", binary.RepoURL, binary.Branch, (*models.Credential)(nil)).Return("abcdef123456", nil).Once()
+	mockGit.On("CloneOrUpdate", binary.RepoURL, binary.Branch, mock.AnythingOfType("string"), binary.BuildPath, models.CloneOptions{}, (*models.Credential)(nil)).Return(nil).Once()
+	mockGit.On("GetCommitHash", mock.AnythingOfType("string")).Return("abcdef123456", nil).Once()
+
+	req, _ := http.NewRequest("POST", "/api/v1/binaries/1/build", nil)
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	waitForBuilds(t, server)
+
+	mockGit.AssertExpectations(t)
+	mockGit.AssertNotCalled(t, "BuildGoBinary", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, "failed", binary.LastBuild.Status)
+	assert.Contains(t, binary.LastBuild.Error, `flag "-extld=/tmp/evil" is not allowed`)
+}
+
+func TestBuildBinaryHandler_GitErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"auth", &repository.GitError{Op: "ls-remote", Kind: repository.ErrAuthFailed, Err: errors.New("exit status 128")}, http.StatusFailedDependency},
+		{"ref", &repository.GitError{Op: "ls-remote", Kind: repository.ErrRefNotFound, Err: errors.New("exit status 2")}, http.StatusUnprocessableEntity},
+		{"network", &repository.GitError{Op: "ls-remote", Kind: repository.ErrNetworkUnreachable, Err: errors.New("exit status 128")}, http.StatusBadGateway},
+		{"timeout", &repository.GitError{Op: "ls-remote", Kind: repository.ErrTimeout, Err: context.DeadlineExceeded}, http.StatusGatewayTimeout},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			mockGit := new(MockGitManager)
+			server := NewServer(config.ServerConfig{}, mockStorage, mockGit, nil)
+
+			adminSession := loginAdmin(t, server)
+
+			binary := &models.Binary{ID: "1", RepoURL: "http://example.com/repo.git", Branch: "main", Status: "pending"}
+			mockStorage.On("GetBinary", "1").Return(binary, nil).Once()
+			mockGit.On("RemoteHead", binary.RepoURL, binary.Branch, (*models.Credential)(nil)).Return("", tc.err).Once()
+
+			req, _ := http.NewRequest("POST", "/api/v1/binaries/1/build", nil)
+			adminSession.authorize(req)
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.status, rr.Code)
+			assert.Equal(t, "pending", binary.Status)
+			mockStorage.AssertNotCalled(t, "UpdateBinary", mock.Anything)
+		})
+	}
+}
+
+func TestBuildBinaryHandler_Targets(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockGit := new(MockGitManager)
+	server := NewServer(config.ServerConfig{}, mockStorage, mockGit, nil)
+
+	adminSession := loginAdmin(t, server)
+
+	binary := &models.Binary{
+		ID:        "targets-test",
+		RepoURL:   "http://example.com/repo.git",
+		Branch:    "main",
+		BuildPath: ".",
+		Status:    "pending",
+		Targets:   []models.Target{{GOOS: "windows", GOARCH: "amd64"}},
+	}
+	t.Cleanup(func() {
+		os.RemoveAll("./data/binaries")
+		os.Remove("./data")
+	})
+
+	isWindows := mock.MatchedBy(func(opts models.BuildOptions) bool { return opts.GOOS == "windows" })
+	mockStorage.On("GetBinary", binary.ID).Return(binary, nil).Once()
+	mockStorage.On("UpdateBinary", mock.AnythingOfType("*models.Binary")).Return(nil).Twice()
+	mockGit.On("RemoteHead", binary.RepoURL, binary.Branch, (*models.Credential)(nil)).Return("abcdef123456", nil).Once()
+	mockGit.On("CloneOrUpdate", binary.RepoURL, binary.Branch, mock.AnythingOfType("string"), binary.BuildPath, models.CloneOptions{}, (*models.Credential)(nil)).Return(nil).Once()
+	mockGit.On("GetCommitHash", mock.AnythingOfType("string")).Return("abcdef123456", nil).Once()
+	mockGit.On("ResolveToolchain", mock.AnythingOfType("string"), binary.BuildPath, "").Return(&models.Toolchain{Version: "go1.22.0", Source: "cache"}, nil).Once()
+	mockGit.On("BuildGoBinary", mock.AnythingOfType("string"), binary.BuildPath, mock.AnythingOfType("string"), mock.Anything, isWindows).
+		Run(func(args mock.Arguments) {
+			out := args.String(2)
+			os.MkdirAll(filepath.Dir(out), 0755)
+			os.WriteFile(out, []byte("artifact"), 0755)
+		}).Return(nil).Once()
+	mockGit.On("BuildGoBinary", mock.AnythingOfType("string"), binary.BuildPath, mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("models.BuildOptions")).
+		Run(func(args mock.Arguments) {
+			out := args.String(2)
+			os.MkdirAll(filepath.Dir(out), 0755)
+			os.WriteFile(out, []byte("binary"), 0755)
+		}).Return(nil).Once()
+
+	req, _ := http.NewRequest("POST", "/api/v1/binaries/"+binary.ID+"/build", nil)
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	waitForBuilds(t, server)
+	mockGit.AssertExpectations(t)
+	if assert.Len(t, binary.Artifacts, 1) {
+		// sha256("artifact")
+		assert.Equal(t, "c7c5c1d70c5dec4416ab6158afd0b223ef40c29b1dc1f97ed9428b94d4cadb1c", binary.Artifacts[0].SHA256)
+		assert.Equal(t, int64(len("artifact")), binary.Artifacts[0].Size)
+		assert.Equal(t, binary.LastBuild.ID, binary.Artifacts[0].BuildID)
+	}
+
+	// The provenance covers the executable and every artifact
+	env, err := provenance.ReadFile(provenancePath(binary.BinaryPath))
+	require.NoError(t, err)
+	stmt, err := env.Statement()
+	require.NoError(t, err)
+	digest, _ := stmt.SubjectDigest(binary.ID)
+	assert.Equal(t, binary.SHA256, digest)
+	digest, _ = stmt.SubjectDigest("windows-amd64")
+	assert.Equal(t, binary.Artifacts[0].SHA256, digest)
+	assert.Equal(t, "abcdef123456", stmt.Predicate.BuildDefinition.ResolvedDependencies[0].Digest["gitCommit"])
+}
+
+func TestDownloadArtifactHandler(t *testing.T) {
+	mockStorage := new(MockStorage)
+	store := artifacts.NewLocalStore(t.TempDir())
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil, WithArtifactStore(store))
+
+	adminSession := loginAdmin(t, server)
+
+	dir := store.ArtifactDir("1", "build-1")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "linux-arm64"), []byte("ELF"), 0755))
+
+	binary := &models.Binary{
+		ID:   "1",
+		Name: "tool",
+		Artifacts: []models.Artifact{{
+			Target:  models.Target{GOOS: "linux", GOARCH: "arm64"},
+			BuildID: "build-1",
+			SHA256:  "deadbeef",
+			Size:    3,
+		}},
+	}
+	mockStorage.On("GetBinary", "1").Return(binary, nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/binaries/1/artifacts/linux-arm64", nil)
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "ELF", rr.Body.String())
+	assert.Equal(t, "deadbeef", rr.Header().Get("X-Checksum-Sha256"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "tool-linux-arm64")
+
+	req, _ = http.NewRequest("GET", "/api/v1/binaries/1/artifacts/darwin-arm64", nil)
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
 func TestExecuteBinaryHandler(t *testing.T) {
 	mockStorage := new(MockStorage)
 	mockExecutor := new(MockExecutor)
@@ -300,18 +720,20 @@ func TestExecuteBinaryHandler(t *testing.T) {
 	executionResult := &models.ExecutionResult{ID: "exec1", Status: "completed"}
 
 	mockStorage.On("GetBinary", "1").Return(binary, nil).Once()
-	mockExecutor.On("Execute", mock.Anything, binary.BinaryPath, executionReq, mock.Anything).Return(executionResult, nil).Once()
+	mockExecutor.On("Execute", mock.Anything, binary.SHA256, executionReq, mock.Anything).Return(executionResult, nil).Once()
 	mockStorage.On("SaveExecution", executionResult).Return(nil).Once()
 
 	body, _ := json.Marshal(executionReq)
 	req, _ := http.NewRequest("POST", "/api/v1/execute", bytes.NewBuffer(body))
-	req.Header.Set("X-API-Key", "test-api-key") // Add API key
+	req.Header.Set("X-API-Key", newTestAPIKey(t, server))
 	rr := httptest.NewRecorder()
 
 	server.router.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, rr.Body.String(), "completed")
+	assert.Equal(t, "test", executionResult.APIKeyName)
+	assert.NotEmpty(t, executionResult.APIKeyID)
 	mockStorage.AssertExpectations(t)
 	mockExecutor.AssertExpectations(t)
 }
@@ -320,11 +742,12 @@ func TestGetExecutionHandler(t *testing.T) {
 	mockStorage := new(MockStorage)
 	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
 
-	executionResult := &models.ExecutionResult{ID: "exec1", Status: "completed"}
+	key, token := issueTestAPIKey(t, server, &models.APIKey{Name: "test"})
+	executionResult := &models.ExecutionResult{ID: "exec1", APIKeyID: key.ID, Status: "completed"}
 	mockStorage.On("GetExecution", "exec1").Return(executionResult, nil).Once()
 
 	req, _ := http.NewRequest("GET", "/api/v1/execute/exec1", nil)
-	req.Header.Set("X-API-Key", "test-api-key") // Add API key
+	req.Header.Set("X-API-Key", token)
 	rr := httptest.NewRecorder()
 
 	server.router.ServeHTTP(rr, req)
@@ -339,9 +762,11 @@ func TestStopExecutionHandler(t *testing.T) {
 	server := NewServer(config.ServerConfig{}, nil, nil, mockExecutor)
 
 	mockExecutor.On("StopExecution", "exec1").Return(nil).Once()
+	key, token := issueTestAPIKey(t, server, &models.APIKey{Name: "test"})
+	server.executions["exec1"] = key
 
 	req, _ := http.NewRequest("DELETE", "/api/v1/execute/exec1", nil)
-	req.Header.Set("X-API-Key", "test-api-key") // Add API key
+	req.Header.Set("X-API-Key", token)
 	rr := httptest.NewRecorder()
 
 	server.router.ServeHTTP(rr, req)
@@ -349,4 +774,4 @@ func TestStopExecutionHandler(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Contains(t, rr.Body.String(), "Execution stopped")
 	mockExecutor.AssertExpectations(t)
-}
\ No newline at end of file
+}
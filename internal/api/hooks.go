@@ -0,0 +1,81 @@
+// internal/api/hooks.go
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go_runner/internal/models"
+	"go_runner/internal/webhook"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxHookPayload matches the largest payload GitHub delivers
+const maxHookPayload = 25 << 20
+
+// webhookHandler receives push events and builds every binary tracking the pushed branch
+func (s *Server) webhookHandler(w http.ResponseWriter, r *http.Request) {
+	if s.hookSecret == "" {
+		s.respondError(w, http.StatusNotFound, "Webhooks are not configured")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHookPayload))
+	if err != nil {
+		s.respondError(w, http.StatusRequestEntityTooLarge, "Payload too large")
+		return
+	}
+
+	provider := chi.URLParam(r, "provider")
+	rec := auditFromContext(r.Context())
+	rec.setActor(models.ActorWebhook, provider)
+	push, err := webhook.Parse(provider, r.Header, body, s.hookSecret)
+	switch {
+	case errors.Is(err, webhook.ErrUnknownProvider):
+		s.respondError(w, http.StatusNotFound, "Unknown webhook provider")
+		return
+	case errors.Is(err, webhook.ErrInvalidSignature):
+		s.respondError(w, http.StatusUnauthorized, "Invalid webhook signature")
+		return
+	case errors.Is(err, webhook.ErrIgnoredEvent):
+		s.respondJSON(w, http.StatusOK, map[string]string{"message": "Event ignored"})
+		return
+	case err != nil:
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	binaries, err := s.storage.ListBinaries()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to list binaries")
+		return
+	}
+
+	builds := []string{}
+	for _, binary := range binaries {
+		if !binary.IsGit() || !push.Matches(binary.RepoURL, binary.Branch) {
+			continue
+		}
+		s.EnqueueBuild(binary)
+		builds = append(builds, binary.ID)
+	}
+
+	rec.setTarget(push.Branch)
+	rec.setDetail("commit", push.Commit)
+	rec.setDetail("builds", strings.Join(builds, ","))
+
+	slog.Info("Webhook received",
+		slog.String("provider", push.Provider),
+		slog.String("branch", push.Branch),
+		slog.String("commit", push.Commit),
+		slog.Int("builds", len(builds)))
+
+	s.respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "Push received",
+		"builds":  builds,
+	})
+}
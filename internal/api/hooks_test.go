@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/repository"
+	"go_runner/internal/storage"
+	"go_runner/internal/webhook"
+)
+
+// cloneOnlyGitManager fetches sources for real but skips compiling them
+type cloneOnlyGitManager struct {
+	*repository.GitManager
+}
+
+func (cloneOnlyGitManager) BuildGoBinary(repoPath, buildPath, outputPath string, tc *models.Toolchain, opts models.BuildOptions) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, []byte("binary built from "+repoPath), 0755)
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+func signedHook(t *testing.T, provider, secret string, payload interface{}) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+	req, _ := http.NewRequest("POST", "/api/v1/hooks/"+provider, bytes.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+webhook.Sign(body, secret))
+	return req
+}
+
+func TestWebhookHandler_BuildsMatchingBinaries(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	// Upstream bare repository with a working copy to push from
+	root := t.TempDir()
+	bare := filepath.Join(root, "upstream.git")
+	work := filepath.Join(root, "work")
+	git(t, root, "init", "--bare", "-b", "main", bare)
+	git(t, root, "clone", bare, work)
+	require.NoError(t, os.WriteFile(filepath.Join(work, "go.mod"), []byte("module example.com/hello\n"), 0644))
+	git(t, work, "add", "go.mod")
+	git(t, work, "commit", "-m", "init")
+	git(t, work, "push", "origin", "HEAD:main")
+
+	store := storage.NewFileStorage(t.TempDir())
+	require.NoError(t, store.Init())
+	gm := cloneOnlyGitManager{repository.NewGitManager(t.TempDir())}
+	server := NewServer(config.ServerConfig{}, store, gm, nil, WithWebhookSecret("hook-secret"))
+	t.Cleanup(func() {
+		os.RemoveAll("./data/binaries")
+		os.Remove("./data")
+	})
+
+	tracked := &models.Binary{ID: "tracked", Name: "tracked", RepoURL: bare, Branch: "main", BuildPath: "."}
+	otherBranch := &models.Binary{ID: "other-branch", Name: "other", RepoURL: bare, Branch: "release", BuildPath: "."}
+	otherRepo := &models.Binary{ID: "other-repo", Name: "other", RepoURL: "https://example.com/other.git", Branch: "main", BuildPath: "."}
+	for _, b := range []*models.Binary{tracked, otherBranch, otherRepo} {
+		require.NoError(t, store.SaveBinary(b))
+	}
+
+	// Push a new commit and deliver the matching event
+	require.NoError(t, os.WriteFile(filepath.Join(work, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	git(t, work, "add", "main.go")
+	git(t, work, "commit", "-m", "add main")
+	git(t, work, "push", "origin", "HEAD:main")
+	commit := git(t, work, "rev-parse", "HEAD")
+
+	req := signedHook(t, "generic", "hook-secret", map[string]string{
+		"repo_url": "file://" + bare + "/",
+		"branch":   "main",
+		"commit":   commit,
+	})
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	var resp struct {
+		Builds []string `json:"builds"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"tracked"}, resp.Builds)
+
+	assert.Eventually(t, func() bool {
+		b, err := store.GetBinary("tracked")
+		return err == nil && b.Status == "ready"
+	}, 10*time.Second, 20*time.Millisecond)
+	b, _ := store.GetBinary("tracked")
+	assert.Equal(t, commit[:8], b.Version)
+
+	other, _ := store.GetBinary("other-branch")
+	assert.Empty(t, other.Status)
+}
+
+func TestWebhookHandler_RejectsUnsignedRequests(t *testing.T) {
+	mockStorage := new(MockStorage)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil, WithWebhookSecret("hook-secret"))
+
+	payload := map[string]string{"repo_url": "https://example.com/repo.git", "branch": "main"}
+	req := signedHook(t, "generic", "wrong-secret", payload)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockStorage.AssertNotCalled(t, "ListBinaries")
+}
+
+func TestWebhookHandler_NotConfigured(t *testing.T) {
+	server := NewServer(config.ServerConfig{}, new(MockStorage), nil, nil)
+
+	req := signedHook(t, "github", "", map[string]string{})
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -3,13 +3,17 @@ package api
 
 import (
 	"html/template"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
-	"time"
+
+	"go_runner/internal/models"
 )
 
+// sessionCookie holds the random token of a server-side session
+const sessionCookie = "session"
+
 // loginPageHandler renders the login page
 func (s *Server) loginPageHandler(w http.ResponseWriter, r *http.Request) {
 	t, err := template.ParseFS(templates, "templates/login.html")
@@ -19,36 +23,76 @@ func (s *Server) loginPageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	_ = t.Execute(w, map[string]any{
 		"Next": r.URL.Query().Get("next"),
+		"OIDC": s.oidc != nil,
 	})
 }
 
-// loginHandler validates the admin token and sets a cookie
+// loginHandler validates a username and password, or the admin token, and sets a cookie
 func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, "bad form", http.StatusBadRequest)
 		return
 	}
 
-	token := r.FormValue("token")
-	expected := os.Getenv("ADMIN_TOKEN")
-	if expected == "" || token != expected {
-		http.Error(w, "invalid token", http.StatusUnauthorized)
+	sess := &models.Session{
+		IP:        r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+	}
+	rec := auditFromContext(r.Context())
+	if username := r.FormValue("username"); username != "" {
+		rec.setActor(models.ActorUser, username)
+		if s.users == nil {
+			http.Error(w, "invalid username or password", http.StatusUnauthorized)
+			return
+		}
+		user, err := s.users.Authenticate(username, r.FormValue("password"))
+		if err != nil {
+			http.Error(w, "invalid username or password", http.StatusUnauthorized)
+			return
+		}
+		sess.UserID, sess.Username = user.ID, user.Username
+		rec.setUser(user)
+	} else {
+		rec.setActor(models.ActorAdminToken, adminTokenUser.Username)
+		if s.adminToken == "" || !s.matchesAdminToken(r.FormValue("token")) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		sess.Username = adminTokenUser.Username
+	}
+
+	if err := s.startSession(w, sess); err != nil {
+		slog.Error("Failed to create session", slog.String("error", err.Error()))
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
 		return
 	}
 
-	// ✅ set cookie
+	http.Redirect(w, r, safeNext(r.FormValue("next")), http.StatusSeeOther)
+}
+
+// startSession creates sess and sets its cookie. The cookie holds a random
+// session token, never the credentials.
+func (s *Server) startSession(w http.ResponseWriter, sess *models.Session) error {
+	token, err := s.sessions.Create(sess)
+	if err != nil {
+		return err
+	}
 	http.SetCookie(w, &http.Cookie{
-		Name:     "admin_token",
+		Name:     sessionCookie,
 		Value:    token,
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   false, // set true if using HTTPS
+		Secure:   s.secureCookies,
 		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(24 * time.Hour),
+		Expires:  sess.ExpiresAt,
 	})
+	return nil
+}
 
+// safeNext returns where to go after signing in, falling back to /admin for
+// anything but a known local page
+func safeNext(next string) string {
 	// ✅ safe redirect
-	next := r.FormValue("next")
 	// Define list of allowed redirect paths
 	allowedNext := map[string]struct{}{
 		"/admin": {},
@@ -65,5 +109,6 @@ func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 		// use normalized and path-only form
 		next = u.Path
 	}
-	http.Redirect(w, r, next, http.StatusSeeOther)
+	return next
 }
+
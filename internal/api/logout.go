@@ -5,15 +5,22 @@ import (
 	"time"
 )
 
-// logoutHandler clears the admin cookie and redirects to login
+// logoutHandler ends the session, clears its cookie and redirects to login
 func (s *Server) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		if sess, err := s.sessions.Lookup(c.Value); err == nil {
+			auditFromContext(r.Context()).setSession(sess)
+			s.sessions.Revoke(sess.ID)
+		}
+	}
+
 	// Overwrite the cookie with an expired one
 	http.SetCookie(w, &http.Cookie{
-		Name:     "admin_token",
+		Name:     sessionCookie,
 		Value:    "",
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   false, // set true if running HTTPS
+		Secure:   s.secureCookies,
 		SameSite: http.SameSiteStrictMode,
 		Expires:  time.Unix(0, 0),
 		MaxAge:   -1,
@@ -2,31 +2,128 @@
 package api
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"net/http"
-	"os"
 	"strings"
+
+	"go_runner/internal/apikeys"
+	"go_runner/internal/models"
+	"go_runner/internal/tlsconfig"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type contextKey int
+
+const (
+	// apiKeyContextKey holds the *models.APIKey that authenticated a request
+	apiKeyContextKey contextKey = iota
+	// userContextKey holds the *models.User that authenticated a request
+	userContextKey
+	// sessionContextKey holds the *models.Session of requests authenticated by cookie
+	sessionContextKey
+	// auditContextKey holds the *auditRecord of audited requests
+	auditContextKey
 )
 
-// authMiddleware secures JSON API endpoints under /api/v1 that expect JSON error responses.
-// Accepts any of:
-//   - Authorization: Bearer <ADMIN_TOKEN>
-//   - ?token=<ADMIN_TOKEN>
-//   - Cookie: admin_token=<ADMIN_TOKEN>
-func (s *Server) authMiddleware(next http.Handler) http.Handler {
+// adminTokenUser is who requests made with the admin token act as
+var adminTokenUser = &models.User{Username: "admin", Role: models.RoleAdmin}
+
+// apiKeyFromContext returns the API key that authenticated the request, if any
+func apiKeyFromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(apiKeyContextKey).(*models.APIKey)
+	return key
+}
+
+// userFromContext returns the user that authenticated the request, if any
+func userFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userContextKey).(*models.User)
+	return user
+}
+
+// sessionFromContext returns the session of a request authenticated by cookie, if any
+func sessionFromContext(ctx context.Context) *models.Session {
+	sess, _ := ctx.Value(sessionContextKey).(*models.Session)
+	return sess
+}
+
+// require secures JSON API endpoints under /api/v1, letting through users
+// whose role grants perm. The user is attached to the request context.
+func (s *Server) require(perm models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return s.authorize(next, func(user *models.User) bool { return user.Can(perm) })
+	}
+}
+
+// requireUser secures endpoints open to every signed-in user
+func (s *Server) requireUser(next http.Handler) http.Handler {
+	return s.authorize(next, func(*models.User) bool { return true })
+}
+
+// authorize authenticates the request, checks the CSRF token of cookie
+// sessions on state-changing methods, and lets through users allowed by can
+func (s *Server) authorize(next http.Handler, can func(*models.User) bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if isAuthenticated(r) {
-			next.ServeHTTP(w, r)
+		user, sess := s.authenticate(r)
+		if user == nil {
+			s.respondError(w, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
-		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		auditFromContext(r.Context()).setUser(user)
+		// Browsers attach cookies to cross-site requests; the CSRF token
+		// proves the request came from the admin UI
+		if sess != nil && !safeMethod(r.Method) &&
+			subtle.ConstantTimeCompare([]byte(r.Header.Get("X-CSRF-Token")), []byte(sess.CSRFToken)) != 1 {
+			s.respondError(w, http.StatusForbidden, "Invalid CSRF token")
+			return
+		}
+		if !can(user) {
+			s.respondError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		if sess != nil {
+			ctx = context.WithValue(ctx, sessionContextKey, sess)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// requireBinaryOwner secures routes that change the binary {id}. Users who
+// may only change their own binaries get 403 for everyone else's.
+func (s *Server) requireBinaryOwner(next http.Handler) http.Handler {
+	return s.require(models.PermWriteBinaries)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := userFromContext(r.Context())
+		if !user.Can(models.PermWriteAllBinaries) {
+			binary, err := s.storage.GetBinary(chi.URLParam(r, "id"))
+			if err != nil {
+				s.respondError(w, http.StatusNotFound, "Binary not found")
+				return
+			}
+			if !user.CanWriteBinary(binary) {
+				s.respondError(w, http.StatusForbidden, "Only the owner of a binary can change it")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
 // requireAdminUI secures the HTML admin UI. If unauthenticated, it redirects to /login.
 func (s *Server) requireAdminUI(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if isAuthenticated(r) {
-			next.ServeHTTP(w, r)
+		if user, sess := s.authenticate(r); user != nil {
+			ctx := context.WithValue(r.Context(), userContextKey, user)
+			if sess != nil {
+				ctx = context.WithValue(ctx, sessionContextKey, sess)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 		// 303 = See Other (safer redirect after POST/other verbs)
@@ -34,44 +131,172 @@ func (s *Server) requireAdminUI(next http.Handler) http.Handler {
 	})
 }
 
-// isAuthenticated checks Authorization header, query token, or admin_token cookie.
-func isAuthenticated(r *http.Request) bool {
-	expectedToken := os.Getenv("ADMIN_TOKEN")
-	if expectedToken == "" {
+// authenticate returns the user making the request and, for session cookies,
+// the session. The admin token and sessions started with it act as an admin;
+// with single sign-on, other bearer tokens must be JWTs from the issuer.
+// Client certificates are checked before session cookies.
+func (s *Server) authenticate(r *http.Request) (*models.User, *models.Session) {
+	if s.isAdminToken(r) {
+		return adminTokenUser, nil
+	}
+	if token, ok := bearerToken(r); ok && s.oidc != nil {
+		// Bearer JWTs carry their own role and need no CSRF token
+		if user, err := s.oidc.Authenticate(r.Context(), token); err == nil {
+			return user, nil
+		}
+		return nil, nil
+	}
+	if user := s.clientCertUser(r); user != nil {
+		return user, nil
+	}
+
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil, nil
+	}
+	sess, err := s.sessions.Lookup(c.Value)
+	if err != nil {
+		return nil, nil
+	}
+	if sess.IsAdminToken() {
+		if s.adminToken == "" {
+			return nil, nil
+		}
+		return adminTokenUser, sess
+	}
+	if sess.Role != "" {
+		// Single sign-on users keep the role mapped when they signed in
+		if s.oidc == nil {
+			return nil, nil
+		}
+		return &models.User{ID: sess.UserID, Username: sess.Username, Role: sess.Role}, sess
+	}
+	if s.users == nil {
+		return nil, nil
+	}
+	// Looked up on every request, so role changes and deletions apply at once
+	user, err := s.users.Get(sess.UserID)
+	if err != nil {
+		return nil, nil
+	}
+	return user, sess
+}
+
+// clientCertUser maps a verified client certificate onto a user. Browsers
+// present certificates on their own, so requests that change anything are
+// only accepted from clients that send no Origin, i.e. not from web pages.
+func (s *Server) clientCertUser(r *http.Request) *models.User {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(s.config.TLS.ClientRoleMap) == 0 {
+		return nil
+	}
+	if !safeMethod(r.Method) && r.Header.Get("Origin") != "" {
+		return nil
+	}
+	user, ok := tlsconfig.ClientUser(r.TLS.VerifiedChains[0][0], s.config.TLS.ClientRoleMap)
+	if !ok {
+		return nil
+	}
+	return user
+}
+
+// isAdminToken checks the Authorization header, and the token query parameter
+// when enabled, against the configured admin token.
+func (s *Server) isAdminToken(r *http.Request) bool {
+	if s.adminToken == "" {
 		// For safety, if unset treat as not authenticated.
 		return false
 	}
 
 	// 1) Authorization: Bearer <token>
-	if h := r.Header.Get("Authorization"); h != "" {
-		parts := strings.SplitN(h, " ", 2)
-		if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") && parts[1] == expectedToken {
+	if token, ok := bearerToken(r); ok && s.matchesAdminToken(token) {
+		return true
+	}
+
+	// 2) ?token=<token>, which leaks into logs and browser history
+	if s.queryToken {
+		if q := r.URL.Query().Get("token"); q != "" && s.matchesAdminToken(q) {
 			return true
 		}
 	}
 
-	// 2) ?token=<token>
-	if q := r.URL.Query().Get("token"); q == expectedToken {
-		return true
-	}
+	return false
+}
 
-	// 3) Cookie: admin_token=<token>
-	if c, err := r.Cookie("admin_token"); err == nil && c.Value == expectedToken {
-		return true
+// bearerToken returns the token of an Authorization: Bearer header
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+		return "", false
 	}
+	return parts[1], true
+}
 
-	return false
+func (s *Server) matchesAdminToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
 }
 
-// apiKeyMiddleware validates API keys for execution endpoints (unchanged)
+// apiKeyMiddleware validates the X-API-Key of execution requests and attaches
+// the key to the request context. With API keys disabled, a user allowed to
+// execute binaries is required instead.
 func (s *Server) apiKeyMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKey := r.Header.Get("X-API-Key")
-		if apiKey == "" {
-			s.respondError(w, http.StatusUnauthorized, "API key required")
+		if s.apiKeys == nil {
+			s.require(models.PermExecute)(next).ServeHTTP(w, r)
 			return
 		}
-		// In production you'd validate the key properly.
-		next.ServeHTTP(w, r)
+
+		key, ok := s.authenticateAPIKey(w, r)
+		if !ok {
+			return
+		}
+		ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// buildAuthMiddleware lets API keys holding build:<id> trigger builds of that
+// binary. Requests without an API key need a user allowed to build.
+func (s *Server) buildAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.apiKeys == nil || r.Header.Get("X-API-Key") == "" {
+			s.require(models.PermBuildBinaries)(next).ServeHTTP(w, r)
+			return
+		}
+
+		key, ok := s.authenticateAPIKey(w, r)
+		if !ok {
+			return
+		}
+		if !key.CanBuild(chi.URLParam(r, "id")) {
+			s.respondError(w, http.StatusForbidden, "API key is not allowed to build this binary")
+			return
+		}
+		ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticateAPIKey checks the X-API-Key header, responding with 401 if it
+// is missing or not valid
+func (s *Server) authenticateAPIKey(w http.ResponseWriter, r *http.Request) (*models.APIKey, bool) {
+	token := r.Header.Get("X-API-Key")
+	if token == "" {
+		s.respondError(w, http.StatusUnauthorized, "API key required")
+		return nil, false
+	}
+
+	key, err := s.apiKeys.Authenticate(token)
+	switch {
+	case errors.Is(err, apikeys.ErrKeyExpired):
+		s.respondError(w, http.StatusUnauthorized, "API key has expired")
+		return nil, false
+	case errors.Is(err, apikeys.ErrKeyRevoked):
+		s.respondError(w, http.StatusUnauthorized, "API key has been revoked")
+		return nil, false
+	case err != nil:
+		s.respondError(w, http.StatusUnauthorized, "Invalid API key")
+		return nil, false
+	}
+	auditFromContext(r.Context()).setAPIKey(key)
+	return key, true
+}
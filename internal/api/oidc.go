@@ -0,0 +1,162 @@
+// internal/api/oidc.go
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go_runner/internal/models"
+	"go_runner/internal/oidc"
+)
+
+const (
+	// oidcStateCookie binds a sign-in to the browser that started it
+	oidcStateCookie = "oidc_state"
+	// oidcLoginTTL is how long a user has to sign in at the issuer
+	oidcLoginTTL = 10 * time.Minute
+	// maxOIDCLogins bounds the sign-ins in progress, which anyone can start
+	maxOIDCLogins = 10000
+)
+
+// oidcLogin is a single sign-on in progress
+type oidcLogin struct {
+	nonce    string
+	verifier string
+	next     string
+	expires  time.Time
+}
+
+// continueTemplate navigates to the next page from our own origin, so the
+// SameSite=Strict session cookie is sent with it
+var continueTemplate = template.Must(template.New("continue").Parse(
+	`<!DOCTYPE html><html><head><meta http-equiv="refresh" content="0;url={{.}}"></head>` +
+		`<body><a href="{{.}}">Continue</a></body></html>`))
+
+// oidcLoginHandler starts a single sign-on, sending the browser to the issuer
+func (s *Server) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, nonce, verifier := randomString(), randomString(), randomString()
+	authURL, err := s.oidc.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		slog.Error("Failed to start single sign-on", slog.String("error", err.Error()))
+		http.Error(w, "single sign-on is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	now := time.Now()
+	s.oidcMu.Lock()
+	for key, login := range s.oidcLogins {
+		if now.After(login.expires) {
+			delete(s.oidcLogins, key)
+		}
+	}
+	if len(s.oidcLogins) >= maxOIDCLogins {
+		s.oidcMu.Unlock()
+		http.Error(w, "too many sign-ins in progress", http.StatusServiceUnavailable)
+		return
+	}
+	s.oidcLogins[state] = &oidcLogin{
+		nonce:    nonce,
+		verifier: verifier,
+		next:     safeNext(r.URL.Query().Get("next")),
+		expires:  now.Add(oidcLoginTTL),
+	}
+	s.oidcMu.Unlock()
+
+	// Lax, since the issuer sends the browser back with a cross-site redirect
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/login/oidc",
+		HttpOnly: true,
+		Secure:   s.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(oidcLoginTTL / time.Second),
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// oidcCallbackHandler finishes a single sign-on: it redeems the code, maps
+// the user's claims onto a role and starts a session
+func (s *Server) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "single sign-on failed: "+e, http.StatusUnauthorized)
+		return
+	}
+
+	state := q.Get("state")
+	c, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(c.Value), []byte(state)) != 1 {
+		http.Error(w, "invalid sign-in state", http.StatusBadRequest)
+		return
+	}
+	s.oidcMu.Lock()
+	login := s.oidcLogins[state]
+	delete(s.oidcLogins, state)
+	s.oidcMu.Unlock()
+	if login == nil || time.Now().After(login.expires) {
+		http.Error(w, "sign-in expired, please try again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     "/login/oidc",
+		HttpOnly: true,
+		Secure:   s.secureCookies,
+		MaxAge:   -1,
+	})
+
+	user, err := s.oidc.Exchange(r.Context(), q.Get("code"), login.verifier, login.nonce)
+	if errors.Is(err, oidc.ErrNoRole) {
+		http.Error(w, "your account has no go_runner role", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		slog.Warn("Single sign-on failed", slog.String("error", err.Error()))
+		http.Error(w, "single sign-on failed", http.StatusUnauthorized)
+		return
+	}
+
+	auditFromContext(r.Context()).setUser(user)
+
+	sess := &models.Session{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		IP:        r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+	}
+	if err := s.startSession(w, sess); err != nil {
+		slog.Error("Failed to create session", slog.String("error", err.Error()))
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = continueTemplate.Execute(w, login.next)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/oidc"
+	"go_runner/internal/oidc/oidctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOIDCTestServer(t *testing.T, mockStorage *MockStorage) (*Server, *oidctest.Issuer) {
+	t.Helper()
+	issuer := oidctest.NewIssuer("go-runner", "client secret")
+	t.Cleanup(issuer.Close)
+	provider, err := oidc.NewProvider(config.OIDCConfig{
+		Issuer:       issuer.URL,
+		ClientID:     "go-runner",
+		ClientSecret: "client secret",
+		RedirectURL:  "http://runner.example.com/login/oidc/callback",
+		GroupsClaim:  "groups",
+		RoleMap:      map[string]string{"devs": models.RoleMaintainer, "ops": models.RoleOperator},
+	})
+	require.NoError(t, err)
+	return NewServer(config.ServerConfig{}, mockStorage, nil, nil, WithOIDC(provider)), issuer
+}
+
+// ssoLogin runs the authorization-code flow through the mock issuer and
+// returns the callback response
+func ssoLogin(t *testing.T, server *Server) *httptest.ResponseRecorder {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/login/oidc?next=/admin", nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusFound, rr.Code, rr.Body.String())
+	stateCookie := rr.Result().Cookies()[0]
+	assert.Equal(t, oidcStateCookie, stateCookie.Name)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	callback, err := resp.Location()
+	require.NoError(t, err)
+
+	req, _ = http.NewRequest("GET", callback.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	// The state is single use
+	replay := httptest.NewRecorder()
+	server.router.ServeHTTP(replay, req)
+	assert.Equal(t, http.StatusBadRequest, replay.Code)
+	return rr
+}
+
+func TestOIDCLogin(t *testing.T) {
+	server, issuer := newOIDCTestServer(t, new(MockStorage))
+
+	issuer.SetClaims(map[string]interface{}{"sub": "u-1", "preferred_username": "alice", "groups": []string{"ops", "devs"}})
+	rr := ssoLogin(t, server)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `url=/admin`)
+
+	var sessionCookie *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "session" {
+			sessionCookie = c
+		}
+	}
+	require.NotNil(t, sessionCookie)
+
+	req, _ := http.NewRequest("GET", "/api/v1/sessions/current", nil)
+	req.AddCookie(sessionCookie)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var sess models.Session
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &sess))
+	assert.Equal(t, "oidc:alice", sess.Username)
+	assert.Equal(t, models.RoleMaintainer, sess.Role)
+
+	req, _ = http.NewRequest("GET", "/api/v1/users", nil)
+	req.AddCookie(sessionCookie)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Users without a mapped group cannot sign in
+	issuer.SetClaims(map[string]interface{}{"sub": "u-2", "preferred_username": "eve", "groups": []string{"sales"}})
+	assert.Equal(t, http.StatusForbidden, ssoLogin(t, server).Code)
+
+	// The callback only completes sign-ins this browser started
+	req, _ = http.NewRequest("GET", "/login/oidc/callback?code=x&state=forged", nil)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestOIDCBearerTokens(t *testing.T) {
+	mockStorage := new(MockStorage)
+	server, issuer := newOIDCTestServer(t, mockStorage)
+	mockStorage.On("ListBinaries").Return([]*models.Binary{}, nil)
+
+	do := func(method, path, token string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	operator := issuer.Token(map[string]interface{}{"sub": "svc-1", "groups": []string{"ops"}})
+	assert.Equal(t, http.StatusOK, do("GET", "/api/v1/binaries", operator))
+	assert.Equal(t, http.StatusForbidden, do("DELETE", "/api/v1/cache", operator))
+
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/api/v1/binaries", "not-a-jwt"))
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/api/v1/binaries", issuer.Token(map[string]interface{}{"sub": "svc-1", "groups": []string{"ops"}, "aud": "other-app"})))
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/api/v1/binaries", issuer.Token(map[string]interface{}{"sub": "svc-2"})))
+
+	// SSO users never own the binaries of a local user with the same name
+	maintainer := issuer.Token(map[string]interface{}{"sub": "u-9", "preferred_username": "alice", "groups": []string{"devs"}})
+	mockStorage.On("GetBinary", "local").Return(&models.Binary{ID: "local", Owner: "alice"}, nil)
+	mockStorage.On("GetBinary", "sso").Return(&models.Binary{ID: "sso", Owner: "oidc:alice"}, nil)
+	mockStorage.On("DeleteBinary", "sso").Return(nil)
+	assert.Equal(t, http.StatusForbidden, do("DELETE", "/api/v1/binaries/local", maintainer))
+	assert.Equal(t, http.StatusOK, do("DELETE", "/api/v1/binaries/sso", maintainer))
+	mockStorage.AssertNotCalled(t, "DeleteBinary", "local")
+
+	// Without single sign-on, JWTs are not accepted
+	plain := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
+	req, _ := http.NewRequest("GET", "/api/v1/binaries", nil)
+	req.Header.Set("Authorization", "Bearer "+operator)
+	rr := httptest.NewRecorder()
+	plain.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
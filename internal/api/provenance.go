@@ -0,0 +1,170 @@
+// internal/api/provenance.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"go_runner/internal/models"
+	"go_runner/internal/provenance"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// provenancePath is where the provenance envelope of an executable is kept
+func provenancePath(binaryPath string) string {
+	return binaryPath + ".intoto.json"
+}
+
+// buildProvenance describes a finished build of binary as an SLSA statement
+func (s *Server) buildProvenance(binary *models.Binary, build *models.BuildRecord) *provenance.Statement {
+	stmt := provenance.NewStatement(provenance.BuildTypeGo, build.ID, build.StartedAt, time.Now())
+	stmt.AddSubject(binary.ID, build.SHA256)
+	for _, artifact := range build.Artifacts {
+		stmt.AddSubject(artifact.Target.String(), artifact.SHA256)
+	}
+
+	def := &stmt.Predicate.BuildDefinition
+	def.ExternalParameters["source_type"] = binary.SourceType
+	def.ExternalParameters["build_path"] = binary.BuildPath
+	def.ExternalParameters["build_options"] = build.Options
+	if len(binary.Targets) > 0 {
+		def.ExternalParameters["targets"] = binary.Targets
+	}
+	if binary.GoVersion != "" {
+		def.ExternalParameters["go_version"] = binary.GoVersion
+	}
+	if build.Toolchain != nil {
+		def.InternalParameters = map[string]interface{}{"toolchain": build.Toolchain.Version}
+	}
+
+	if build.SourceSHA256 != "" {
+		name := "source"
+		if binary.Upload != nil && binary.Upload.Filename != "" {
+			name = binary.Upload.Filename
+		}
+		def.ResolvedDependencies = append(def.ResolvedDependencies, provenance.ResourceDescriptor{
+			Name:   name,
+			Digest: map[string]string{"sha256": build.SourceSHA256},
+		})
+	} else {
+		repo := redactRepoURL(binary.RepoURL)
+		def.ExternalParameters["repository"] = repo
+		def.ExternalParameters["ref"] = "refs/heads/" + binary.Branch
+		def.ResolvedDependencies = append(def.ResolvedDependencies, provenance.ResourceDescriptor{
+			URI:    "git+" + repo + "@refs/heads/" + binary.Branch,
+			Digest: map[string]string{"gitCommit": build.Commit},
+		})
+	}
+	return stmt
+}
+
+// uploadProvenance describes a prebuilt executable installed from an upload
+func (s *Server) uploadProvenance(binary *models.Binary, build *models.BuildRecord) *provenance.Statement {
+	stmt := provenance.NewStatement(provenance.BuildTypeUploaded, build.ID, build.StartedAt, build.FinishedAt)
+	stmt.AddSubject(binary.ID, build.SHA256)
+	stmt.Predicate.BuildDefinition.ExternalParameters["source_type"] = binary.SourceType
+
+	name := "upload"
+	if binary.Upload != nil && binary.Upload.Filename != "" {
+		name = binary.Upload.Filename
+	}
+	stmt.Predicate.BuildDefinition.ResolvedDependencies = []provenance.ResourceDescriptor{{
+		Name:   name,
+		Digest: map[string]string{"sha256": build.SHA256},
+	}}
+	return stmt
+}
+
+// writeProvenance stores the statement next to the executable, signed when a
+// signing key is configured
+func (s *Server) writeProvenance(binaryPath string, stmt *provenance.Statement) error {
+	env, err := provenance.Seal(stmt, s.signer)
+	if err != nil {
+		return err
+	}
+	return provenance.WriteFile(provenancePath(binaryPath), env)
+}
+
+// verifyProvenance checks that the signed provenance of the active version
+// vouches for the digest the executor is about to verify. Without a signing
+// key only the digest recorded on the binary is checked.
+func (s *Server) verifyProvenance(binary *models.Binary) error {
+	if s.signer == nil {
+		return nil
+	}
+
+	env, err := provenance.ReadFile(provenancePath(s.artifacts.BinaryPath(binary.ID)))
+	if err != nil {
+		return fmt.Errorf("failed to read provenance: %w", err)
+	}
+	stmt, err := s.signer.Verify(env)
+	if err != nil {
+		return err
+	}
+	digest, ok := stmt.SubjectDigest(binary.ID)
+	if !ok || digest != binary.SHA256 {
+		return errors.New("provenance does not match the recorded digest")
+	}
+	return nil
+}
+
+// redactRepoURL drops credentials embedded in a repository URL
+func redactRepoURL(repoURL string) string {
+	u, err := url.Parse(repoURL)
+	if err != nil || u.User == nil {
+		return repoURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// getProvenanceHandler returns the provenance envelope of the active version
+func (s *Server) getProvenanceHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	binary, err := s.storage.GetBinary(id)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Binary not found")
+		return
+	}
+	if binary.BinaryPath == "" {
+		s.respondError(w, http.StatusNotFound, "Binary has not been built")
+		return
+	}
+
+	env, err := provenance.ReadFile(provenancePath(s.artifacts.BinaryPath(binary.ID)))
+	if errors.Is(err, os.ErrNotExist) {
+		s.respondError(w, http.StatusNotFound, "No provenance recorded; rebuild the binary")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to read provenance", slog.String("id", id), slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to read provenance")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, env)
+}
+
+// signingKeyHandler returns the public key that verifies provenance signatures
+func (s *Server) signingKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if s.signer == nil {
+		s.respondError(w, http.StatusNotFound, "Provenance signing is not configured")
+		return
+	}
+
+	key, err := s.signer.PublicKeyPEM()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to encode public key")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Header().Set("X-Key-Id", s.signer.KeyID())
+	w.Write(key)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/artifacts"
+	"go_runner/internal/config"
+	"go_runner/internal/executor"
+	"go_runner/internal/models"
+	"go_runner/internal/provenance"
+)
+
+func executeRequest(t *testing.T, server *Server, binaryID string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(&models.ExecutionRequest{BinaryID: binaryID})
+	req, _ := http.NewRequest("POST", "/api/v1/execute", bytes.NewBuffer(body))
+	req.Header.Set("X-API-Key", newTestAPIKey(t, server))
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestExecuteBinaryHandler_VerifiesProvenance(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := provenance.NewSigner(key)
+
+	mockStorage := new(MockStorage)
+	mockExecutor := new(MockExecutor)
+	store := artifacts.NewLocalStore(t.TempDir())
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, mockExecutor, WithSigner(signer), WithArtifactStore(store))
+
+	binaryPath := store.BinaryPath("1")
+	require.NoError(t, os.WriteFile(binaryPath, []byte("binary"), 0755))
+	sum, _, err := fileSHA256(binaryPath)
+	require.NoError(t, err)
+
+	binary := &models.Binary{ID: "1", BinaryPath: binaryPath, SHA256: sum, Status: "ready"}
+	build := &models.BuildRecord{ID: "build-1", Commit: "abcdef123456", SHA256: sum, StartedAt: time.Now()}
+	require.NoError(t, server.writeProvenance(binaryPath, server.buildProvenance(binary, build)))
+
+	result := &models.ExecutionResult{ID: "exec1", Status: "completed"}
+	mockStorage.On("GetBinary", "1").Return(binary, nil)
+	mockStorage.On("SaveExecution", result).Return(nil)
+	mockExecutor.On("Execute", mock.Anything, sum, mock.Anything, mock.Anything).Return(result, nil).Once()
+
+	rr := executeRequest(t, server, "1")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// Swapping the recorded digest is caught by the signed provenance
+	binary.SHA256 = fmt.Sprintf("%064d", 0)
+	rr = executeRequest(t, server, "1")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	binary.SHA256 = sum
+
+	// So is replacing the provenance with an unsigned one
+	unsigned, err := provenance.Seal(server.buildProvenance(binary, build), nil)
+	require.NoError(t, err)
+	require.NoError(t, provenance.WriteFile(provenancePath(binaryPath), unsigned))
+	rr = executeRequest(t, server, "1")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	mockExecutor.AssertNumberOfCalls(t, "Execute", 1)
+}
+
+func TestExecuteBinaryHandler_TamperedBinary(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockExecutor := new(MockExecutor)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, mockExecutor)
+
+	binary := &models.Binary{ID: "1", BinaryPath: "/path/to/binary", SHA256: "aaaa", Status: "ready"}
+	mockStorage.On("GetBinary", "1").Return(binary, nil)
+	mockExecutor.On("Execute", mock.Anything, binary.SHA256, mock.Anything, mock.Anything).
+		Return((*models.ExecutionResult)(nil), fmt.Errorf("%w: expected sha256 aaaa, got bbbb", executor.ErrDigestMismatch))
+
+	rr := executeRequest(t, server, "1")
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "integrity")
+	mockStorage.AssertNotCalled(t, "SaveExecution", mock.Anything)
+}
+
+func TestSigningKeyHandler(t *testing.T) {
+	server := NewServer(config.ServerConfig{}, new(MockStorage), nil, nil)
+	req, _ := http.NewRequest("GET", "/api/v1/provenance/key", nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := provenance.NewSigner(key)
+	server = NewServer(config.ServerConfig{}, new(MockStorage), nil, nil, WithSigner(signer))
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "BEGIN PUBLIC KEY")
+	assert.Equal(t, signer.KeyID(), rr.Header().Get("X-Key-Id"))
+}
+
+func TestInstallBinary_MetadataFollowsBinary(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := dir + "/bin"
+	stagingPath := outputPath + ".new"
+	require.NoError(t, os.WriteFile(provenancePath(outputPath), []byte("old provenance"), 0644))
+	require.NoError(t, os.WriteFile(sbomPath(outputPath), []byte("old sbom"), 0644))
+	require.NoError(t, os.WriteFile(provenancePath(stagingPath), []byte("new provenance"), 0644))
+
+	// The active version keeps its metadata when the binary cannot be installed
+	assert.Error(t, installBinary(stagingPath, outputPath))
+	data, err := os.ReadFile(provenancePath(outputPath))
+	require.NoError(t, err)
+	assert.Equal(t, "old provenance", string(data))
+	assert.FileExists(t, sbomPath(outputPath))
+
+	// Once it is, the staged provenance replaces the old one and the stale SBOM goes
+	require.NoError(t, os.WriteFile(stagingPath, []byte("binary"), 0755))
+	require.NoError(t, installBinary(stagingPath, outputPath))
+	data, err = os.ReadFile(provenancePath(outputPath))
+	require.NoError(t, err)
+	assert.Equal(t, "new provenance", string(data))
+	assert.NoFileExists(t, sbomPath(outputPath))
+	assert.NoFileExists(t, provenancePath(stagingPath))
+}
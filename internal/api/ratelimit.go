@@ -0,0 +1,174 @@
+// internal/api/ratelimit.go
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_runner/internal/models"
+	"go_runner/internal/ratelimit"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// apiKeyUsage is what an API key may execute and has executed
+type apiKeyUsage struct {
+	Quota models.Quota       `json:"quota"`
+	Usage *models.QuotaUsage `json:"usage"`
+}
+
+// rateLimit takes a token from the client IP's bucket and then the API
+// key's, answering 429 when either is empty. RateLimit-* headers describe
+// the bucket with fewer tokens left.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report *ratelimit.Decision
+		take := func(l *ratelimit.Limiter, key string) bool {
+			d := l.Allow(key)
+			if report == nil || !d.Allowed || d.Remaining < report.Remaining {
+				report = &d
+			}
+			return d.Allowed
+		}
+
+		allowed := true
+		if s.ipLimiter != nil {
+			allowed = take(s.ipLimiter, clientIP(r))
+		}
+		if key := apiKeyFromContext(r.Context()); allowed && key != nil && s.keyLimiter != nil {
+			allowed = take(s.keyLimiter, key.ID)
+		}
+
+		if report != nil {
+			setRateLimitHeaders(w, report.Limit, report.Remaining, report.Reset)
+		}
+		if !allowed {
+			w.Header().Set("Retry-After", seconds(report.RetryAfter))
+			s.respondError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitBinary takes a token from the binary's bucket, answering 429 and
+// returning false when it is empty. The RateLimit-* headers are replaced
+// when this bucket has fewer tokens left than those already reported.
+func (s *Server) limitBinary(w http.ResponseWriter, binaryID string) bool {
+	if s.binaryLimiter == nil {
+		return true
+	}
+
+	d := s.binaryLimiter.Allow(binaryID)
+	remaining, err := strconv.Atoi(w.Header().Get("RateLimit-Remaining"))
+	if err != nil || !d.Allowed || d.Remaining < remaining {
+		setRateLimitHeaders(w, d.Limit, d.Remaining, d.Reset)
+	}
+	if !d.Allowed {
+		w.Header().Set("Retry-After", seconds(d.RetryAfter))
+		s.respondError(w, http.StatusTooManyRequests, "Rate limit exceeded for this binary")
+		return false
+	}
+	return true
+}
+
+// reserveQuota counts an execution against key's quota, answering 429 and
+// returning false when the quota is used up. Executions without a key are
+// not metered.
+func (s *Server) reserveQuota(w http.ResponseWriter, key *models.APIKey) bool {
+	if s.quotas == nil || key == nil {
+		return true
+	}
+
+	_, err := s.quotas.Reserve(key.ID, s.quotaFor(key))
+	var exceeded *models.QuotaExceededError
+	if errors.As(err, &exceeded) {
+		reset := time.Until(exceeded.Reset)
+		setRateLimitHeaders(w, int(exceeded.Max), 0, reset)
+		w.Header().Set("Retry-After", seconds(reset))
+		s.respondError(w, http.StatusTooManyRequests, "Quota exceeded: "+exceeded.Limit)
+		return false
+	}
+	if err != nil {
+		slog.Error("Failed to check quota", slog.String("key", key.ID), slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to check quota")
+		return false
+	}
+	return true
+}
+
+// releaseQuota gives back the execution reserved for key when it did not run
+func (s *Server) releaseQuota(key *models.APIKey) {
+	if s.quotas == nil || key == nil {
+		return
+	}
+	if err := s.quotas.Release(key.ID); err != nil {
+		slog.Error("Failed to release quota", slog.String("key", key.ID), slog.String("error", err.Error()))
+	}
+}
+
+// recordCPU counts the CPU time of an execution against key's quota
+func (s *Server) recordCPU(key *models.APIKey, result *models.ExecutionResult) {
+	if s.quotas == nil || key == nil {
+		return
+	}
+	if err := s.quotas.AddCPU(key.ID, time.Duration(result.CPUTime)*time.Millisecond); err != nil {
+		slog.Error("Failed to record CPU time", slog.String("key", key.ID), slog.String("error", err.Error()))
+	}
+}
+
+func (s *Server) quotaFor(key *models.APIKey) models.Quota {
+	if key.Quota != nil {
+		return *key.Quota
+	}
+	return s.defaultQuota
+}
+
+// apiKeyUsageHandler returns an API key's quota and what it has used this day and month
+func (s *Server) apiKeyUsageHandler(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		s.respondError(w, http.StatusNotFound, "API keys are disabled")
+		return
+	}
+
+	key, err := s.apiKeys.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	resp := apiKeyUsage{Quota: s.quotaFor(key), Usage: &models.QuotaUsage{}}
+	if s.quotas != nil {
+		if resp.Usage, err = s.quotas.Usage(key.ID); err != nil {
+			s.respondError(w, http.StatusInternalServerError, "Failed to read usage")
+			return
+		}
+	}
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// setRateLimitHeaders sets the RateLimit-Limit, -Remaining and -Reset headers
+func setRateLimitHeaders(w http.ResponseWriter, limit, remaining int, reset time.Duration) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("RateLimit-Reset", seconds(reset))
+}
+
+// seconds rounds d up to whole seconds
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// clientIP returns the request's source address without its port. Behind
+// a trusted proxy, realIP has already replaced it.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
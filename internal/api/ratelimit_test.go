@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_runner/internal/config"
+	"go_runner/internal/executor"
+	"go_runner/internal/models"
+	"go_runner/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newExecutingServer returns a server whose executor runs binary 1 and
+// reports cpuMillis of CPU time
+func newExecutingServer(t *testing.T, cpuMillis int64, opts ...Option) *Server {
+	t.Helper()
+	mockStorage := new(MockStorage)
+	mockExecutor := new(MockExecutor)
+	mockStorage.On("GetBinary", "1").Return(&models.Binary{ID: "1", Status: "ready"}, nil)
+	mockStorage.On("SaveExecution", mock.Anything).Return(nil)
+	result := &models.ExecutionResult{ID: "exec", Status: "completed", CPUTime: cpuMillis}
+	mockExecutor.On("Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(result, nil)
+	return NewServer(config.ServerConfig{}, mockStorage, nil, mockExecutor, opts...)
+}
+
+func execute(server *Server, key, ip string, headers ...string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(&models.ExecutionRequest{BinaryID: "1"})
+	req, _ := http.NewRequest("POST", "/api/v1/execute", bytes.NewBuffer(body))
+	req.Header.Set("X-API-Key", key)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Add(headers[i], headers[i+1])
+	}
+	req.RemoteAddr = ip + ":40000"
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimits(t *testing.T) {
+	server := newExecutingServer(t, 0, WithRateLimits(ratelimit.NewLimiter(60, 2), ratelimit.NewLimiter(60, 3)))
+	ci := newTestAPIKey(t, server)
+	other := newTestAPIKey(t, server)
+
+	rr := execute(server, ci, "192.0.2.1")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Reset"))
+
+	// Each key has its own bucket
+	assert.Equal(t, http.StatusOK, execute(server, ci, "192.0.2.2").Code)
+	rr = execute(server, ci, "192.0.2.3")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, execute(server, other, "192.0.2.3").Code)
+
+	// And each client IP, whatever key it uses
+	assert.Equal(t, http.StatusOK, execute(server, other, "192.0.2.1").Code)
+	assert.Equal(t, http.StatusOK, execute(server, newTestAPIKey(t, server), "192.0.2.1").Code)
+	rr = execute(server, newTestAPIKey(t, server), "192.0.2.1")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "3", rr.Header().Get("RateLimit-Limit"))
+
+	// Reading results is not limited
+	req, _ := http.NewRequest("GET", "/api/v1/execute/exec", nil)
+	req.Header.Set("X-API-Key", ci)
+	req.RemoteAddr = "192.0.2.1:40000"
+	server.storage.(*MockStorage).On("GetExecution", "exec").Return(&models.ExecutionResult{ID: "exec"}, nil)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.NotEqual(t, http.StatusTooManyRequests, rr.Code)
+	assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimits_ForwardedFor(t *testing.T) {
+	server := newExecutingServer(t, 0, WithRateLimits(nil, ratelimit.NewLimiter(60, 1)))
+	server.proxies = parseProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	key := newTestAPIKey(t, server)
+
+	// Clients cannot get a fresh bucket by claiming another address
+	assert.Equal(t, http.StatusOK, execute(server, key, "198.51.100.7").Code)
+	for _, header := range []string{"X-Forwarded-For", "X-Real-IP", "True-Client-IP"} {
+		assert.Equal(t, http.StatusTooManyRequests, execute(server, key, "198.51.100.7", header, "203.0.113.99").Code, header)
+	}
+
+	// Behind a trusted proxy each forwarded client has its own bucket
+	assert.Equal(t, http.StatusOK, execute(server, key, "10.1.2.3", "X-Forwarded-For", "203.0.113.1").Code)
+	assert.Equal(t, http.StatusOK, execute(server, key, "10.1.2.3", "X-Real-IP", "203.0.113.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, execute(server, key, "192.0.2.10", "X-Forwarded-For", "203.0.113.1").Code)
+
+	// Only the entry the proxy appended counts, not what the client sent
+	assert.Equal(t, http.StatusTooManyRequests, execute(server, key, "10.1.2.3", "X-Forwarded-For", "203.0.113.50, 203.0.113.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, execute(server, key, "10.1.2.3", "X-Forwarded-For", "203.0.113.51, 203.0.113.1, 10.9.9.9").Code)
+}
+
+func TestRateLimits_PerBinary(t *testing.T) {
+	server := newExecutingServer(t, 0, WithBinaryRateLimit(ratelimit.NewLimiter(60, 2)))
+
+	// Every key and client shares the binary's bucket
+	assert.Equal(t, http.StatusOK, execute(server, newTestAPIKey(t, server), "192.0.2.1").Code)
+	assert.Equal(t, http.StatusOK, execute(server, newTestAPIKey(t, server), "192.0.2.2").Code)
+	rr := execute(server, newTestAPIKey(t, server), "192.0.2.3")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Contains(t, rr.Body.String(), "this binary")
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+}
+
+func TestQuotas(t *testing.T) {
+	store, err := ratelimit.NewQuotaStore(t.TempDir())
+	require.NoError(t, err)
+	server := newExecutingServer(t, 1500, WithQuotas(store, models.Quota{DailyExecutions: 1}))
+	adminSession := loginAdmin(t, server)
+
+	ciKey, ci := issueTestAPIKey(t, server, &models.APIKey{Name: "ci"})
+	assert.Equal(t, http.StatusOK, execute(server, ci, "192.0.2.1").Code)
+	rr := execute(server, ci, "192.0.2.1")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Contains(t, rr.Body.String(), "daily_executions")
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	// Keys created with a quota of their own use it instead of the default
+	body, _ := json.Marshal(map[string]interface{}{"name": "batch", "quota": map[string]int{"daily_executions": 3}})
+	req, _ := http.NewRequest("POST", "/api/v1/apikeys", bytes.NewBuffer(body))
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var batch issuedAPIKey
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &batch))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, execute(server, batch.Key, "192.0.2.1").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, execute(server, batch.Key, "192.0.2.1").Code)
+
+	// Usage includes the CPU time of each run
+	req, _ = http.NewRequest("GET", "/api/v1/apikeys/"+ciKey.ID+"/usage", nil)
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var usage apiKeyUsage
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &usage))
+	assert.Equal(t, int64(1), usage.Quota.DailyExecutions)
+	assert.Equal(t, int64(1), usage.Usage.DayExecutions)
+	assert.Equal(t, int64(1500), usage.Usage.DayCPUMillis)
+
+	// Executions refused by the integrity check are not counted
+	mockStorage := new(MockStorage)
+	mockExecutor := new(MockExecutor)
+	mockStorage.On("GetBinary", "1").Return(&models.Binary{ID: "1", Status: "ready", SHA256: "abc"}, nil)
+	mockExecutor.On("Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return((*models.ExecutionResult)(nil), executor.ErrDigestMismatch)
+	tampered := NewServer(config.ServerConfig{}, mockStorage, nil, mockExecutor, WithQuotas(store, models.Quota{DailyExecutions: 1}))
+	tamperedKey, tamperedSecret := issueTestAPIKey(t, tampered, &models.APIKey{Name: "tampered"})
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusConflict, execute(tampered, tamperedSecret, "192.0.2.1").Code)
+	}
+	used, err := store.Usage(tamperedKey.ID)
+	require.NoError(t, err)
+	assert.Zero(t, used.DayExecutions)
+
+	// Negative limits are refused
+	body, _ = json.Marshal(map[string]interface{}{"name": "bad", "quota": map[string]int{"daily_executions": -1}})
+	req, _ = http.NewRequest("POST", "/api/v1/apikeys", bytes.NewBuffer(body))
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
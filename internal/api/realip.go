@@ -0,0 +1,84 @@
+// internal/api/realip.go
+package api
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// realIP replaces RemoteAddr with the client address reported by a trusted
+// proxy. Other peers keep their own address, so clients cannot choose the
+// IP they are rate limited and audited by.
+func (s *Server) realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := s.forwardedClient(r); ip != "" {
+			r.RemoteAddr = ip
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedClient returns the client a trusted proxy forwarded r for, or ""
+// when the peer is not a trusted proxy. X-Forwarded-For is read from the
+// right, skipping other trusted proxies, since entries to the left of the
+// last proxy were written by the client.
+func (s *Server) forwardedClient(r *http.Request) string {
+	if !s.trustedProxy(net.ParseIP(clientIP(r))) {
+		return ""
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				return ""
+			}
+			if i == 0 || !s.trustedProxy(ip) {
+				return ip.String()
+			}
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+func (s *Server) trustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range s.proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProxies parses IPs and CIDRs, skipping invalid entries
+func parseProxies(list []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range list {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * net.IPv6len
+				if ip4 := ip.To4(); ip4 != nil {
+					ip, bits = ip4, 8*net.IPv4len
+				}
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			slog.Warn("Ignoring invalid trusted proxy", slog.String("proxy", entry))
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
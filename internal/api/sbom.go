@@ -0,0 +1,99 @@
+// internal/api/sbom.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go_runner/internal/models"
+	"go_runner/internal/sbom"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// sbomPath is where the CycloneDX SBOM of an executable is kept
+func sbomPath(binaryPath string) string {
+	return binaryPath + ".cdx.json"
+}
+
+// inspectBinary records the modules linked into the staged executable at path
+// as its SBOM and checks them against the vulnerability database. It fails
+// when the build must not be promoted.
+func (s *Server) inspectBinary(binary *models.Binary, build *models.BuildRecord, path string) error {
+	info, err := sbom.Read(path)
+	if err != nil {
+		if s.vulns != nil && s.vulnBlock {
+			return fmt.Errorf("cannot check for vulnerabilities: %w", err)
+		}
+		slog.Warn("Skipping SBOM", slog.String("id", binary.ID), slog.String("error", err.Error()))
+		return nil
+	}
+
+	if s.vulns != nil {
+		report, err := s.vulns.Check(info)
+		switch {
+		case err != nil && s.vulnBlock:
+			return fmt.Errorf("vulnerability check failed: %w", err)
+		case err != nil:
+			slog.Warn("Vulnerability check failed", slog.String("id", binary.ID), slog.String("error", err.Error()))
+		default:
+			build.VulnReport = report
+			if ids := vulnIDs(report); len(ids) > 0 && s.vulnBlock {
+				return fmt.Errorf("linked modules have known vulnerabilities: %s", strings.Join(ids, ", "))
+			}
+		}
+	}
+
+	bom := sbom.FromBuildInfo(info, binary.Name, build.SHA256, time.Now())
+	if err := sbom.WriteFile(sbomPath(path), bom); err != nil {
+		return fmt.Errorf("failed to write SBOM: %w", err)
+	}
+	return nil
+}
+
+// vulnIDs returns the distinct vulnerability IDs of a report
+func vulnIDs(report *models.VulnReport) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, v := range report.Vulnerabilities {
+		if !seen[v.ID] {
+			seen[v.ID] = true
+			ids = append(ids, v.ID)
+		}
+	}
+	return ids
+}
+
+// getSBOMHandler returns the CycloneDX SBOM of the active version
+func (s *Server) getSBOMHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	binary, err := s.storage.GetBinary(id)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Binary not found")
+		return
+	}
+	if binary.BinaryPath == "" {
+		s.respondError(w, http.StatusNotFound, "Binary has not been built")
+		return
+	}
+
+	data, err := os.ReadFile(sbomPath(s.artifacts.BinaryPath(binary.ID)))
+	if errors.Is(err, os.ErrNotExist) {
+		s.respondError(w, http.StatusNotFound, "No SBOM recorded; the executable has no Go build information or predates SBOM generation")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to read SBOM", slog.String("id", id), slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to read SBOM")
+		return
+	}
+
+	w.Header().Set("Content-Type", sbom.MediaType)
+	w.Write(data)
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/sbom"
+)
+
+// staticVulnDB reports the same vulnerabilities for every executable
+type staticVulnDB []models.Vulnerability
+
+func (db staticVulnDB) Check(info *debug.BuildInfo) (*models.VulnReport, error) {
+	return &models.VulnReport{ScannedAt: time.Now(), Vulnerabilities: db}, nil
+}
+
+// buildHello compiles a trivial Go module and returns the executable
+func buildHello(t *testing.T) []byte {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "go.mod"), []byte("module example.com/hello\n\ngo 1.21\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	exe := filepath.Join(src, "hello")
+	cmd := exec.Command("go", "build", "-o", exe, ".")
+	cmd.Dir = src
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	content, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	return content
+}
+
+func TestGetSBOMHandler(t *testing.T) {
+	content := buildHello(t)
+	server, store, _ := newUploadServer(t)
+	vuln := models.Vulnerability{ID: "GO-2024-0001", Module: "stdlib", Version: "go1.21.0", Fixed: "1.21.9"}
+	WithVulnDB(staticVulnDB{vuln}, false)(server)
+	adminSession := loginAdmin(t, server)
+
+	require.NoError(t, store.SaveBinary(&models.Binary{ID: "hello", Name: "hello", SourceType: models.SourcePrebuilt}))
+
+	req, _ := http.NewRequest("GET", "/api/v1/binaries/hello/sbom", nil)
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	req, _ = http.NewRequest("POST", "/api/v1/binaries/hello/upload", bytes.NewReader(content))
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// Without blocking, vulnerabilities are only reported
+	var binary models.Binary
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &binary))
+	require.NotNil(t, binary.LastBuild.VulnReport)
+	assert.Equal(t, []models.Vulnerability{vuln}, binary.LastBuild.VulnReport.Vulnerabilities)
+
+	req, _ = http.NewRequest("GET", "/api/v1/binaries/hello/sbom", nil)
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, sbom.MediaType, rr.Header().Get("Content-Type"))
+
+	var bom sbom.BOM
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &bom))
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Equal(t, "pkg:golang/example.com/hello", bom.Metadata.Component.PURL)
+	assert.Equal(t, []sbom.Hash{{Alg: "SHA-256", Content: binary.SHA256}}, bom.Metadata.Component.Hashes)
+}
+
+func TestUploadBinaryHandler_BlocksVulnerable(t *testing.T) {
+	content := buildHello(t)
+	server, store, _ := newUploadServer(t)
+	WithVulnDB(staticVulnDB{{ID: "GO-2024-0001", Module: "stdlib", Version: "go1.21.0"}}, true)(server)
+	adminSession := loginAdmin(t, server)
+
+	require.NoError(t, store.SaveBinary(&models.Binary{ID: "hello", Name: "hello", SourceType: models.SourcePrebuilt}))
+
+	req, _ := http.NewRequest("POST", "/api/v1/binaries/hello/upload", bytes.NewReader(content))
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "GO-2024-0001")
+	b, err := store.GetBinary("hello")
+	require.NoError(t, err)
+	assert.Empty(t, b.BinaryPath)
+	assert.NoFileExists(t, "./data/binaries/hello")
+}
+
+func TestBuildBinaryHandler_BlockedBuildKeepsPreviousVersion(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockGit := new(MockGitManager)
+	server := NewServer(config.ServerConfig{}, mockStorage, mockGit, nil, WithVulnDB(staticVulnDB{}, true))
+	adminSession := loginAdmin(t, server)
+	t.Cleanup(func() {
+		os.RemoveAll("./data/binaries")
+		os.Remove("./data")
+	})
+
+	binary := &models.Binary{
+		ID:         "blocked",
+		RepoURL:    "http://example.com/repo.git",
+		Branch:     "main",
+		BuildPath:  ".",
+		BinaryPath: "./data/binaries/blocked",
+		Version:    "11111111",
+		Status:     "ready",
+	}
+	mockStorage.On("GetBinary", binary.ID).Return(binary, nil).Once()
+	mockStorage.On("UpdateBinary", mock.AnythingOfType("*models.Binary")).Return(nil).Twice()
+	mockGit.On("RemoteHead", binary.RepoURL, binary.Branch, (*models.Credential)(nil)).Return("abcdef123456", nil).Once()
+	mockGit.On("CloneOrUpdate", binary.RepoURL, binary.Branch, mock.AnythingOfType("string"), binary.BuildPath, models.CloneOptions{}, (*models.Credential)(nil)).Return(nil).Once()
+	mockGit.On("GetCommitHash", mock.AnythingOfType("string")).Return("abcdef123456", nil).Once()
+	mockGit.On("ResolveToolchain", mock.AnythingOfType("string"), binary.BuildPath, "").Return(nil, nil).Once()
+	// Not a Go executable, so its modules cannot be checked
+	mockGit.On("BuildGoBinary", mock.AnythingOfType("string"), binary.BuildPath, mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("models.BuildOptions")).
+		Run(func(args mock.Arguments) {
+			out := args.String(2)
+			os.MkdirAll(filepath.Dir(out), 0755)
+			os.WriteFile(out, []byte("binary"), 0755)
+		}).Return(nil).Once()
+
+	req, _ := http.NewRequest("POST", "/api/v1/binaries/"+binary.ID+"/build", nil)
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	waitForBuilds(t, server)
+
+	mockGit.AssertExpectations(t)
+	assert.Equal(t, "ready", binary.Status)
+	assert.Equal(t, "11111111", binary.Version)
+	assert.Equal(t, "failed", binary.LastBuild.Status)
+	assert.Contains(t, binary.LastBuild.Error, "cannot check for vulnerabilities")
+	assert.NoFileExists(t, binary.BinaryPath)
+	assert.NoFileExists(t, binary.BinaryPath+".new")
+}
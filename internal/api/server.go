@@ -4,47 +4,339 @@ package api
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"runtime/debug"
+	"sync"
 	"time"
 
+	"go_runner/internal/artifacts"
 	"go_runner/internal/config"
 	"go_runner/internal/models"
+	"go_runner/internal/provenance"
+	"go_runner/internal/ratelimit"
+	"go_runner/internal/sessions"
 	"go_runner/internal/storage"
+	"go_runner/internal/tlsconfig"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 )
 
 // GitManager interface for Git operations
 type GitManager interface {
-	CloneOrUpdate(repoURL, branch, targetPath string) error
-	GetCommitHash(repoPath string) (string, error)
-	BuildGoBinary(repoPath, buildPath, outputPath string) error
+	CloneOrUpdate(ctx context.Context, repoURL, branch, targetPath, buildPath string, opts models.CloneOptions, cred *models.Credential) error
+	RemoteHead(ctx context.Context, repoURL, branch string, cred *models.Credential) (string, error)
+	GetCommitHash(ctx context.Context, repoPath string) (string, error)
+	ExtractSource(repoPath, archivePath string) error
+	ResolveToolchain(repoPath, buildPath, goVersion string) (*models.Toolchain, error)
+	RunGates(ctx context.Context, repoPath, buildPath string, tc *models.Toolchain, opts models.BuildOptions, gates models.Gates) []models.GateResult
+	BuildGoBinary(repoPath, buildPath, outputPath string, tc *models.Toolchain, opts models.BuildOptions) error
+	RemoveCheckout(repoPath string) error
 }
 
 // Executor interface for binary execution
 type Executor interface {
-	Execute(ctx context.Context, binaryPath string, req *models.ExecutionRequest, started chan<- string) (*models.ExecutionResult, error)
+	Execute(ctx context.Context, digest string, req *models.ExecutionRequest, started chan<- string) (*models.ExecutionResult, error)
 	StopExecution(executionID string) error
 }
 
+// BuildCache interface for the shared Go module and build caches
+type BuildCache interface {
+	Stats() (*models.CacheStats, error)
+	Purge(target string) error
+}
+
+// CredentialStore interface for repository credentials
+type CredentialStore interface {
+	Save(cred *models.Credential) error
+	Get(id string) (*models.Credential, error)
+	List() ([]*models.Credential, error)
+	Delete(id string) error
+}
+
+// VulnDB interface for the offline vulnerability database
+type VulnDB interface {
+	Check(info *debug.BuildInfo) (*models.VulnReport, error)
+}
+
+// APIKeyStore interface for the keys that authorize execution requests
+type APIKeyStore interface {
+	Create(spec *models.APIKey) (*models.APIKey, string, error)
+	Get(id string) (*models.APIKey, error)
+	List() ([]*models.APIKey, error)
+	Revoke(id string) (*models.APIKey, error)
+	Rotate(id string, grace time.Duration) (*models.APIKey, string, error)
+	Authenticate(token string) (*models.APIKey, error)
+}
+
+// UserStore interface for the accounts that sign in to the admin UI and API
+type UserStore interface {
+	Create(user *models.User, password string) error
+	Get(id string) (*models.User, error)
+	List() ([]*models.User, error)
+	Update(user *models.User, password string) error
+	Delete(id string) error
+	Authenticate(username, password string) (*models.User, error)
+}
+
+// SessionStore interface for admin UI sign-ins
+type SessionStore interface {
+	Create(sess *models.Session) (string, error)
+	Lookup(token string) (*models.Session, error)
+	List() ([]*models.Session, error)
+	Revoke(id string) error
+	RevokeUser(userID string) error
+}
+
+// OIDCProvider interface for single sign-on with an OpenID Connect issuer
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (*models.User, error)
+	Authenticate(ctx context.Context, token string) (*models.User, error)
+}
+
+// AuditLog interface for the record of who changed or ran what
+type AuditLog interface {
+	Append(e *models.AuditEvent) error
+	Query(f models.AuditFilter) ([]*models.AuditEvent, error)
+	Verify() (*models.AuditVerification, error)
+}
+
+// QuotaStore interface for the executions and CPU time counted against API key quotas
+type QuotaStore interface {
+	Reserve(keyID string, q models.Quota) (*models.QuotaUsage, error)
+	Release(keyID string) error
+	AddCPU(keyID string, cpu time.Duration) error
+	Usage(keyID string) (*models.QuotaUsage, error)
+}
+
 // Server represents the API server
 type Server struct {
-	config   config.ServerConfig
-	router   *chi.Mux
-	server   *http.Server
-	storage  storage.Storage
-	git      GitManager
-	executor Executor
+	config    config.ServerConfig
+	router    *chi.Mux
+	server    *http.Server
+	storage   storage.Storage
+	git       GitManager
+	executor  Executor
+	artifacts artifacts.Store
+	cache     BuildCache
+	creds     CredentialStore
+	apiKeys   APIKeyStore
+	users     UserStore
+	audit     AuditLog
+
+	sessions   SessionStore
+	adminToken string
+	queryToken bool
+	// secureCookies marks cookies Secure, since the server speaks HTTPS
+	secureCookies bool
+
+	// oidc signs users in with single sign-on; oidcLogins holds the PKCE
+	// verifier and nonce of sign-ins in progress, by state
+	oidc       OIDCProvider
+	oidcMu     sync.Mutex
+	oidcLogins map[string]*oidcLogin
+
+	hookSecret string
+	maxUpload  int64
+	signer     *provenance.Signer
+	vulns      VulnDB
+	vulnBlock  bool
+
+	// repoSchemes lists the URL schemes git binaries may be cloned from
+	repoSchemes []string
+	// proxies are the trusted reverse proxies, whose forwarded headers name the client
+	proxies []*net.IPNet
+
+	// keyLimiter, ipLimiter and binaryLimiter limit how often executions
+	// start, per API key, client IP and binary; nil allows any rate
+	keyLimiter    *ratelimit.Limiter
+	ipLimiter     *ratelimit.Limiter
+	binaryLimiter *ratelimit.Limiter
+	quotas        QuotaStore
+	defaultQuota  models.Quota
+
+	// builds is canceled on shutdown to stop background builds
+	builds       context.Context
+	cancelBuilds context.CancelFunc
+
+	// running maps binaries with a build in flight to whether another build
+	// was requested meanwhile
+	buildMu sync.Mutex
+	running map[string]bool
+
+	// executions maps running executions to the API key that started them,
+	// nil for the admin
+	execMu     sync.Mutex
+	executions map[string]*models.APIKey
+}
+
+// Option configures optional Server dependencies
+type Option func(*Server)
+
+// WithArtifactStore sets where executables and build outputs are kept,
+// ./data/binaries by default
+func WithArtifactStore(store artifacts.Store) Option {
+	return func(s *Server) {
+		s.artifacts = store
+	}
+}
+
+// WithAdminToken accepts token as an admin login, for bootstrapping and
+// automation. Without it, only users can sign in.
+func WithAdminToken(token string) Option {
+	return func(s *Server) {
+		s.adminToken = token
+	}
+}
+
+// WithQueryToken also accepts the admin token as a ?token= query parameter.
+// URLs end up in logs and browser history, so it is off by default.
+func WithQueryToken() Option {
+	return func(s *Server) {
+		s.queryToken = true
+	}
+}
+
+// WithSessions keeps sign-ins in store, by default in memory for
+// defaultSessionIdle and at most defaultSessionMaxAge
+func WithSessions(store SessionStore) Option {
+	return func(s *Server) {
+		s.sessions = store
+	}
+}
+
+// WithOIDC lets users sign in with single sign-on and authenticate API
+// requests with bearer JWTs from the same issuer
+func WithOIDC(p OIDCProvider) Option {
+	return func(s *Server) {
+		s.oidc = p
+	}
+}
+
+// WithRateLimits limits how often executions may be started per API key
+// and per client IP. Either may be nil.
+func WithRateLimits(perKey, perIP *ratelimit.Limiter) Option {
+	return func(s *Server) {
+		s.keyLimiter = perKey
+		s.ipLimiter = perIP
+	}
+}
+
+// WithBinaryRateLimit limits how often each binary may be executed,
+// whichever key or client starts it
+func WithBinaryRateLimit(l *ratelimit.Limiter) Option {
+	return func(s *Server) {
+		s.binaryLimiter = l
+	}
+}
+
+// WithQuotas counts executions and CPU time per API key against the key's
+// own quota, or defaults for keys without one
+func WithQuotas(store QuotaStore, defaults models.Quota) Option {
+	return func(s *Server) {
+		s.quotas = store
+		s.defaultQuota = defaults
+	}
+}
+
+// WithAuditLog records every change and execution, with who made it, in log
+func WithAuditLog(log AuditLog) Option {
+	return func(s *Server) {
+		s.audit = log
+	}
+}
+
+// WithUsers lets users sign in with a password and act according to their role
+func WithUsers(store UserStore) Option {
+	return func(s *Server) {
+		s.users = store
+	}
+}
+
+// WithBuildCache exposes cache statistics and purging through the admin API
+func WithBuildCache(c BuildCache) Option {
+	return func(s *Server) {
+		s.cache = c
+	}
+}
+
+// WithWebhookSecret enables the push webhook receiver, verifying requests with secret
+func WithWebhookSecret(secret string) Option {
+	return func(s *Server) {
+		s.hookSecret = secret
+	}
+}
+
+// WithCredentials enables cloning private repositories with stored credentials
+func WithCredentials(store CredentialStore) Option {
+	return func(s *Server) {
+		s.creds = store
+	}
+}
+
+// WithAPIKeys validates execution requests against stored API keys. Without
+// it, the execution endpoints require the admin token.
+func WithAPIKeys(store APIKeyStore) Option {
+	return func(s *Server) {
+		s.apiKeys = store
+	}
+}
+
+// WithMaxUploadSize limits the size of uploaded source archives and executables
+func WithMaxUploadSize(n int64) Option {
+	return func(s *Server) {
+		s.maxUpload = n
+	}
+}
+
+// WithRepoSchemes sets the URL schemes, such as https and ssh, that git
+// binaries may be cloned from
+func WithRepoSchemes(schemes ...string) Option {
+	return func(s *Server) {
+		s.repoSchemes = schemes
+	}
+}
+
+// WithSigner signs build provenance and requires a valid signature before execution
+func WithSigner(signer *provenance.Signer) Option {
+	return func(s *Server) {
+		s.signer = signer
+	}
 }
 
-func NewServer(cfg config.ServerConfig, storage storage.Storage, git GitManager, exec Executor) *Server {
+// WithVulnDB checks every build against an offline vulnerability database.
+// With block set, binaries linking a vulnerable module are never promoted.
+func WithVulnDB(db VulnDB, block bool) Option {
+	return func(s *Server) {
+		s.vulns = db
+		s.vulnBlock = block
+	}
+}
+
+func NewServer(cfg config.ServerConfig, storage storage.Storage, git GitManager, exec Executor, opts ...Option) *Server {
 	s := &Server{
-		config:   cfg,
-		storage:  storage,
-		git:      git,
-		executor: exec,
+		config:    cfg,
+		storage:   storage,
+		git:       git,
+		executor:  exec,
+		artifacts: artifacts.NewLocalStore(defaultBinaryPath),
+		maxUpload: defaultMaxUpload,
+		running:   make(map[string]bool),
+
+		repoSchemes: defaultRepoSchemes,
+
+		executions: make(map[string]*models.APIKey),
+		sessions:   sessions.NewMemoryStore(defaultSessionIdle, defaultSessionMaxAge),
+		oidcLogins: make(map[string]*oidcLogin),
+
+		secureCookies: cfg.TLS.Enabled(),
+		proxies:       parseProxies(cfg.TrustedProxies),
+	}
+	s.builds, s.cancelBuilds = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
 	}
 	s.setupRoutes()
 	return s
@@ -55,25 +347,22 @@ func (s *Server) setupRoutes() {
 
 	// Middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(s.realIP)
+	// Outside the recoverer, so requests that panic are recorded as failed
+	r.Use(s.auditMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
 
 	// CORS
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
+	r.Use(s.corsMiddleware)
 
 	// Auth pages
 	r.Get("/login", s.loginPageHandler)
 	r.Post("/login", s.loginHandler)
 	r.Post("/logout", s.logoutHandler)
+	r.Get("/login/oidc", s.oidcLoginHandler)
+	r.Get("/login/oidc/callback", s.oidcCallbackHandler)
 
 	// API
 	r.Route("/api/v1", func(r chi.Router) {
@@ -81,22 +370,82 @@ func (s *Server) setupRoutes() {
 		r.Get("/health", s.healthHandler)
 		r.Get("/docs", s.swaggerUIHandler)
 		r.Get("/openapi.json", s.openAPIHandler)
+		r.Get("/provenance/key", s.signingKeyHandler)
 
-		// Admin-protected
+		// Signed by the git provider
+		r.Post("/hooks/{provider}", s.webhookHandler)
+
+		// Role-protected
 		r.Route("/binaries", func(r chi.Router) {
-			r.Use(s.authMiddleware)
-			r.Get("/", s.listBinariesHandler)
-			r.Post("/", s.createBinaryHandler)
-			r.Get("/{id}", s.getBinaryHandler)
-			r.Put("/{id}", s.updateBinaryHandler)
-			r.Delete("/{id}", s.deleteBinaryHandler)
-			r.Post("/{id}/build", s.buildBinaryHandler)
+			read := r.With(s.require(models.PermReadBinaries))
+			read.Get("/", s.listBinariesHandler)
+			read.Get("/{id}", s.getBinaryHandler)
+			read.Get("/{id}/provenance", s.getProvenanceHandler)
+			read.Get("/{id}/sbom", s.getSBOMHandler)
+			read.Get("/{id}/artifacts", s.listArtifactsHandler)
+			read.Get("/{id}/artifacts/{goos}-{goarch}", s.downloadArtifactHandler)
+
+			r.With(s.require(models.PermCreateBinaries)).Post("/", s.createBinaryHandler)
+
+			owner := r.With(s.requireBinaryOwner)
+			owner.Put("/{id}", s.updateBinaryHandler)
+			owner.Delete("/{id}", s.deleteBinaryHandler)
+			owner.Post("/{id}/upload", s.uploadBinaryHandler)
+
+			// Also open to API keys with a build:<id> scope
+			r.With(s.buildAuthMiddleware).Post("/{id}/build", s.buildBinaryHandler)
+		})
+
+		r.Route("/credentials", func(r chi.Router) {
+			r.Use(s.require(models.PermManageCredentials))
+			r.Get("/", s.listCredentialsHandler)
+			r.Post("/", s.createCredentialHandler)
+			r.Get("/{id}", s.getCredentialHandler)
+			r.Put("/{id}", s.updateCredentialHandler)
+			r.Delete("/{id}", s.deleteCredentialHandler)
+		})
+
+		r.Route("/apikeys", func(r chi.Router) {
+			r.Use(s.require(models.PermManageAPIKeys))
+			r.Get("/", s.listAPIKeysHandler)
+			r.Post("/", s.createAPIKeyHandler)
+			r.Get("/{id}", s.getAPIKeyHandler)
+			r.Delete("/{id}", s.revokeAPIKeyHandler)
+			r.Post("/{id}/rotate", s.rotateAPIKeyHandler)
+			r.Get("/{id}/usage", s.apiKeyUsageHandler)
+		})
+
+		r.Route("/users", func(r chi.Router) {
+			r.Use(s.require(models.PermManageUsers))
+			r.Get("/", s.listUsersHandler)
+			r.Post("/", s.createUserHandler)
+			r.Get("/{id}", s.getUserHandler)
+			r.Put("/{id}", s.updateUserHandler)
+			r.Delete("/{id}", s.deleteUserHandler)
+		})
+
+		r.Route("/sessions", func(r chi.Router) {
+			r.Use(s.requireUser)
+			r.Get("/", s.listSessionsHandler)
+			r.Get("/current", s.currentSessionHandler)
+			r.Delete("/{id}", s.revokeSessionHandler)
+		})
+
+		r.Route("/audit", func(r chi.Router) {
+			r.Use(s.require(models.PermReadAudit))
+			r.Get("/", s.listAuditHandler)
+			r.Get("/verify", s.verifyAuditHandler)
+		})
+
+		r.Route("/cache", func(r chi.Router) {
+			r.With(s.require(models.PermReadCache)).Get("/", s.cacheStatsHandler)
+			r.With(s.require(models.PermManageCache)).Delete("/", s.purgeCacheHandler)
 		})
 
 		// API key–protected
 		r.Route("/execute", func(r chi.Router) {
 			r.Use(s.apiKeyMiddleware)
-			r.Post("/", s.executeBinaryHandler)
+			r.With(s.rateLimit).Post("/", s.executeBinaryHandler)
 			r.Get("/{id}", s.getExecutionHandler)
 			r.Delete("/{id}", s.stopExecutionHandler)
 		})
@@ -120,12 +469,22 @@ func (s *Server) setupRoutes() {
 	}
 }
 
-// Start starts the server
+// Start starts the server, over HTTPS when a certificate is configured
 func (s *Server) Start() error {
-	return s.server.ListenAndServe()
+	if !s.config.TLS.Enabled() {
+		return s.server.ListenAndServe()
+	}
+
+	reloader, err := tlsconfig.NewReloader(s.config.TLS)
+	if err != nil {
+		return err
+	}
+	s.server.TLSConfig = reloader.Config()
+	return s.server.ListenAndServeTLS("", "")
 }
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancelBuilds()
 	return s.server.Shutdown(ctx)
 }
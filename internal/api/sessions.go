@@ -0,0 +1,98 @@
+// internal/api/sessions.go
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go_runner/internal/models"
+	"go_runner/internal/sessions"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Session lifetimes used unless the server is given a session store
+const (
+	defaultSessionIdle   = time.Hour
+	defaultSessionMaxAge = 24 * time.Hour
+)
+
+// listSessionsHandler returns the caller's sessions, or every session for
+// users who manage users
+func (s *Server) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+
+	list, err := s.sessions.List()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to list sessions")
+		return
+	}
+
+	visible := make([]*models.Session, 0, len(list))
+	for _, sess := range list {
+		if canSeeSession(user, sess) {
+			visible = append(visible, sess.Redacted())
+		}
+	}
+	s.respondJSON(w, http.StatusOK, visible)
+}
+
+// currentSessionHandler returns the session of the request, including the
+// CSRF token to send with POST, PUT and DELETE requests
+func (s *Server) currentSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sess := sessionFromContext(r.Context())
+	if sess == nil {
+		s.respondError(w, http.StatusNotFound, "Not signed in with a session")
+		return
+	}
+
+	current := *sess
+	current.TokenHash = ""
+	s.respondJSON(w, http.StatusOK, &current)
+}
+
+// revokeSessionHandler ends a session of the caller, or any session for
+// users who manage users
+func (s *Server) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+	id := chi.URLParam(r, "id")
+
+	list, err := s.sessions.List()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to list sessions")
+		return
+	}
+	var target *models.Session
+	for _, sess := range list {
+		if sess.ID == id && canSeeSession(user, sess) {
+			target = sess
+		}
+	}
+	if target == nil {
+		s.respondError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	err = s.sessions.Revoke(id)
+	if errors.Is(err, sessions.ErrSessionNotFound) {
+		s.respondError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to revoke session", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]string{"message": "Session revoked"})
+}
+
+// canSeeSession reports whether user may list and revoke sess
+func canSeeSession(user *models.User, sess *models.Session) bool {
+	if user.Can(models.PermManageUsers) {
+		return true
+	}
+	return user.ID != "" && sess.UserID == user.ID
+}
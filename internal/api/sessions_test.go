@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions_CSRFAndAdminToken(t *testing.T) {
+	mockStorage := new(MockStorage)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
+	adminSession := loginAdmin(t, server)
+	mockStorage.On("DeleteBinary", "1").Return(nil)
+
+	// The cookie is a random session token, not the admin token
+	assert.NotContains(t, adminSession.cookie.Value, server.adminToken)
+
+	deleteBinary := func(prepare func(*http.Request)) int {
+		req, _ := http.NewRequest("DELETE", "/api/v1/binaries/1", nil)
+		prepare(req)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Cookie-authenticated changes need the CSRF token
+	assert.Equal(t, http.StatusForbidden, deleteBinary(func(req *http.Request) { req.AddCookie(adminSession.cookie) }))
+	assert.Equal(t, http.StatusForbidden, deleteBinary(func(req *http.Request) {
+		req.AddCookie(adminSession.cookie)
+		req.Header.Set("X-CSRF-Token", "wrong")
+	}))
+	assert.Equal(t, http.StatusOK, deleteBinary(adminSession.authorize))
+
+	// Bearer tokens are not sent by browsers on their own, so need no CSRF token
+	assert.Equal(t, http.StatusOK, deleteBinary(func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+server.adminToken)
+	}))
+
+	// The admin token is not accepted in the query string unless enabled
+	req, _ := http.NewRequest("GET", "/api/v1/sessions?token="+server.adminToken, nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	WithQueryToken()(server)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Logging out ends the session on the server too
+	req, _ = http.NewRequest("POST", "/logout", nil)
+	req.AddCookie(adminSession.cookie)
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, http.StatusUnauthorized, deleteBinary(adminSession.authorize))
+}
+
+func TestSessions_ListAndRevoke(t *testing.T) {
+	store, err := users.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	server := NewServer(config.ServerConfig{}, nil, nil, nil, WithUsers(store))
+	adminSession := loginAdmin(t, server)
+
+	require.NoError(t, store.Create(&models.User{Username: "alice", Role: models.RoleViewer}, "alice password"))
+	require.NoError(t, store.Create(&models.User{Username: "bob", Role: models.RoleViewer}, "bob password"))
+	alice := login(t, server, url.Values{"username": {"alice"}, "password": {"alice password"}})
+	aliceLaptop := login(t, server, url.Values{"username": {"alice"}, "password": {"alice password"}})
+	bob := login(t, server, url.Values{"username": {"bob"}, "password": {"bob password"}})
+
+	list := func(sess *testSession) []models.Session {
+		req, _ := http.NewRequest("GET", "/api/v1/sessions", nil)
+		sess.authorize(req)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.NotContains(t, rr.Body.String(), "csrf_token")
+		var sessions []models.Session
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &sessions))
+		return sessions
+	}
+	revoke := func(sess *testSession, id string) int {
+		req, _ := http.NewRequest("DELETE", "/api/v1/sessions/"+id, nil)
+		sess.authorize(req)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Users see their own sessions; admins see everyone's
+	assert.Len(t, list(adminSession), 4)
+	aliceSessions := list(alice)
+	require.Len(t, aliceSessions, 2)
+	for _, sess := range aliceSessions {
+		assert.Equal(t, "alice", sess.Username)
+	}
+
+	// Revoking another user's session looks like it does not exist
+	assert.Equal(t, http.StatusNotFound, revoke(bob, aliceSessions[0].ID))
+
+	// Revoking one of your own sessions signs that browser out
+	var laptopID string
+	for _, sess := range aliceSessions {
+		req, _ := http.NewRequest("GET", "/api/v1/sessions/current", nil)
+		aliceLaptop.authorize(req)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		var current models.Session
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &current))
+		if sess.ID == current.ID {
+			laptopID = sess.ID
+		}
+	}
+	require.NotEmpty(t, laptopID)
+	assert.Equal(t, http.StatusOK, revoke(alice, laptopID))
+	assert.Len(t, list(alice), 1)
+
+	req, _ := http.NewRequest("GET", "/api/v1/sessions", nil)
+	aliceLaptop.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// Admins can end anyone's session
+	bobID := list(bob)[0].ID
+	assert.Equal(t, http.StatusOK, revoke(adminSession, bobID))
+	assert.Len(t, list(adminSession), 2)
+}
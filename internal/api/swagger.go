@@ -49,7 +49,7 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 		"openapi": "3.0.0",
 		"info": map[string]interface{}{
 			"title":       "Binary Executor API",
-			"description": "API for managing and executing Go binaries from Git repositories",
+			"description": "API for managing and executing Go binaries from Git repositories. Management routes check the role of the signed-in user (viewer, operator, maintainer or admin); the admin token acts as an admin.",
 			"version":     "1.0.0",
 		},
 		"servers": []map[string]interface{}{
@@ -127,13 +127,592 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 								},
 							},
 						},
+						"422": map[string]interface{}{
+							"description": "Invalid fields, each named with the reason",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"$ref": "#/components/schemas/ValidationError",
+									},
+								},
+							},
+						},
+						"413": map[string]interface{}{
+							"description": "Request body too large",
+						},
+					},
+				},
+			},
+			"/binaries/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get Binary",
+					"description": "Returns a specific binary",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "Binary ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Binary details",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"$ref": "#/components/schemas/Binary",
+									},
+								},
+							},
+						},
+					},
+				},
+				"delete": map[string]interface{}{
+					"summary":     "Delete Binary",
+					"description": "Deletes a binary together with its executable, build outputs and repository checkout",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "Binary ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Binary deleted",
+						},
+						"409": map[string]interface{}{
+							"description": "Binary is being built",
+						},
+					},
+				},
+			},
+			"/binaries/{id}/build": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Build Binary",
+					"description": "Triggers a build for the binary. Also accepts API keys holding build:<id>.",
+					"security":    []map[string][]string{{"bearerAuth": {}}, {"apiKey": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "Binary ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"202": map[string]interface{}{
+							"description": "Build started",
+						},
+						"403": map[string]interface{}{
+							"description": "API key lacks the build:<id> scope",
+						},
+						"422": map[string]interface{}{
+							"description": "Repository or branch not found",
+						},
+						"424": map[string]interface{}{
+							"description": "Repository authentication failed",
+						},
+						"502": map[string]interface{}{
+							"description": "Repository unreachable",
+						},
+						"504": map[string]interface{}{
+							"description": "Repository did not respond within GIT_TIMEOUT",
+						},
+					},
+				},
+			},
+			"/binaries/{id}/upload": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Upload Source or Executable",
+					"description": "Uploads a .tar.gz/.zip Go module for an archive binary, which is then built, or a static Linux ELF executable for a prebuilt binary, which becomes the active version immediately. Send the file as the raw body or as the \"file\" field of a multipart form.",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "Binary ID",
+						},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/octet-stream": map[string]interface{}{
+								"schema": map[string]string{"type": "string", "format": "binary"},
+							},
+							"multipart/form-data": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"file": map[string]string{"type": "string", "format": "binary"},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Executable installed",
+						},
+						"202": map[string]interface{}{
+							"description": "Archive stored and build started",
+						},
+						"409": map[string]interface{}{
+							"description": "Binary is built from git",
+						},
+						"413": map[string]interface{}{
+							"description": "Upload exceeds UPLOAD_MAX_SIZE_MB",
+						},
+						"415": map[string]interface{}{
+							"description": "Upload does not match the binary's source_type",
+						},
+						"422": map[string]interface{}{
+							"description": "Executable is not a static Linux ELF for the host architecture",
+						},
+					},
+				},
+			},
+			"/binaries/{id}/provenance": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get Provenance",
+					"description": "Returns the SLSA provenance of the active version as a DSSE envelope (payloadType application/vnd.in-toto+json). The envelope is signed when SIGNING_KEY_PATH is set.",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "Binary ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "DSSE envelope",
+						},
+						"404": map[string]interface{}{
+							"description": "Binary not found or built before provenance was recorded",
+						},
+					},
+				},
+			},
+			"/binaries/{id}/sbom": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get SBOM",
+					"description": "Returns a CycloneDX 1.5 SBOM of the modules linked into the active version, read from its Go build information.",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "Binary ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "CycloneDX JSON (application/vnd.cyclonedx+json)",
+						},
+						"404": map[string]interface{}{
+							"description": "Binary not found, or no SBOM recorded for its executable",
+						},
+					},
+				},
+			},
+			"/users": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "List Users",
+					"description": "Lists users without their password hashes. Requires the admin role.",
+					"security":    []map[string][]string{{"bearerAuth": {}}, {"sessionAuth": {}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "List of users",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type":  "array",
+										"items": map[string]interface{}{"$ref": "#/components/schemas/User"},
+									},
+								},
+							},
+						},
+					},
+				},
+				"post": map[string]interface{}{
+					"summary":     "Create User",
+					"description": "Adds a user who signs in at /login. Requires the admin role.",
+					"security":    []map[string][]string{{"bearerAuth": {}}, {"sessionAuth": {}}},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{"$ref": "#/components/schemas/UserInput"},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"201": map[string]interface{}{
+							"description": "User created",
+						},
+						"409": map[string]interface{}{
+							"description": "Username is already taken",
+						},
+					},
+				},
+			},
+			"/users/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":  "Get User",
+					"security": []map[string][]string{{"bearerAuth": {}}, {"sessionAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "User ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "User",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{"$ref": "#/components/schemas/User"},
+								},
+							},
+						},
+					},
+				},
+				"put": map[string]interface{}{
+					"summary":     "Update User",
+					"description": "Changes the role and, if given, the password of a user. The username cannot be changed.",
+					"security":    []map[string][]string{{"bearerAuth": {}}, {"sessionAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "User ID",
+						},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{"$ref": "#/components/schemas/UserInput"},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "User",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{"$ref": "#/components/schemas/User"},
+								},
+							},
+						},
+					},
+				},
+				"delete": map[string]interface{}{
+					"summary":     "Delete User",
+					"description": "Deletes a user and ends their sessions",
+					"security":    []map[string][]string{{"bearerAuth": {}}, {"sessionAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "User ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "User deleted",
+						},
+					},
+				},
+			},
+			"/sessions": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "List Sessions",
+					"description": "Lists the caller's sessions, or every session for admins",
+					"security":    []map[string][]string{{"bearerAuth": {}}, {"sessionAuth": {}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "List of sessions",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type":  "array",
+										"items": map[string]interface{}{"$ref": "#/components/schemas/Session"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/sessions/current": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get Current Session",
+					"description": "Returns the session of the request, including the CSRF token to send in X-CSRF-Token",
+					"security":    []map[string][]string{{"sessionAuth": {}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Session",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{"$ref": "#/components/schemas/Session"},
+								},
+							},
+						},
+						"404": map[string]interface{}{
+							"description": "Not signed in with a session",
+						},
+					},
+				},
+			},
+			"/sessions/{id}": map[string]interface{}{
+				"delete": map[string]interface{}{
+					"summary":     "Revoke Session",
+					"description": "Ends one of the caller's sessions, or any session for admins",
+					"security":    []map[string][]string{{"bearerAuth": {}}, {"sessionAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "Session ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Session revoked",
+						},
+						"404": map[string]interface{}{
+							"description": "Session not found",
+						},
+					},
+				},
+			},
+			"/audit": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "List Audit Events",
+					"description": "Lists the latest audit events, oldest first, or exports every matching event as JSON lines",
+					"security":    []map[string][]string{{"bearerAuth": {}}, {"sessionAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "actor",
+							"in":          "query",
+							"schema":      map[string]string{"type": "string"},
+							"description": "Username, key name or webhook provider",
+						},
+						{
+							"name":        "action",
+							"in":          "query",
+							"schema":      map[string]string{"type": "string"},
+							"description": "Action, or a prefix ending in a dot such as binary.",
+						},
+						{
+							"name":        "target",
+							"in":          "query",
+							"schema":      map[string]string{"type": "string"},
+							"description": "ID acted on",
+						},
+						{
+							"name":        "outcome",
+							"in":          "query",
+							"schema":      map[string]string{"type": "string"},
+							"description": "success, denied or failure",
+						},
+						{
+							"name":        "since",
+							"in":          "query",
+							"schema":      map[string]string{"type": "string", "format": "date-time"},
+							"description": "Earliest event time (RFC 3339)",
+						},
+						{
+							"name":        "until",
+							"in":          "query",
+							"schema":      map[string]string{"type": "string", "format": "date-time"},
+							"description": "Latest event time (RFC 3339)",
+						},
+						{
+							"name":        "limit",
+							"in":          "query",
+							"schema":      map[string]string{"type": "integer"},
+							"description": "Most recent events to return; defaults to 100, or all when exporting",
+						},
+						{
+							"name":        "format",
+							"in":          "query",
+							"schema":      map[string]string{"type": "string"},
+							"description": "jsonl to export one event per line",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Audit events",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type":  "array",
+										"items": map[string]interface{}{"$ref": "#/components/schemas/AuditEvent"},
+									},
+								},
+								"application/x-ndjson": map[string]interface{}{
+									"schema": map[string]interface{}{"$ref": "#/components/schemas/AuditEvent"},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid filter",
+						},
+						"404": map[string]interface{}{
+							"description": "Audit log is not configured",
+						},
+					},
+				},
+			},
+			"/audit/verify": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Verify Audit Log",
+					"description": "Checks the hash chain of the whole audit log",
+					"security":    []map[string][]string{{"bearerAuth": {}}, {"sessionAuth": {}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Verification result",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{"$ref": "#/components/schemas/AuditVerification"},
+								},
+							},
+						},
+						"404": map[string]interface{}{
+							"description": "Audit log is not configured",
+						},
+					},
+				},
+			},
+			"/apikeys": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "List API Keys",
+					"description": "Lists API keys, including revoked ones, without their hashes",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "List of API keys",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type":  "array",
+										"items": map[string]interface{}{"$ref": "#/components/schemas/APIKey"},
+									},
+								},
+							},
+						},
+						"404": map[string]interface{}{
+							"description": "API keys are disabled",
+						},
+					},
+				},
+				"post": map[string]interface{}{
+					"summary":     "Create API Key",
+					"description": "Issues an API key for the execution endpoints. The key is only returned in this response.",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"name"},
+									"properties": map[string]interface{}{
+										"name":       map[string]string{"type": "string"},
+										"team":       map[string]string{"type": "string"},
+										"scopes":     map[string]interface{}{"$ref": "#/components/schemas/APIKeyScopes"},
+										"expires_at": map[string]string{"type": "string", "format": "date-time"},
+										"quota":      map[string]interface{}{"$ref": "#/components/schemas/Quota"},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"201": map[string]interface{}{
+							"description": "The API key and, in key, its secret",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{"$ref": "#/components/schemas/IssuedAPIKey"},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/apikeys/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":  "Get API Key",
+					"security": []map[string][]string{{"bearerAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "API key ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "API key",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{"$ref": "#/components/schemas/APIKey"},
+								},
+							},
+						},
+					},
+				},
+				"delete": map[string]interface{}{
+					"summary":     "Revoke API Key",
+					"description": "Permanently disables an API key. The record is kept for attribution.",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "API key ID",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Revoked API key",
+						},
 					},
 				},
 			},
-			"/binaries/{id}": map[string]interface{}{
-				"get": map[string]interface{}{
-					"summary":     "Get Binary",
-					"description": "Returns a specific binary",
+			"/apikeys/{id}/rotate": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Rotate API Key",
+					"description": "Replaces the secret of an API key. The previous key stays valid for grace_period seconds (at most 7 days).",
 					"security":    []map[string][]string{{"bearerAuth": {}}},
 					"parameters": []map[string]interface{}{
 						{
@@ -141,25 +720,40 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 							"in":          "path",
 							"required":    true,
 							"schema":      map[string]string{"type": "string"},
-							"description": "Binary ID",
+							"description": "API key ID",
+						},
+					},
+					"requestBody": map[string]interface{}{
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"grace_period": map[string]string{"type": "integer", "description": "Seconds, 0 to retire the old key at once"},
+									},
+								},
+							},
 						},
 					},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
-							"description": "Binary details",
+							"description": "The API key and its new secret",
 							"content": map[string]interface{}{
 								"application/json": map[string]interface{}{
-									"schema": map[string]interface{}{
-										"$ref": "#/components/schemas/Binary",
-									},
+									"schema": map[string]interface{}{"$ref": "#/components/schemas/IssuedAPIKey"},
 								},
 							},
 						},
+						"409": map[string]interface{}{
+							"description": "API key has been revoked",
+						},
 					},
 				},
-				"delete": map[string]interface{}{
-					"summary":     "Delete Binary",
-					"description": "Deletes a binary",
+			},
+			"/apikeys/{id}/usage": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get API Key Usage",
+					"description": "Returns the quota of an API key and what it has executed in the current UTC day and month",
 					"security":    []map[string][]string{{"bearerAuth": {}}},
 					"parameters": []map[string]interface{}{
 						{
@@ -167,20 +761,48 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 							"in":          "path",
 							"required":    true,
 							"schema":      map[string]string{"type": "string"},
-							"description": "Binary ID",
+							"description": "API key ID",
 						},
 					},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
-							"description": "Binary deleted",
+							"description": "Quota and usage",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"quota": map[string]interface{}{"$ref": "#/components/schemas/Quota"},
+											"usage": map[string]interface{}{"$ref": "#/components/schemas/QuotaUsage"},
+										},
+									},
+								},
+							},
+						},
+						"404": map[string]interface{}{
+							"description": "API key not found",
 						},
 					},
 				},
 			},
-			"/binaries/{id}/build": map[string]interface{}{
-				"post": map[string]interface{}{
-					"summary":     "Build Binary",
-					"description": "Triggers a build for the binary",
+			"/provenance/key": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get Signing Key",
+					"description": "Returns the PEM encoded ed25519 public key that verifies provenance signatures. The key ID is returned in the X-Key-Id header.",
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Public key",
+						},
+						"404": map[string]interface{}{
+							"description": "Provenance signing is not configured",
+						},
+					},
+				},
+			},
+			"/binaries/{id}/artifacts/{target}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Download Artifact",
+					"description": "Downloads a cross-compiled artifact of the active version. The SHA-256 is returned in the X-Checksum-Sha256 header.",
 					"security":    []map[string][]string{{"bearerAuth": {}}},
 					"parameters": []map[string]interface{}{
 						{
@@ -190,10 +812,79 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 							"schema":      map[string]string{"type": "string"},
 							"description": "Binary ID",
 						},
+						{
+							"name":        "target",
+							"in":          "path",
+							"required":    true,
+							"schema":      map[string]string{"type": "string"},
+							"description": "Target in os-arch form, e.g. linux-amd64",
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Artifact contents",
+							"content": map[string]interface{}{
+								"application/octet-stream": map[string]interface{}{
+									"schema": map[string]string{"type": "string", "format": "binary"},
+								},
+							},
+						},
+						"404": map[string]interface{}{
+							"description": "Binary or artifact not found",
+						},
+					},
+				},
+			},
+			"/hooks/{provider}": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Push Webhook",
+					"description": "Builds every binary whose repo_url and branch match the pushed branch. GitHub, Gitea and generic payloads are verified with an HMAC-SHA256 of the body using WEBHOOK_SECRET; GitLab sends WEBHOOK_SECRET as X-Gitlab-Token.",
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "provider",
+							"in":       "path",
+							"required": true,
+							"schema":   map[string]interface{}{"type": "string", "enum": []string{"github", "gitlab", "gitea", "generic"}},
+						},
 					},
 					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Event ignored (not a branch push)",
+						},
 						"202": map[string]interface{}{
-							"description": "Build started",
+							"description": "Builds enqueued for the matching binaries",
+						},
+						"401": map[string]interface{}{
+							"description": "Invalid signature",
+						},
+					},
+				},
+			},
+			"/cache": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Cache Stats",
+					"description": "Returns the size of the shared Go module and build caches",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Cache statistics",
+						},
+					},
+				},
+				"delete": map[string]interface{}{
+					"summary":     "Purge Cache",
+					"description": "Empties the module cache, the build cache or both",
+					"security":    []map[string][]string{{"bearerAuth": {}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":   "target",
+							"in":     "query",
+							"schema": map[string]interface{}{"type": "string", "enum": []string{"mod", "build", "all"}},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Cache purged",
 						},
 					},
 				},
@@ -201,7 +892,7 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 			"/execute": map[string]interface{}{
 				"post": map[string]interface{}{
 					"summary":     "Execute Binary",
-					"description": "Executes a binary with given parameters",
+					"description": "Executes a binary with given parameters. The API key needs execute:*, execute:<id> or execute:tag=<label> for one of the binary's labels.",
 					"security":    []map[string][]string{{"apiKey": {}}},
 					"requestBody": map[string]interface{}{
 						"required": true,
@@ -224,6 +915,28 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 								},
 							},
 						},
+						"403": map[string]interface{}{
+							"description": "API key is not allowed to execute this binary",
+						},
+						"409": map[string]interface{}{
+							"description": "The executable does not match its recorded SHA-256 or signed provenance",
+						},
+						"422": map[string]interface{}{
+							"description": "Invalid fields, each named with the reason",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"$ref": "#/components/schemas/ValidationError",
+									},
+								},
+							},
+						},
+						"413": map[string]interface{}{
+							"description": "Request body too large",
+						},
+						"429": map[string]interface{}{
+							"description": "Rate limit or quota exceeded; see the RateLimit-* and Retry-After headers",
+						},
 					},
 				},
 			},
@@ -231,14 +944,22 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 		"components": map[string]interface{}{
 			"securitySchemes": map[string]interface{}{
 				"bearerAuth": map[string]interface{}{
-					"type":   "http",
-					"scheme": "bearer",
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "The admin token, or with single sign-on a JWT from the OIDC issuer whose groups map to a role",
 				},
 				"apiKey": map[string]interface{}{
 					"type": "apiKey",
 					"in":   "header",
 					"name": "X-API-Key",
 				},
+				"sessionAuth": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "cookie",
+					"name":        "session",
+					"description": "Set by POST /login. Requests that change anything must also send the session's CSRF token in the X-CSRF-Token header.",
+				},
 			},
 			"schemas": map[string]interface{}{
 				"Binary": map[string]interface{}{
@@ -247,25 +968,246 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 						"id":          map[string]string{"type": "string"},
 						"name":        map[string]string{"type": "string"},
 						"description": map[string]string{"type": "string"},
-						"repo_url":    map[string]string{"type": "string"},
-						"branch":      map[string]string{"type": "string"},
-						"build_path":  map[string]string{"type": "string"},
-						"status":      map[string]string{"type": "string", "enum": "pending,building,ready,failed"},
-						"version":     map[string]string{"type": "string"},
-						"last_built":  map[string]string{"type": "string", "format": "date-time"},
-						"created_at":  map[string]string{"type": "string", "format": "date-time"},
-						"updated_at":  map[string]string{"type": "string", "format": "date-time"},
+						"labels": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "string"},
+							"description": "Groups binaries for execute:tag=<label> API key scopes",
+						},
+						"owner":       map[string]string{"type": "string", "description": "Username of the maintainer who may change the binary"},
+						"source_type": map[string]string{"type": "string", "enum": "git,archive,prebuilt"},
+						"repo_url":    map[string]string{"type": "string", "description": "Required for git binaries. A URL with a scheme allowed by GIT_ALLOWED_SCHEMES, or git@host:path for ssh; passwords belong in a credential"},
+						"branch":      map[string]string{"type": "string", "description": "Required for git binaries"},
+						"build_path":  map[string]string{"type": "string", "description": "Relative path of the main package, which must stay inside the repository"},
+						"build_options": map[string]interface{}{
+							"$ref": "#/components/schemas/BuildOptions",
+						},
+						"clone_options": map[string]interface{}{
+							"$ref": "#/components/schemas/CloneOptions",
+						},
+						"targets": map[string]interface{}{
+							"type":  "array",
+							"items": map[string]interface{}{"$ref": "#/components/schemas/Target"},
+						},
+						"go_version":    map[string]string{"type": "string", "description": "Explicit Go toolchain, e.g. 1.22.3"},
+						"credential_id": map[string]string{"type": "string", "description": "Credential used to clone a private repository"},
+						"gates": map[string]interface{}{
+							"$ref": "#/components/schemas/Gates",
+						},
+						"auto_build": map[string]interface{}{
+							"$ref": "#/components/schemas/AutoBuild",
+						},
+						"commit": map[string]string{"type": "string", "description": "Full commit hash of the active version"},
+						"sha256": map[string]string{"type": "string", "description": "Digest of the active executable, verified before every execution"},
+						"upload": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"filename":    map[string]string{"type": "string"},
+								"sha256":      map[string]string{"type": "string"},
+								"size":        map[string]string{"type": "integer"},
+								"uploaded_at": map[string]string{"type": "string", "format": "date-time"},
+							},
+						},
+						"last_build": map[string]interface{}{
+							"$ref": "#/components/schemas/BuildRecord",
+						},
+						"status":     map[string]string{"type": "string", "enum": "pending,building,ready,failed"},
+						"version":    map[string]string{"type": "string"},
+						"last_built": map[string]string{"type": "string", "format": "date-time"},
+						"created_at": map[string]string{"type": "string", "format": "date-time"},
+						"updated_at": map[string]string{"type": "string", "format": "date-time"},
 					},
 				},
 				"BinaryInput": map[string]interface{}{
 					"type":     "object",
-					"required": []string{"name", "repo_url", "branch"},
+					"required": []string{"name"},
 					"properties": map[string]interface{}{
-						"name":        map[string]string{"type": "string"},
-						"description": map[string]string{"type": "string"},
-						"repo_url":    map[string]string{"type": "string"},
+						"name":        map[string]interface{}{"type": "string", "maxLength": 255},
+						"description": map[string]interface{}{"type": "string", "maxLength": 1000},
+						"labels": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "string"},
+							"description": "Groups binaries for execute:tag=<label> API key scopes",
+						},
+						"owner":       map[string]string{"type": "string", "description": "Only admins can set the owner; maintainers own what they create"},
+						"source_type": map[string]string{"type": "string", "enum": "git,archive,prebuilt"},
+						"repo_url":    map[string]string{"type": "string", "description": "Required for git binaries"},
 						"branch":      map[string]string{"type": "string"},
 						"build_path":  map[string]string{"type": "string"},
+						"build_options": map[string]interface{}{
+							"$ref": "#/components/schemas/BuildOptions",
+						},
+						"clone_options": map[string]interface{}{
+							"$ref": "#/components/schemas/CloneOptions",
+						},
+						"targets": map[string]interface{}{
+							"type":  "array",
+							"items": map[string]interface{}{"$ref": "#/components/schemas/Target"},
+						},
+						"go_version":    map[string]string{"type": "string", "description": "Explicit Go toolchain, e.g. 1.22.3"},
+						"credential_id": map[string]string{"type": "string", "description": "Credential used to clone a private repository"},
+						"gates": map[string]interface{}{
+							"$ref": "#/components/schemas/Gates",
+						},
+						"auto_build": map[string]interface{}{
+							"$ref": "#/components/schemas/AutoBuild",
+						},
+					},
+				},
+				"BuildOptions": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"tags": map[string]interface{}{
+							"type":  "array",
+							"items": map[string]string{"type": "string"},
+						},
+						"ldflags":     map[string]string{"type": "string", "description": "Only -s, -w and -X importpath.name=value; supports ${COMMIT}, ${SHORT_COMMIT}, ${BRANCH}, ${BUILD_TIME}"},
+						"trimpath":    map[string]string{"type": "boolean"},
+						"race":        map[string]string{"type": "boolean"},
+						"cgo_enabled": map[string]string{"type": "boolean"},
+						"goos":        map[string]string{"type": "string"},
+						"goarch":      map[string]string{"type": "string"},
+					},
+				},
+				"Gates": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"vet":          map[string]string{"type": "boolean", "description": "Run go vet ./... in the build path"},
+						"test":         map[string]string{"type": "boolean", "description": "Run go test ./... in the build path"},
+						"test_timeout": map[string]string{"type": "integer", "description": "Seconds, 0 for 10 minutes"},
+						"checks": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"name": map[string]string{"type": "string"},
+									"command": map[string]interface{}{
+										"type":  "array",
+										"items": map[string]string{"type": "string"},
+									},
+									"timeout": map[string]string{"type": "integer", "description": "Seconds, 0 for 10 minutes"},
+								},
+							},
+						},
+					},
+				},
+				"GateResult": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name":        map[string]string{"type": "string"},
+						"status":      map[string]string{"type": "string", "enum": "passed,failed,timeout"},
+						"exit_code":   map[string]string{"type": "integer"},
+						"output":      map[string]string{"type": "string", "description": "Last 16KB of combined output"},
+						"duration_ms": map[string]string{"type": "integer"},
+					},
+				},
+				"VulnReport": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"database_modified": map[string]string{"type": "string", "format": "date-time"},
+						"scanned_at":        map[string]string{"type": "string", "format": "date-time"},
+						"vulnerabilities": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"id": map[string]string{"type": "string", "description": "Go vulnerability ID, e.g. GO-2023-2186"},
+									"aliases": map[string]interface{}{
+										"type":  "array",
+										"items": map[string]string{"type": "string"},
+									},
+									"summary": map[string]string{"type": "string"},
+									"module":  map[string]string{"type": "string", "description": "stdlib for the standard library"},
+									"version": map[string]string{"type": "string"},
+									"fixed":   map[string]string{"type": "string", "description": "Earliest fixed version, if any"},
+								},
+							},
+						},
+					},
+				},
+				"AutoBuild": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"enabled":  map[string]string{"type": "boolean"},
+						"interval": map[string]string{"type": "integer", "description": "Seconds between polls of the upstream branch, 0 for WATCH_DEFAULT_INTERVAL"},
+					},
+				},
+				"CloneOptions": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"depth":  map[string]string{"type": "integer", "description": "Commits of history to fetch, 0 for all"},
+						"sparse": map[string]string{"type": "boolean", "description": "Only check out the build path and its module root"},
+						"sparse_paths": map[string]interface{}{
+							"type":  "array",
+							"items": map[string]string{"type": "string"},
+						},
+						"filter":     map[string]string{"type": "string", "description": "Partial clone filter: blob:none, blob:limit=<n>[kmg] or tree:<depth>"},
+						"submodules": map[string]string{"type": "boolean"},
+					},
+				},
+				"Target": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"goos":   map[string]string{"type": "string"},
+						"goarch": map[string]string{"type": "string"},
+					},
+				},
+				"Artifact": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"goos":   map[string]string{"type": "string"},
+						"goarch": map[string]string{"type": "string"},
+						"sha256": map[string]string{"type": "string"},
+						"size":   map[string]string{"type": "integer"},
+					},
+				},
+				"BuildRecord": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id":            map[string]string{"type": "string"},
+						"commit":        map[string]string{"type": "string"},
+						"source_sha256": map[string]string{"type": "string", "description": "Checksum of the uploaded archive or executable"},
+						"sha256":        map[string]string{"type": "string", "description": "Digest of the built executable"},
+						"status":        map[string]string{"type": "string", "enum": "building,succeeded,failed"},
+						"options": map[string]interface{}{
+							"$ref": "#/components/schemas/BuildOptions",
+						},
+						"toolchain": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"version": map[string]string{"type": "string"},
+								"source":  map[string]string{"type": "string", "enum": "system,cache,offline,downloaded"},
+							},
+						},
+						"artifacts": map[string]interface{}{
+							"type":  "array",
+							"items": map[string]interface{}{"$ref": "#/components/schemas/Artifact"},
+						},
+						"gates": map[string]interface{}{
+							"type":  "array",
+							"items": map[string]interface{}{"$ref": "#/components/schemas/GateResult"},
+						},
+						"vuln_report": map[string]interface{}{
+							"$ref": "#/components/schemas/VulnReport",
+						},
+						"error":       map[string]string{"type": "string"},
+						"started_at":  map[string]string{"type": "string", "format": "date-time"},
+						"finished_at": map[string]string{"type": "string", "format": "date-time"},
+					},
+				},
+				"ValidationError": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"error": map[string]string{"type": "string"},
+						"fields": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"field":   map[string]string{"type": "string", "description": "JSON name of the field, dotted for nested objects"},
+									"message": map[string]string{"type": "string"},
+								},
+							},
+						},
 					},
 				},
 				"ExecutionRequest": map[string]interface{}{
@@ -274,29 +1216,154 @@ func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
 					"properties": map[string]interface{}{
 						"binary_id": map[string]string{"type": "string"},
 						"args": map[string]interface{}{
-							"type":  "array",
-							"items": map[string]string{"type": "string"},
+							"type":        "array",
+							"maxItems":    256,
+							"items":       map[string]string{"type": "string"},
+							"description": "At most 128 KiB in total",
 						},
 						"env": map[string]interface{}{
-							"type":  "array",
-							"items": map[string]string{"type": "string"},
+							"type":        "array",
+							"maxItems":    256,
+							"items":       map[string]string{"type": "string"},
+							"description": "NAME=value entries, at most 128 KiB in total",
+						},
+						"stdin":   map[string]string{"type": "string", "description": "At most 1 MiB"},
+						"timeout": map[string]interface{}{"type": "integer", "minimum": 0, "description": "Timeout in seconds"},
+					},
+				},
+				"User": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id":         map[string]string{"type": "string"},
+						"username":   map[string]string{"type": "string"},
+						"role":       map[string]string{"type": "string", "enum": "viewer,operator,maintainer,admin"},
+						"created_at": map[string]string{"type": "string", "format": "date-time"},
+						"updated_at": map[string]string{"type": "string", "format": "date-time"},
+					},
+				},
+				"UserInput": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"username": map[string]string{"type": "string", "description": "Only on create"},
+						"password": map[string]string{"type": "string", "description": "8 to 72 bytes; optional on update"},
+						"role":     map[string]string{"type": "string", "enum": "viewer,operator,maintainer,admin"},
+					},
+				},
+				"Session": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id":           map[string]string{"type": "string"},
+						"user_id":      map[string]string{"type": "string", "description": "Empty when signed in with the admin token"},
+						"username":     map[string]string{"type": "string"},
+						"csrf_token":   map[string]string{"type": "string", "description": "Only returned for the current session"},
+						"ip":           map[string]string{"type": "string"},
+						"user_agent":   map[string]string{"type": "string"},
+						"created_at":   map[string]string{"type": "string", "format": "date-time"},
+						"last_seen_at": map[string]string{"type": "string", "format": "date-time"},
+						"expires_at":   map[string]string{"type": "string", "format": "date-time"},
+					},
+				},
+				"AuditEvent": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"seq":        map[string]string{"type": "integer"},
+						"time":       map[string]string{"type": "string", "format": "date-time"},
+						"actor":      map[string]string{"type": "string"},
+						"actor_id":   map[string]string{"type": "string"},
+						"actor_type": map[string]string{"type": "string", "description": "user, sso, certificate, admin_token, api_key, webhook or anonymous"},
+						"action":     map[string]string{"type": "string", "description": "e.g. binary.create or execution.start"},
+						"target":     map[string]string{"type": "string"},
+						"details":    map[string]interface{}{"type": "object", "additionalProperties": map[string]string{"type": "string"}},
+						"request_id": map[string]string{"type": "string"},
+						"ip":         map[string]string{"type": "string"},
+						"method":     map[string]string{"type": "string"},
+						"path":       map[string]string{"type": "string"},
+						"status":     map[string]string{"type": "integer"},
+						"outcome":    map[string]string{"type": "string", "enum": "success,denied,failure"},
+						"prev_hash":  map[string]string{"type": "string"},
+						"hash":       map[string]string{"type": "string", "description": "SHA-256 of the event, including prev_hash"},
+					},
+				},
+				"AuditVerification": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"valid":     map[string]string{"type": "boolean"},
+						"events":    map[string]string{"type": "integer"},
+						"head_hash": map[string]string{"type": "string"},
+						"broken_at": map[string]string{"type": "integer", "description": "Sequence number of the first event that does not verify"},
+						"error":     map[string]string{"type": "string"},
+					},
+				},
+				"APIKey": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id":                  map[string]string{"type": "string"},
+						"name":                map[string]string{"type": "string"},
+						"prefix":              map[string]string{"type": "string", "description": "Start of the key, to recognise it"},
+						"team":                map[string]string{"type": "string", "description": "Keys of a team see and stop each other's executions"},
+						"scopes":              map[string]interface{}{"$ref": "#/components/schemas/APIKeyScopes"},
+						"expires_at":          map[string]string{"type": "string", "format": "date-time"},
+						"revoked_at":          map[string]string{"type": "string", "format": "date-time"},
+						"last_used_at":        map[string]string{"type": "string", "format": "date-time"},
+						"created_at":          map[string]string{"type": "string", "format": "date-time"},
+						"rotated_at":          map[string]string{"type": "string", "format": "date-time"},
+						"previous_expires_at": map[string]string{"type": "string", "format": "date-time", "description": "When the key replaced by the last rotation stops working"},
+						"quota":               map[string]interface{}{"$ref": "#/components/schemas/Quota"},
+					},
+				},
+				"Quota": map[string]interface{}{
+					"type":        "object",
+					"description": "Limits per UTC day and month; 0 or unset is unlimited. Keys without a quota use the server's defaults.",
+					"properties": map[string]interface{}{
+						"daily_executions":    map[string]string{"type": "integer"},
+						"monthly_executions":  map[string]string{"type": "integer"},
+						"daily_cpu_seconds":   map[string]string{"type": "integer"},
+						"monthly_cpu_seconds": map[string]string{"type": "integer"},
+					},
+				},
+				"QuotaUsage": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"day":              map[string]string{"type": "string", "format": "date"},
+						"day_executions":   map[string]string{"type": "integer"},
+						"day_cpu_ms":       map[string]string{"type": "integer"},
+						"month":            map[string]string{"type": "string", "description": "YYYY-MM"},
+						"month_executions": map[string]string{"type": "integer"},
+						"month_cpu_ms":     map[string]string{"type": "integer"},
+					},
+				},
+				"APIKeyScopes": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]string{"type": "string"},
+					"description": "execute:*, execute:<binary-id>, execute:tag=<label>, read:executions or build:<binary-id>. Defaults to execute:* and read:executions.",
+				},
+				"IssuedAPIKey": map[string]interface{}{
+					"allOf": []map[string]interface{}{
+						{"$ref": "#/components/schemas/APIKey"},
+						{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"key": map[string]string{"type": "string", "description": "Secret to send as X-API-Key; not retrievable later"},
+							},
 						},
-						"stdin":   map[string]string{"type": "string"},
-						"timeout": map[string]string{"type": "integer", "description": "Timeout in seconds"},
 					},
 				},
 				"ExecutionResult": map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
-						"id":          map[string]string{"type": "string"},
-						"binary_id":   map[string]string{"type": "string"},
-						"status":      map[string]string{"type": "string", "enum": "running,completed,failed,timeout"},
-						"exit_code":   map[string]string{"type": "integer"},
-						"stdout":      map[string]string{"type": "string"},
-						"stderr":      map[string]string{"type": "string"},
-						"started_at":  map[string]string{"type": "string", "format": "date-time"},
-						"finished_at": map[string]string{"type": "string", "format": "date-time"},
-						"duration_ms": map[string]string{"type": "integer"},
+						"id":           map[string]string{"type": "string"},
+						"binary_id":    map[string]string{"type": "string"},
+						"api_key_id":   map[string]string{"type": "string", "description": "API key that requested the execution"},
+						"api_key_name": map[string]string{"type": "string"},
+						"team":         map[string]string{"type": "string"},
+						"status":       map[string]string{"type": "string", "enum": "running,completed,failed,timeout"},
+						"exit_code":    map[string]string{"type": "integer"},
+						"stdout":       map[string]string{"type": "string"},
+						"stderr":       map[string]string{"type": "string"},
+						"started_at":   map[string]string{"type": "string", "format": "date-time"},
+						"finished_at":  map[string]string{"type": "string", "format": "date-time"},
+						"duration_ms":  map[string]string{"type": "integer"},
+						"cpu_ms":       map[string]string{"type": "integer", "description": "User and system CPU time"},
 					},
 				},
 			},
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/tlsconfig"
+	"go_runner/internal/tlsconfig/tlstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecureCookies(t *testing.T) {
+	plain := NewServer(config.ServerConfig{}, nil, nil, nil)
+	assert.False(t, loginAdmin(t, plain).cookie.Secure)
+
+	tlsCfg := config.TLSConfig{CertFile: "server.crt", KeyFile: "server.key"}
+	secure := NewServer(config.ServerConfig{TLS: tlsCfg}, nil, nil, nil)
+	assert.True(t, loginAdmin(t, secure).cookie.Secure)
+}
+
+func TestClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := tlstest.NewCA("test CA")
+	certFile, keyFile := ca.WriteServer(dir)
+	caFile := filepath.Join(dir, "clients.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.PEM, 0600))
+
+	tlsCfg := config.TLSConfig{
+		CertFile:      certFile,
+		KeyFile:       keyFile,
+		ClientCAFile:  caFile,
+		ClientRoleMap: map[string]string{"URI:spiffe://example.com/ci": models.RoleMaintainer},
+	}
+	reloader, err := tlsconfig.NewReloader(tlsCfg)
+	require.NoError(t, err)
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("ListBinaries").Return([]*models.Binary{}, nil)
+	server := NewServer(config.ServerConfig{TLS: tlsCfg}, mockStorage, nil, nil)
+	ts := httptest.NewUnstartedServer(server.router)
+	ts.TLS = reloader.Config()
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	clientWith := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+	}
+	do := func(client *http.Client, method, path, origin string) int {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(`{"name":"tool","repo_url":"https://example.com/tool.git","branch":"main"}`))
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	ci := clientWith(ca.Client(tlstest.Identity{CommonName: "ci", URIs: []string{"spiffe://example.com/ci"}}))
+	assert.Equal(t, http.StatusOK, do(ci, "GET", "/api/v1/binaries", ""))
+	assert.Equal(t, http.StatusForbidden, do(ci, "GET", "/api/v1/users", ""))
+	// Binaries created with a certificate are owned by its identity
+	mockStorage.On("SaveBinary", mock.MatchedBy(func(b *models.Binary) bool { return b.Owner == "URI:spiffe://example.com/ci" })).Return(nil).Once()
+	assert.Equal(t, http.StatusCreated, do(ci, "POST", "/api/v1/binaries", ""))
+	// Web pages cannot use a certificate the browser presents on its own
+	assert.Equal(t, http.StatusUnauthorized, do(ci, "POST", "/api/v1/binaries", "https://evil.example.com"))
+
+	unmapped := clientWith(ca.Client(tlstest.Identity{CommonName: "someone"}))
+	assert.Equal(t, http.StatusUnauthorized, do(unmapped, "GET", "/api/v1/binaries", ""))
+	assert.Equal(t, http.StatusUnauthorized, do(clientWith(), "GET", "/api/v1/binaries", ""))
+
+	// Certificates from other CAs prove nothing
+	other := clientWith(tlstest.NewCA("other CA").Client(tlstest.Identity{URIs: []string{"spiffe://example.com/ci"}}))
+	assert.Equal(t, http.StatusUnauthorized, do(other, "GET", "/api/v1/binaries", ""))
+	mockStorage.AssertExpectations(t)
+}
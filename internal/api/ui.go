@@ -19,7 +19,13 @@ func (s *Server) adminUIHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "template error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	_ = t.Execute(w, nil)
+	var csrf string
+	if sess := sessionFromContext(r.Context()); sess != nil {
+		csrf = sess.CSRFToken
+	}
+	_ = t.Execute(w, map[string]any{
+		"CSRFToken": csrf,
+	})
 }
 
 // loginUIHandler renders login.html
@@ -0,0 +1,216 @@
+// internal/api/upload.go
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"go_runner/internal/models"
+	"go_runner/internal/upload"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// defaultMaxUpload bounds uploads when WithMaxUploadSize is not used
+const defaultMaxUpload = 256 << 20
+
+// uploadReadTimeout replaces the server read timeout for upload bodies
+const uploadReadTimeout = 10 * time.Minute
+
+// uploadBinaryHandler accepts a source archive or a prebuilt executable for a
+// binary that is not built from git. The file may be sent as the raw request
+// body or as the "file" field of a multipart form.
+func (s *Server) uploadBinaryHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	binary, err := s.storage.GetBinary(id)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Binary not found")
+		return
+	}
+	if binary.IsGit() {
+		s.respondError(w, http.StatusConflict, "Binary is built from git; set source_type to archive or prebuilt to upload")
+		return
+	}
+
+	// Large uploads would not fit in the default read timeout
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadReadTimeout))
+	body, filename, err := uploadBody(http.MaxBytesReader(w, r.Body, s.maxUpload), r)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Sniff the format before choosing where the file goes
+	br := bufio.NewReader(body)
+	header, _ := br.Peek(512)
+	kind := upload.Detect(header)
+
+	switch {
+	case binary.SourceType == models.SourceArchive && (kind == upload.KindTarGz || kind == upload.KindZip):
+	case binary.SourceType == models.SourcePrebuilt && kind == upload.KindELF:
+	case binary.SourceType == models.SourceArchive:
+		s.respondError(w, http.StatusUnsupportedMediaType, "Expected a .tar.gz or .zip archive")
+		return
+	default:
+		s.respondError(w, http.StatusUnsupportedMediaType, "Expected a Linux ELF executable")
+		return
+	}
+
+	// Stage next to the final location so it can be moved into place
+	tmp, info, err := saveUpload(filepath.Dir(s.artifacts.UploadPath(binary.ID)), br)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			s.respondError(w, http.StatusRequestEntityTooLarge, "Upload too large")
+			return
+		}
+		slog.Error("Failed to save upload", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to save upload")
+		return
+	}
+	defer os.Remove(tmp)
+	info.Filename = filename
+
+	if binary.SourceType == models.SourcePrebuilt {
+		s.installPrebuilt(w, binary, tmp, info)
+		return
+	}
+
+	// Keep the archive so the binary can be rebuilt without uploading again
+	if err := os.Rename(tmp, s.artifacts.UploadPath(binary.ID)); err != nil {
+		slog.Error("Failed to store upload", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to save upload")
+		return
+	}
+	binary.Upload = info
+	if err := s.storage.UpdateBinary(binary); err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to update binary")
+		return
+	}
+
+	s.respondBuildQueued(w, binary)
+}
+
+// installPrebuilt validates an uploaded executable and makes it the active version
+func (s *Server) installPrebuilt(w http.ResponseWriter, binary *models.Binary, tmp string, info *models.Upload) {
+	if err := upload.ValidateELF(tmp, runtime.GOARCH); err != nil {
+		s.respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	now := time.Now()
+	build := &models.BuildRecord{
+		ID:           uuid.New().String(),
+		SourceSHA256: info.SHA256,
+		SHA256:       info.SHA256,
+		Status:       "succeeded",
+		StartedAt:    info.UploadedAt,
+		FinishedAt:   now,
+	}
+
+	outputPath := s.artifacts.BinaryPath(binary.ID)
+	defer removeStaged(tmp)
+	if err := s.inspectBinary(binary, build, tmp); err != nil {
+		s.respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	binary.Upload = info
+	if err := s.writeProvenance(tmp, s.uploadProvenance(binary, build)); err != nil {
+		slog.Error("Failed to write provenance", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to write provenance")
+		return
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to install executable")
+		return
+	}
+	if err := installBinary(tmp, outputPath); err != nil {
+		slog.Error("Failed to install executable", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to install executable")
+		return
+	}
+
+	binary.LastBuild = build
+	binary.BinaryPath = outputPath
+	binary.SHA256 = info.SHA256
+	binary.Version = info.SHA256[:8]
+	binary.Status = "ready"
+	binary.LastBuilt = now
+	if err := s.storage.UpdateBinary(binary); err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to update binary")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, binary)
+}
+
+// uploadBody returns the uploaded file and its name, if the client sent one
+func uploadBody(body io.Reader, r *http.Request) (io.Reader, string, error) {
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Disposition"))
+		return body, uploadFilename(params["filename"]), nil
+	}
+
+	mr := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, "", errors.New("missing file field")
+		}
+		if err != nil {
+			return nil, "", errors.New("invalid multipart body")
+		}
+		if part.FormName() == "file" {
+			return part, uploadFilename(part.FileName()), nil
+		}
+	}
+}
+
+// uploadFilename strips any directories from a client supplied file name
+func uploadFilename(name string) string {
+	if name == "" {
+		return ""
+	}
+	return filepath.Base(filepath.Clean("/" + name))
+}
+
+// saveUpload streams r to a new file in dir and checksums it
+func saveUpload(dir string, r io.Reader) (string, *models.Upload, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, err
+	}
+	f, err := os.CreateTemp(dir, ".upload-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", nil, err
+	}
+
+	return f.Name(), &models.Upload{
+		SHA256:     hex.EncodeToString(h.Sum(nil)),
+		Size:       size,
+		UploadedAt: time.Now(),
+	}, nil
+}
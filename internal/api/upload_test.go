@@ -0,0 +1,163 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/artifacts"
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/repository"
+	"go_runner/internal/storage"
+)
+
+func newUploadServer(t *testing.T) (*Server, storage.Storage, string) {
+	t.Helper()
+	store := storage.NewFileStorage(t.TempDir())
+	require.NoError(t, store.Init())
+	repos := t.TempDir()
+	gm := cloneOnlyGitManager{repository.NewGitManager(repos)}
+	server := NewServer(config.ServerConfig{}, store, gm, nil, WithArtifactStore(artifacts.NewLocalStore(t.TempDir())))
+	return server, store, repos
+}
+
+func multipartUpload(t *testing.T, path, filename string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = fw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	req, _ := http.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestUploadBinaryHandler_Archive(t *testing.T) {
+	server, store, repos := newUploadServer(t)
+	adminSession := loginAdmin(t, server)
+
+	binary := &models.Binary{ID: "from-archive", Name: "tool", SourceType: models.SourceArchive, BuildPath: "."}
+	require.NoError(t, store.SaveBinary(binary))
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"tool/go.mod":  "module example.com/tool\n",
+		"tool/main.go": "package main\n\nfunc main() {}\n",
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		tw.Write([]byte(content))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	req := multipartUpload(t, "/api/v1/binaries/from-archive/upload", "tool-src.tar.gz", archive.Bytes())
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+	assert.Eventually(t, func() bool {
+		b, err := store.GetBinary("from-archive")
+		return err == nil && b.Status == "ready"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	b, _ := store.GetBinary("from-archive")
+	require.NotNil(t, b.Upload)
+	assert.Equal(t, "tool-src.tar.gz", b.Upload.Filename)
+	assert.Equal(t, b.Upload.SHA256[:8], b.Version)
+	assert.Equal(t, b.Upload.SHA256, b.LastBuild.SourceSHA256)
+	assert.Empty(t, b.Commit)
+	assert.FileExists(t, filepath.Join(repos, "repo_from-archive", "main.go"))
+
+	// Executables are not accepted as source
+	req, _ = http.NewRequest("POST", "/api/v1/binaries/from-archive/upload", bytes.NewReader([]byte("\x7fELF")))
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+}
+
+func TestUploadBinaryHandler_Prebuilt(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	server, store, _ := newUploadServer(t)
+	adminSession := loginAdmin(t, server)
+
+	// A static executable for the host
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	exe := filepath.Join(src, "hello")
+	cmd := exec.Command("go", "build", "-o", exe, "main.go")
+	cmd.Dir = src
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GO111MODULE=off")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	content, err := os.ReadFile(exe)
+	require.NoError(t, err)
+
+	binary := &models.Binary{ID: "prebuilt", Name: "hello", SourceType: models.SourcePrebuilt}
+	require.NoError(t, store.SaveBinary(binary))
+
+	req, _ := http.NewRequest("POST", "/api/v1/binaries/prebuilt/upload", bytes.NewReader(content))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var resp models.Binary
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "ready", resp.Status)
+	assert.Equal(t, resp.Upload.SHA256[:8], resp.Version)
+	info, err := os.Stat(resp.BinaryPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// Shell scripts and other non-ELF files are rejected
+	req, _ = http.NewRequest("POST", "/api/v1/binaries/prebuilt/upload", bytes.NewReader([]byte("#!/bin/sh\n")))
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+
+	// Prebuilt binaries are never built
+	req, _ = http.NewRequest("POST", "/api/v1/binaries/prebuilt/build", nil)
+	adminSession.authorize(req)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestUploadBinaryHandler_GitBinary(t *testing.T) {
+	mockStorage := new(MockStorage)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil)
+	adminSession := loginAdmin(t, server)
+
+	mockStorage.On("GetBinary", "1").Return(&models.Binary{ID: "1", SourceType: models.SourceGit}, nil)
+
+	req, _ := http.NewRequest("POST", "/api/v1/binaries/1/upload", bytes.NewReader([]byte("\x1f\x8b")))
+	adminSession.authorize(req)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
@@ -0,0 +1,172 @@
+// internal/api/users.go
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go_runner/internal/models"
+	"go_runner/internal/users"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type userRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role"`
+}
+
+// listUsersHandler returns all users without their password hashes
+func (s *Server) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	if s.users == nil {
+		s.respondError(w, http.StatusNotFound, "Users are not configured")
+		return
+	}
+
+	list, err := s.users.List()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "Failed to list users")
+		return
+	}
+
+	redacted := make([]*models.User, 0, len(list))
+	for _, user := range list {
+		redacted = append(redacted, user.Redacted())
+	}
+	s.respondJSON(w, http.StatusOK, redacted)
+}
+
+// createUserHandler adds a user with a password and role
+func (s *Server) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	if s.users == nil {
+		s.respondError(w, http.StatusNotFound, "Users are not configured")
+		return
+	}
+
+	var req userRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := validateUserRequest(&req, true); err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user := &models.User{Username: req.Username, Role: req.Role}
+	err := s.users.Create(user, req.Password)
+	if errors.Is(err, users.ErrUsernameTaken) {
+		s.respondError(w, http.StatusConflict, "Username is already taken")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to create user", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to create user")
+		return
+	}
+	auditFromContext(r.Context()).setTarget(user.ID)
+
+	s.respondJSON(w, http.StatusCreated, user.Redacted())
+}
+
+// getUserHandler returns a user without its password hash
+func (s *Server) getUserHandler(w http.ResponseWriter, r *http.Request) {
+	if s.users == nil {
+		s.respondError(w, http.StatusNotFound, "Users are not configured")
+		return
+	}
+
+	user, err := s.users.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, user.Redacted())
+}
+
+// updateUserHandler changes the role and, if given, the password of a user,
+// which signs the user out everywhere. Usernames cannot be changed, since
+// binaries are owned by username.
+func (s *Server) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	if s.users == nil {
+		s.respondError(w, http.StatusNotFound, "Users are not configured")
+		return
+	}
+
+	var req userRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := validateUserRequest(&req, false); err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user := &models.User{ID: chi.URLParam(r, "id"), Role: req.Role}
+	err := s.users.Update(user, req.Password)
+	if errors.Is(err, users.ErrUserNotFound) {
+		s.respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to update user", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+	// A new password ends every sign-in made with the old one
+	if req.Password != "" {
+		if err := s.sessions.RevokeUser(user.ID); err != nil {
+			slog.Error("Failed to revoke sessions after password change", slog.String("error", err.Error()))
+		}
+	}
+
+	s.respondJSON(w, http.StatusOK, user.Redacted())
+}
+
+// deleteUserHandler removes a user. Binaries it owns can then only be
+// changed by admins.
+func (s *Server) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	if s.users == nil {
+		s.respondError(w, http.StatusNotFound, "Users are not configured")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	err := s.users.Delete(id)
+	if errors.Is(err, users.ErrUserNotFound) {
+		s.respondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to delete user", slog.String("error", err.Error()))
+		s.respondError(w, http.StatusInternalServerError, "Failed to delete user")
+		return
+	}
+	if err := s.sessions.RevokeUser(id); err != nil {
+		slog.Error("Failed to revoke sessions of deleted user", slog.String("error", err.Error()))
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]string{"message": "User deleted successfully"})
+}
+
+// validateUserRequest checks the fields of a create or update request, so
+// bad input is reported as 400 rather than a store failure
+func validateUserRequest(req *userRequest, create bool) error {
+	if create {
+		if err := models.ValidateUsername(req.Username); err != nil {
+			return err
+		}
+	}
+	if err := models.ValidateRole(req.Role); err != nil {
+		return err
+	}
+	if create || req.Password != "" {
+		return users.ValidatePassword(req.Password)
+	}
+	return nil
+}
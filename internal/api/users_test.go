@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRoles(t *testing.T) {
+	mockStorage := new(MockStorage)
+	store, err := users.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	server := NewServer(config.ServerConfig{}, mockStorage, nil, nil, WithUsers(store))
+	adminSession := loginAdmin(t, server)
+
+	do := func(sess *testSession, method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		sess.authorize(req)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Only admins manage users, and the password hash is never returned
+	rr := do(adminSession, "POST", "/api/v1/users", map[string]string{"username": "alice", "password": "alice password", "role": "maintainer"})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.NotContains(t, rr.Body.String(), "password")
+	var alice models.User
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &alice))
+	rr = do(adminSession, "POST", "/api/v1/users", map[string]string{"username": "bob", "password": "bob password", "role": "viewer"})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var bob models.User
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &bob))
+	rr = do(adminSession, "POST", "/api/v1/users", map[string]string{"username": "mallory", "password": "mallory password", "role": "maintainer"})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, http.StatusConflict, do(adminSession, "POST", "/api/v1/users", map[string]string{"username": "Bob", "password": "bob password", "role": "viewer"}).Code)
+	assert.Equal(t, http.StatusBadRequest, do(adminSession, "POST", "/api/v1/users", map[string]string{"username": "eve", "password": "eve password", "role": "root"}).Code)
+
+	aliceSession := login(t, server, url.Values{"username": {"alice"}, "password": {"alice password"}})
+	bobSession := login(t, server, url.Values{"username": {"bob"}, "password": {"bob password"}})
+	mallorySession := login(t, server, url.Values{"username": {"mallory"}, "password": {"mallory password"}})
+
+	form := url.Values{"username": {"alice"}, "password": {"wrong password"}}
+	req, _ := http.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// Viewers can read but not change anything
+	mockStorage.On("ListBinaries").Return([]*models.Binary{}, nil)
+	assert.Equal(t, http.StatusOK, do(bobSession, "GET", "/api/v1/binaries", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do(bobSession, "POST", "/api/v1/binaries", models.Binary{Name: "x"}).Code)
+	assert.Equal(t, http.StatusForbidden, do(bobSession, "DELETE", "/api/v1/cache", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do(bobSession, "GET", "/api/v1/users", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do(aliceSession, "GET", "/api/v1/apikeys", nil).Code)
+
+	// Maintainers own the binaries they create
+	mockStorage.On("SaveBinary", mock.MatchedBy(func(b *models.Binary) bool { return b.Owner == "alice" })).Return(nil).Once()
+	rr = do(aliceSession, "POST", "/api/v1/binaries", models.Binary{Name: "tool", RepoURL: "https://example.com/tool.git", Branch: "main", Owner: "mallory"})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created models.Binary
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "alice", created.Owner)
+
+	mockStorage.On("GetBinary", created.ID).Return(&created, nil)
+	mockStorage.On("UpdateBinary", mock.MatchedBy(func(b *models.Binary) bool { return b.Owner == "alice" })).Return(nil).Once()
+	assert.Equal(t, http.StatusOK, do(aliceSession, "PUT", "/api/v1/binaries/"+created.ID, models.Binary{Name: "renamed", RepoURL: created.RepoURL, Branch: "main"}).Code)
+	assert.Equal(t, http.StatusForbidden, do(mallorySession, "PUT", "/api/v1/binaries/"+created.ID, models.Binary{Name: "stolen", RepoURL: created.RepoURL, Branch: "main"}).Code)
+	assert.Equal(t, http.StatusForbidden, do(mallorySession, "DELETE", "/api/v1/binaries/"+created.ID, nil).Code)
+
+	// Admins can reassign ownership
+	mockStorage.On("UpdateBinary", mock.MatchedBy(func(b *models.Binary) bool { return b.Owner == "mallory" })).Return(nil).Once()
+	assert.Equal(t, http.StatusOK, do(adminSession, "PUT", "/api/v1/binaries/"+created.ID, models.Binary{Name: "tool", RepoURL: created.RepoURL, Branch: "main", Owner: "mallory"}).Code)
+
+	// Password changes end existing sessions
+	rr = do(adminSession, "PUT", "/api/v1/users/"+bob.ID, map[string]string{"role": "viewer", "password": "new bob password"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, do(bobSession, "GET", "/api/v1/binaries", nil).Code)
+	bobSession = login(t, server, url.Values{"username": {"bob"}, "password": {"new bob password"}})
+	assert.Equal(t, http.StatusOK, do(bobSession, "GET", "/api/v1/binaries", nil).Code)
+
+	// Role changes and deletions apply to existing sessions
+	rr = do(adminSession, "PUT", "/api/v1/users/"+alice.ID, map[string]string{"role": "viewer"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusForbidden, do(aliceSession, "POST", "/api/v1/binaries", models.Binary{Name: "x"}).Code)
+	require.Equal(t, http.StatusOK, do(adminSession, "DELETE", "/api/v1/users/"+alice.ID, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do(aliceSession, "GET", "/api/v1/binaries", nil).Code)
+
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,230 @@
+// internal/api/validate.go
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"go_runner/internal/models"
+	"go_runner/internal/validation"
+)
+
+// Limits on what an execution request may pass to the process
+const (
+	maxArgBytes  = 128 << 10 // all arguments together
+	maxEnvBytes  = 128 << 10 // all variables together
+	maxStdinSize = 1 << 20
+)
+
+// Limits on request bodies, enforced while they are read. JSON escaping can
+// make stdin and arguments several times larger on the wire.
+const (
+	maxExecutionBody  = 4 * (maxArgBytes + maxEnvBytes + maxStdinSize)
+	maxBinaryBody     = 1 << 20
+	maxCredentialBody = 64 << 10
+)
+
+// defaultRepoSchemes are the repository URL schemes allowed when
+// WithRepoSchemes is not used
+var defaultRepoSchemes = []string{"https", "ssh"}
+
+// scpRepoURL matches scp-like ssh addresses such as git@github.com:org/repo.git
+var scpRepoURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^/\\].*$`)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validationResponse is the body of 422 responses, naming each invalid field
+type validationResponse struct {
+	Error  string            `json:"error"`
+	Fields validation.Errors `json:"fields"`
+}
+
+// decodeBody decodes r's JSON body into v, reading at most limit bytes. It
+// answers 413 or 400 and returns false when the body is too large or malformed.
+func (s *Server) decodeBody(w http.ResponseWriter, r *http.Request, limit int64, v interface{}) bool {
+	return s.decode(w, r, limit, v, false)
+}
+
+// decodeStrictBody is decodeBody for requests whose unknown fields are
+// rejected, so a misspelt field is not silently dropped
+func (s *Server) decodeStrictBody(w http.ResponseWriter, r *http.Request, limit int64, v interface{}) bool {
+	return s.decode(w, r, limit, v, true)
+}
+
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, limit int64, v interface{}, strict bool) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	err := dec.Decode(v)
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		s.respondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+		return false
+	case err != nil:
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return false
+	}
+	return true
+}
+
+// respondValidation reports the invalid fields of a request
+func (s *Server) respondValidation(w http.ResponseWriter, errs validation.Errors) {
+	s.respondJSON(w, http.StatusUnprocessableEntity, validationResponse{Error: "Validation failed", Fields: errs})
+}
+
+// validateBinary checks a binary sent to create or update one
+func (s *Server) validateBinary(b *models.Binary) validation.Errors {
+	errs := validation.Struct(b)
+
+	errs.Check("labels", models.ValidateLabels(b.Labels))
+	if !errs.Has("branch") {
+		errs.Check("branch", models.ValidateBranch(b.Branch))
+	}
+	errs.Check("build_options", b.BuildOptions.Validate())
+	errs.Check("clone_options", b.CloneOptions.Validate())
+	errs.Check("targets", models.ValidateTargets(b.Targets))
+	errs.Check("go_version", models.ValidateGoVersion(b.GoVersion))
+	errs.Check("gates", b.Gates.Validate())
+	errs.Check("auto_build", b.AutoBuild.Validate())
+	if b.AutoBuild != nil && b.AutoBuild.Enabled && !b.IsGit() {
+		errs.Add("auto_build", "only git binaries can be rebuilt automatically")
+	}
+
+	if !errs.Has("build_path") {
+		errs.Check("build_path", checkBuildPath(b.BuildPath))
+	}
+	if b.IsGit() && b.RepoURL != "" && !errs.Has("repo_url") {
+		errs.Check("repo_url", s.checkRepoURL(b.RepoURL))
+	}
+
+	if b.CredentialID != "" {
+		if _, err := s.resolveCredential(b); err != nil {
+			errs.Add("credential_id", "unknown credential")
+		}
+	}
+	return errs
+}
+
+// checkBuildPath checks the build path stays inside the repository
+func checkBuildPath(p string) error {
+	if p == "" {
+		return nil
+	}
+	if strings.ContainsAny(p, "\\\x00") {
+		return errors.New("must be a slash-separated path")
+	}
+	if path.IsAbs(p) {
+		return errors.New("must be relative to the repository root")
+	}
+	if clean := path.Clean(p); clean == ".." || strings.HasPrefix(clean, "../") {
+		return errors.New("must stay inside the repository")
+	}
+	return nil
+}
+
+// checkRepoURL checks a repository URL uses an allowed scheme and carries
+// no password, which belongs in a credential
+func (s *Server) checkRepoURL(raw string) error {
+	allowed := strings.Join(s.repoSchemes, ", ")
+	if !strings.Contains(raw, "://") {
+		if scpRepoURL.MatchString(raw) && s.repoSchemeAllowed("ssh") {
+			return nil
+		}
+		return fmt.Errorf("must be a URL with one of the schemes %s", allowed)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.New("must be an absolute URL")
+	}
+	if !s.repoSchemeAllowed(u.Scheme) {
+		return fmt.Errorf("scheme %s is not allowed; use one of %s", u.Scheme, allowed)
+	}
+	if u.Host == "" && u.Scheme != "file" {
+		return errors.New("must be an absolute URL")
+	}
+	if _, ok := u.User.Password(); ok {
+		return errors.New("must not contain a password; use credential_id")
+	}
+	return nil
+}
+
+func (s *Server) repoSchemeAllowed(scheme string) bool {
+	for _, allowed := range s.repoSchemes {
+		if strings.EqualFold(allowed, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCredential checks a credential sent to create or update one
+func validateCredential(c *models.Credential) validation.Errors {
+	errs := validation.Struct(c)
+
+	switch c.Type {
+	case models.CredentialToken:
+		if c.Secret == "" {
+			errs.Add("secret", "is required")
+		}
+	case models.CredentialBasic:
+		if c.Username == "" {
+			errs.Add("username", "is required for basic credentials")
+		}
+		if c.Secret == "" {
+			errs.Add("secret", "is required")
+		}
+	case models.CredentialSSH:
+		if !strings.Contains(c.Secret, "PRIVATE KEY") {
+			errs.Add("secret", "must be a PEM private key")
+		}
+		if strings.TrimSpace(c.KnownHosts) == "" {
+			errs.Add("known_hosts", "is required to pin the host key")
+		}
+	}
+	return errs
+}
+
+// validateExecution checks an execution request against the limits on
+// arguments, environment and stdin
+func validateExecution(req *models.ExecutionRequest) validation.Errors {
+	errs := validation.Struct(req)
+
+	size := 0
+	for _, arg := range req.Args {
+		size += len(arg)
+		if strings.ContainsRune(arg, 0) {
+			errs.Add("args", "must not contain NUL bytes")
+			break
+		}
+	}
+	if size > maxArgBytes && !errs.Has("args") {
+		errs.Add("args", "must total at most %d bytes", maxArgBytes)
+	}
+
+	size = 0
+	for i, kv := range req.Env {
+		size += len(kv)
+		name, _, ok := strings.Cut(kv, "=")
+		if !ok || !envName.MatchString(name) || strings.ContainsRune(kv, 0) {
+			errs.Add("env", "entry %d must be NAME=value", i)
+			break
+		}
+	}
+	if size > maxEnvBytes && !errs.Has("env") {
+		errs.Add("env", "must total at most %d bytes", maxEnvBytes)
+	}
+
+	if len(req.Stdin) > maxStdinSize {
+		errs.Add("stdin", "must be at most %d bytes", maxStdinSize)
+	}
+	return errs
+}
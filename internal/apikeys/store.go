@@ -0,0 +1,331 @@
+// internal/apikeys/store.go
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go_runner/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrInvalidKey  = errors.New("invalid api key")
+	ErrKeyExpired  = errors.New("api key has expired")
+	ErrKeyRevoked  = errors.New("api key has been revoked")
+)
+
+// tokenPrefix starts every key so leaked keys are easy to scan for
+const tokenPrefix = "grk_"
+
+// lastUsedResolution limits how often last-used times are written to disk
+const lastUsedResolution = time.Minute
+
+// Store interface for API keys
+type Store interface {
+	Create(spec *models.APIKey) (*models.APIKey, string, error)
+	Get(id string) (*models.APIKey, error)
+	List() ([]*models.APIKey, error)
+	Revoke(id string) (*models.APIKey, error)
+	Rotate(id string, grace time.Duration) (*models.APIKey, string, error)
+	Authenticate(token string) (*models.APIKey, error)
+}
+
+// FileStore keeps API keys in a single 0600 JSON file
+type FileStore struct {
+	path string
+	now  func() time.Time
+	mu   sync.Mutex
+	keys map[string]*models.APIKey
+	// lastSaved records when each key's last use was persisted
+	lastSaved map[string]time.Time
+}
+
+// NewFileStore creates an API key store under dir
+func NewFileStore(dir string) (*FileStore, error) {
+	fs := &FileStore{
+		path:      filepath.Join(dir, "apikeys.json"),
+		now:       time.Now,
+		keys:      make(map[string]*models.APIKey),
+		lastSaved: make(map[string]time.Time),
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create api key directory: %w", err)
+	}
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// Create issues a key with the name, team, scopes and expiry of spec and
+// returns it together with its secret token. Keys without scopes get
+// models.DefaultScopes.
+func (fs *FileStore) Create(spec *models.APIKey) (*models.APIKey, string, error) {
+	if strings.TrimSpace(spec.Name) == "" {
+		return nil, "", errors.New("name is required")
+	}
+	if spec.ExpiresAt != nil && !spec.ExpiresAt.After(fs.now()) {
+		return nil, "", errors.New("expires_at must be in the future")
+	}
+	if err := models.ValidateScopes(spec.Scopes); err != nil {
+		return nil, "", err
+	}
+	if err := spec.Quota.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	scopes := spec.Scopes
+	if len(scopes) == 0 {
+		scopes = models.DefaultScopes
+	}
+	key := &models.APIKey{
+		ID:        uuid.New().String(),
+		Name:      spec.Name,
+		Team:      spec.Team,
+		Scopes:    append([]string(nil), scopes...),
+		ExpiresAt: spec.ExpiresAt,
+		Quota:     spec.Quota,
+		CreatedAt: fs.now(),
+	}
+	token, err := setSecret(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.keys[key.ID] = key
+	if err := fs.save(); err != nil {
+		delete(fs.keys, key.ID)
+		return nil, "", err
+	}
+	return copyKey(key), token, nil
+}
+
+// Get retrieves a key by ID
+func (fs *FileStore) Get(id string) (*models.APIKey, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	key, ok := fs.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return copyKey(key), nil
+}
+
+// List returns all keys, newest first
+func (fs *FileStore) List() ([]*models.APIKey, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	keys := make([]*models.APIKey, 0, len(fs.keys))
+	for _, key := range fs.keys {
+		keys = append(keys, copyKey(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Revoke permanently disables a key. The record is kept so executions made
+// with it can still be attributed.
+func (fs *FileStore) Revoke(id string) (*models.APIKey, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	key, ok := fs.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		now := fs.now()
+		key.RevokedAt = &now
+		key.PreviousSalt, key.PreviousHash, key.PreviousExpiresAt = "", "", nil
+		if err := fs.save(); err != nil {
+			return nil, err
+		}
+	}
+	return copyKey(key), nil
+}
+
+// Rotate replaces the secret of a key and returns the new token. The old
+// token keeps working for grace, so clients can be switched over.
+func (fs *FileStore) Rotate(id string, grace time.Duration) (*models.APIKey, string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	key, ok := fs.keys[id]
+	if !ok {
+		return nil, "", ErrKeyNotFound
+	}
+	if key.Revoked() {
+		return nil, "", ErrKeyRevoked
+	}
+
+	rotated := *key
+	now := fs.now()
+	rotated.RotatedAt = &now
+	rotated.PreviousSalt, rotated.PreviousHash, rotated.PreviousExpiresAt = "", "", nil
+	if grace > 0 {
+		until := now.Add(grace)
+		rotated.PreviousSalt, rotated.PreviousHash = key.Salt, key.Hash
+		rotated.PreviousExpiresAt = &until
+	}
+	token, err := setSecret(&rotated)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fs.keys[id] = &rotated
+	if err := fs.save(); err != nil {
+		fs.keys[id] = key
+		return nil, "", err
+	}
+	return copyKey(&rotated), token, nil
+}
+
+// Authenticate returns the key a token belongs to and records its use
+func (fs *FileStore) Authenticate(token string) (*models.APIKey, error) {
+	id, secret, ok := parseToken(token)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	key, ok := fs.keys[id]
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	now := fs.now()
+	switch {
+	case matches(key.Salt, key.Hash, secret):
+	case key.PreviousHash != "" && key.PreviousExpiresAt != nil && now.Before(*key.PreviousExpiresAt) &&
+		matches(key.PreviousSalt, key.PreviousHash, secret):
+	default:
+		return nil, ErrInvalidKey
+	}
+	if key.Revoked() {
+		return nil, ErrKeyRevoked
+	}
+	if key.Expired(now) {
+		return nil, ErrKeyExpired
+	}
+
+	key.LastUsedAt = &now
+	if now.Sub(fs.lastSaved[id]) >= lastUsedResolution {
+		// Last use is informational, so a failed write does not reject the key
+		if err := fs.save(); err == nil {
+			fs.lastSaved[id] = now
+		}
+	}
+	return copyKey(key), nil
+}
+
+// setSecret generates a new secret for key and returns the full token
+func setSecret(key *models.APIKey) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+	key.Salt = hex.EncodeToString(salt)
+	key.Hash = hashSecret(key.Salt, secret)
+	token := tokenPrefix + key.ID + "_" + secret
+	key.Prefix = token[:len(tokenPrefix)+8]
+	return token, nil
+}
+
+// parseToken splits a token into the key ID and its secret
+func parseToken(token string) (id, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(token, tokenPrefix)
+	if !ok {
+		return "", "", false
+	}
+	id, secret, ok = strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+// hashSecret is a salted SHA-256. Secrets are 256 random bits, so a slow
+// password hash would add latency to every call without adding strength.
+func hashSecret(salt, secret string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func matches(salt, hash, secret string) bool {
+	want, err := hex.DecodeString(hash)
+	if err != nil || salt == "" {
+		return false
+	}
+	got, _ := hex.DecodeString(hashSecret(salt, secret))
+	return subtle.ConstantTimeCompare(want, got) == 1
+}
+
+func copyKey(key *models.APIKey) *models.APIKey {
+	k := *key
+	return &k
+}
+
+func (fs *FileStore) load() error {
+	data, err := os.ReadFile(fs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var keys map[string]*models.APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.Scopes == nil {
+			key.Scopes = append([]string(nil), models.DefaultScopes...)
+		}
+	}
+	if keys != nil {
+		fs.keys = keys
+	}
+	return nil
+}
+
+// save must be called with the lock held
+func (fs *FileStore) save() error {
+	data, err := json.MarshalIndent(fs.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.path)
+}
@@ -0,0 +1,149 @@
+package apikeys
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go_runner/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*FileStore, *time.Time) {
+	t.Helper()
+	fs, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	fs.now = func() time.Time { return now }
+	return fs, &now
+}
+
+func TestFileStore_CreateAndAuthenticate(t *testing.T) {
+	fs, now := newTestStore(t)
+
+	key, token, err := fs.Create(&models.APIKey{Name: "ci"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "grk_"+key.ID+"_"))
+	assert.True(t, strings.HasPrefix(token, key.Prefix))
+	assert.NotContains(t, key.Hash, token)
+
+	// Only the salted hash reaches the disk
+	data, err := os.ReadFile(fs.path)
+	require.NoError(t, err)
+	secret := token[strings.LastIndex(token, "_")+1:]
+	assert.NotContains(t, string(data), secret)
+	info, err := os.Stat(fs.path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	got, err := fs.Authenticate(token)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, got.ID)
+	require.NotNil(t, got.LastUsedAt)
+	assert.Equal(t, *now, *got.LastUsedAt)
+
+	for _, bad := range []string{"", "grk_", "grk_" + key.ID + "_wrong", "grk_missing_" + secret, secret} {
+		_, err := fs.Authenticate(bad)
+		assert.ErrorIs(t, err, ErrInvalidKey, bad)
+	}
+
+	assert.Equal(t, models.DefaultScopes, got.Scopes)
+
+	// Keys survive a restart
+	reopened, err := NewFileStore(filepath.Dir(fs.path))
+	require.NoError(t, err)
+	got, err = reopened.Authenticate(token)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", got.Name)
+}
+
+func TestFileStore_ExpiryAndRevocation(t *testing.T) {
+	fs, now := newTestStore(t)
+
+	past := now.Add(-time.Minute)
+	_, _, err := fs.Create(&models.APIKey{Name: "expired", ExpiresAt: &past})
+	assert.Error(t, err)
+
+	expires := now.Add(time.Hour)
+	key, token, err := fs.Create(&models.APIKey{Name: "temp", ExpiresAt: &expires})
+	require.NoError(t, err)
+
+	*now = now.Add(2 * time.Hour)
+	_, err = fs.Authenticate(token)
+	assert.ErrorIs(t, err, ErrKeyExpired)
+
+	key, token, err = fs.Create(&models.APIKey{Name: "revoked"})
+	require.NoError(t, err)
+	revoked, err := fs.Revoke(key.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+	_, err = fs.Authenticate(token)
+	assert.ErrorIs(t, err, ErrKeyRevoked)
+
+	_, _, err = fs.Rotate(key.ID, 0)
+	assert.ErrorIs(t, err, ErrKeyRevoked)
+	_, err = fs.Revoke("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestFileStore_Rotate(t *testing.T) {
+	fs, now := newTestStore(t)
+
+	key, oldToken, err := fs.Create(&models.APIKey{Name: "deploy"})
+	require.NoError(t, err)
+
+	rotated, newToken, err := fs.Rotate(key.ID, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, rotated.ID)
+	assert.NotEqual(t, oldToken, newToken)
+
+	// Both work during the grace period
+	_, err = fs.Authenticate(oldToken)
+	assert.NoError(t, err)
+	_, err = fs.Authenticate(newToken)
+	assert.NoError(t, err)
+
+	*now = now.Add(2 * time.Hour)
+	_, err = fs.Authenticate(oldToken)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = fs.Authenticate(newToken)
+	assert.NoError(t, err)
+
+	// Without a grace period the old token stops working immediately
+	_, newer, err := fs.Rotate(key.ID, 0)
+	require.NoError(t, err)
+	_, err = fs.Authenticate(newToken)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = fs.Authenticate(newer)
+	assert.NoError(t, err)
+}
+
+func TestFileStore_Scopes(t *testing.T) {
+	fs, _ := newTestStore(t)
+
+	_, _, err := fs.Create(&models.APIKey{Name: "bad", Scopes: []string{"admin"}})
+	assert.Error(t, err)
+
+	key, token, err := fs.Create(&models.APIKey{Name: "team-a", Team: "a", Scopes: []string{"execute:tag=nightly"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"execute:tag=nightly"}, key.Scopes)
+
+	got, err := fs.Authenticate(token)
+	require.NoError(t, err)
+	assert.Equal(t, "a", got.Team)
+	assert.True(t, got.CanExecute(&models.Binary{ID: "b1", Labels: []string{"nightly"}}))
+	assert.False(t, got.CanExecute(&models.Binary{ID: "b1"}))
+	assert.False(t, got.HasScope(models.ScopeReadExecutions))
+
+	// Keys stored before scopes existed keep full execution rights
+	require.NoError(t, os.WriteFile(fs.path, []byte(`{"old": {"id": "old", "name": "old"}}`), 0600))
+	reopened, err := NewFileStore(filepath.Dir(fs.path))
+	require.NoError(t, err)
+	old, err := reopened.Get("old")
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultScopes, old.Scopes)
+}
@@ -0,0 +1,80 @@
+// internal/artifacts/store.go
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var ErrInvalidID = errors.New("invalid binary id")
+
+// idPattern keeps ids to a single path element without dots, so that the
+// files of one binary can never be mistaken for those of another
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Store decides where the executables and build outputs of binaries live.
+// Everything a binary owns is removed by Remove. Paths are local because
+// executables are run in place; other backends can mirror them later.
+type Store interface {
+	Init() error
+	BinaryPath(id string) string
+	ArtifactDir(id, buildID string) string
+	UploadPath(id string) string
+	Remove(id string) error
+}
+
+// LocalStore keeps everything in one directory. The active executable of a
+// binary is <root>/<id>; everything else belonging to it is named <id>.<suffix>
+// next to it (provenance, SBOM, staged builds, artifacts and uploads).
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates a store rooted at dir
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{root: dir}
+}
+
+// Init creates the store directory
+func (s *LocalStore) Init() error {
+	return os.MkdirAll(s.root, 0755)
+}
+
+// BinaryPath is where the active executable of a binary is installed
+func (s *LocalStore) BinaryPath(id string) string {
+	return filepath.Join(s.root, id)
+}
+
+// ArtifactDir holds the cross-compiled artifacts of a single build
+func (s *LocalStore) ArtifactDir(id, buildID string) string {
+	return filepath.Join(s.root, id+".artifacts", buildID)
+}
+
+// UploadPath is where the last uploaded source archive of a binary is kept
+func (s *LocalStore) UploadPath(id string) string {
+	return filepath.Join(s.root, id+".upload")
+}
+
+// Remove deletes the executable of a binary and every file stored alongside it
+func (s *LocalStore) Remove(id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrInvalidID
+	}
+
+	paths, err := filepath.Glob(filepath.Join(s.root, id+".*"))
+	if err != nil {
+		return err
+	}
+	paths = append(paths, s.BinaryPath(id))
+
+	var errs []error
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", filepath.Base(path), err))
+		}
+	}
+	return errors.Join(errs...)
+}
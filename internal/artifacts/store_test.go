@@ -0,0 +1,54 @@
+package artifacts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore_Remove(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "binaries")
+	store := NewLocalStore(root)
+	require.NoError(t, store.Init())
+
+	write := func(path string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0644))
+	}
+	write(store.BinaryPath("a"))
+	write(store.BinaryPath("a") + ".intoto.json")
+	write(store.UploadPath("a"))
+	write(filepath.Join(store.ArtifactDir("a", "build-1"), "linux-arm64"))
+	// Files of binaries whose id starts with the same characters stay put
+	write(store.BinaryPath("ab"))
+	write(store.BinaryPath("ab") + ".cdx.json")
+
+	require.NoError(t, store.Remove("a"))
+
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"ab", "ab.cdx.json"}, names)
+
+	// Removing a binary that never had files is not an error
+	assert.NoError(t, store.Remove("missing"))
+}
+
+func TestLocalStore_RemoveRejectsInvalidIDs(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocalStore(filepath.Join(root, "binaries"))
+	require.NoError(t, store.Init())
+	require.NoError(t, os.WriteFile(filepath.Join(root, "keep"), []byte("x"), 0644))
+
+	for _, id := range []string{"", "..", "../keep", "a/b", "*", "a.b"} {
+		assert.ErrorIs(t, store.Remove(id), ErrInvalidID, id)
+	}
+	assert.FileExists(t, filepath.Join(root, "keep"))
+	assert.DirExists(t, filepath.Join(root, "binaries"))
+}
@@ -0,0 +1,197 @@
+// internal/audit/log.go
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go_runner/internal/models"
+)
+
+// maxEventSize bounds a single line of the log
+const maxEventSize = 1 << 20
+
+// FileLog keeps audit events in an append-only JSONL file, one event per
+// line, each hash-chained to the one before it
+type FileLog struct {
+	path string
+	now  func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64 // bytes of complete events, so readers skip a write in progress
+	seq  int64
+	head string // hash of the last event
+}
+
+// Open opens or creates the audit log under dir
+func Open(dir string) (*FileLog, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	l := &FileLog{path: filepath.Join(dir, "audit.jsonl"), now: time.Now}
+
+	// Continue the chain from the last event
+	err := l.scan(-1, func(e *models.AuditEvent) error {
+		l.seq, l.head = e.Seq, e.Hash
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	l.file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := l.file.Stat()
+	if err != nil {
+		l.file.Close()
+		return nil, err
+	}
+	l.size = info.Size()
+	return l, nil
+}
+
+// Append assigns e its sequence number, time and hashes and writes it
+func (l *FileLog) Append(e *models.AuditEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	if e.Time.IsZero() {
+		e.Time = l.now()
+	}
+	e.Time = e.Time.UTC()
+	e.PrevHash = l.head
+	e.Hash = hashEvent(e)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := l.file.Write(line); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.seq, l.head = e.Seq, e.Hash
+	l.size += int64(len(line))
+	return nil
+}
+
+// Query returns the events matching f in the order they were recorded. With
+// a limit, only the most recent matching events are kept.
+func (l *FileLog) Query(f models.AuditFilter) ([]*models.AuditEvent, error) {
+	var events []*models.AuditEvent
+	err := l.scan(l.written(), func(e *models.AuditEvent) error {
+		if !f.Matches(e) {
+			return nil
+		}
+		events = append(events, e)
+		if f.Limit > 0 && len(events) > f.Limit {
+			events = events[1:]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Verify walks the hash chain and reports the first event that does not
+// match its predecessor or its own hash
+func (l *FileLog) Verify() (*models.AuditVerification, error) {
+	size := l.written()
+	result := &models.AuditVerification{Valid: true}
+	err := l.scan(size, func(e *models.AuditEvent) error {
+		switch {
+		case e.Seq != result.Events+1:
+			return brokenChain(result, e, "sequence gap")
+		case e.PrevHash != result.HeadHash:
+			return brokenChain(result, e, "previous hash does not match")
+		case e.Hash != hashEvent(e):
+			return brokenChain(result, e, "hash does not match contents")
+		}
+		result.Events, result.HeadHash = e.Seq, e.Hash
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBroken) {
+		// An undecodable line is as much a break as a wrong hash
+		result.Valid = false
+		result.BrokenAt = result.Events + 1
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+// Close closes the log file
+func (l *FileLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+var errBroken = errors.New("audit chain is broken")
+
+func brokenChain(result *models.AuditVerification, e *models.AuditEvent, reason string) error {
+	result.Valid = false
+	result.BrokenAt = e.Seq
+	result.Error = reason
+	return errBroken
+}
+
+func (l *FileLog) written() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+// scan decodes the first limit bytes of the log, or all of it when limit is
+// negative, calling fn for each event
+func (l *FileLog) scan(limit int64, fn func(*models.AuditEvent) error) error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+	for line := 1; scanner.Scan(); line++ {
+		var e models.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// hashEvent hashes the event without its own hash. The previous hash is
+// part of it, which chains the events.
+func hashEvent(e *models.AuditEvent) string {
+	c := *e
+	c.Hash = ""
+	data, _ := json.Marshal(&c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
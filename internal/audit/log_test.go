@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go_runner/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendEvents(t *testing.T, l *FileLog, actions ...string) {
+	t.Helper()
+	for _, action := range actions {
+		require.NoError(t, l.Append(&models.AuditEvent{Actor: "alice", ActorType: models.ActorUser, Action: action, Target: "b1", Outcome: models.OutcomeSuccess}))
+	}
+}
+
+func TestFileLog_AppendAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir)
+	require.NoError(t, err)
+	appendEvents(t, l, "binary.create", "binary.build")
+	require.NoError(t, l.Close())
+
+	info, err := os.Stat(l.path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// The chain continues across restarts
+	l, err = Open(dir)
+	require.NoError(t, err)
+	defer l.Close()
+	appendEvents(t, l, "binary.delete")
+
+	events, err := l.Query(models.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, int64(3), events[2].Seq)
+	assert.Equal(t, events[1].Hash, events[2].PrevHash)
+	assert.Empty(t, events[0].PrevHash)
+
+	result, err := l.Verify()
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(3), result.Events)
+	assert.Equal(t, events[2].Hash, result.HeadHash)
+}
+
+func TestFileLog_Query(t *testing.T) {
+	l, err := Open(t.TempDir())
+	require.NoError(t, err)
+	defer l.Close()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	appendEvents(t, l, "binary.create", "binary.build")
+	now = now.Add(time.Hour)
+	require.NoError(t, l.Append(&models.AuditEvent{Actor: "ci", ActorType: models.ActorAPIKey, Action: "execution.start", Target: "b1", Outcome: models.OutcomeDenied}))
+	appendEvents(t, l, "binary.build")
+
+	count := func(f models.AuditFilter) int {
+		events, err := l.Query(f)
+		require.NoError(t, err)
+		return len(events)
+	}
+	assert.Equal(t, 3, count(models.AuditFilter{Actor: "alice"}))
+	assert.Equal(t, 2, count(models.AuditFilter{Action: "binary.build"}))
+	assert.Equal(t, 3, count(models.AuditFilter{Action: "binary."}))
+	assert.Equal(t, 0, count(models.AuditFilter{Action: "binary"}))
+	assert.Equal(t, 1, count(models.AuditFilter{Outcome: models.OutcomeDenied}))
+	assert.Equal(t, 2, count(models.AuditFilter{Since: now}))
+	assert.Equal(t, 2, count(models.AuditFilter{Until: now}))
+
+	// A limit keeps the most recent events
+	events, err := l.Query(models.AuditFilter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(3), events[0].Seq)
+	assert.Equal(t, int64(4), events[1].Seq)
+}
+
+func TestFileLog_VerifyDetectsTampering(t *testing.T) {
+	tamper := func(t *testing.T, edit func(lines []string) []string) *models.AuditVerification {
+		dir := t.TempDir()
+		l, err := Open(dir)
+		require.NoError(t, err)
+		appendEvents(t, l, "binary.create", "binary.build", "binary.delete")
+		require.NoError(t, l.Close())
+
+		data, err := os.ReadFile(l.path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		require.NoError(t, os.WriteFile(l.path, []byte(strings.Join(edit(lines), "\n")+"\n"), 0600))
+
+		l, err = Open(dir)
+		require.NoError(t, err)
+		defer l.Close()
+		result, err := l.Verify()
+		require.NoError(t, err)
+		return result
+	}
+
+	// Rewriting who did something
+	result := tamper(t, func(lines []string) []string {
+		lines[1] = strings.Replace(lines[1], `"actor":"alice"`, `"actor":"mallory"`, 1)
+		return lines
+	})
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(2), result.BrokenAt)
+
+	// Removing an event
+	result = tamper(t, func(lines []string) []string {
+		return append(lines[:1], lines[2:]...)
+	})
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(3), result.BrokenAt)
+}
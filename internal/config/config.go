@@ -3,17 +3,33 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
+
+	"go_runner/internal/models"
 )
 
 // Config holds all configuration for our application
 type Config struct {
-	Server   ServerConfig
-	Storage  StorageConfig
-	Executor ExecutorConfig
-	Auth     AuthConfig
+	Server    ServerConfig
+	Storage   StorageConfig
+	Upload    UploadConfig
+	Git       GitConfig
+	Webhook   WebhookConfig
+	Watcher   WatcherConfig
+	Toolchain ToolchainConfig
+	Cache     CacheConfig
+	Executor  ExecutorConfig
+	Signing   SigningConfig
+	Vuln      VulnConfig
+	Auth      AuthConfig
+	OIDC      OIDCConfig
+	RateLimit RateLimitConfig
 }
 
 type ServerConfig struct {
@@ -21,12 +37,91 @@ type ServerConfig struct {
 	Host         string        `json:"host"`
 	ReadTimeout  time.Duration `json:"read_timeout"`
 	WriteTimeout time.Duration `json:"write_timeout"`
+	TLS          TLSConfig     `json:"tls"`
+	CORS         CORSConfig    `json:"cors"`
+	// TrustedProxies lists the IPs and CIDRs of reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers name the client
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+// CORSConfig holds the cross-origin policies of the admin routes, which
+// accept session cookies, and of the execution API
+type CORSConfig struct {
+	Admin CORSPolicy `json:"admin"`
+	API   CORSPolicy `json:"api"`
+}
+
+// CORSPolicy lists what other origins may request. Without origins only
+// same-origin requests are allowed.
+type CORSPolicy struct {
+	AllowedOrigins []string      `json:"allowed_origins"` // e.g. https://ops.example.com, https://*.example.com or *
+	AllowedMethods []string      `json:"allowed_methods"`
+	AllowedHeaders []string      `json:"allowed_headers"`
+	MaxAge         time.Duration `json:"max_age"` // how long browsers may cache a preflight
+}
+
+type TLSConfig struct {
+	CertFile       string            `json:"cert_file"` // HTTPS is served when set, with KeyFile
+	KeyFile        string            `json:"key_file"`
+	ClientCAFile   string            `json:"client_ca_file"`  // CAs of client certificates, unset to not ask for them
+	ClientAuth     string            `json:"client_auth"`     // optional or require
+	ClientRoleMap  map[string]string `json:"client_role_map"` // certificate identity, e.g. CN:ci-bot, to role
+	ReloadInterval time.Duration     `json:"reload_interval"` // how often the files are checked for changes
+}
+
+// Enabled reports whether HTTPS is configured
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
 }
 
 type StorageConfig struct {
-	Path       string `json:"path"`
-	RepoPath   string `json:"repo_path"`
-	BinaryPath string `json:"binary_path"`
+	Path            string `json:"path"`
+	RepoPath        string `json:"repo_path"`
+	BinaryPath      string `json:"binary_path"`
+	CredentialsPath string `json:"credentials_path"`
+	CredentialsKey  string `json:"-"`
+	APIKeysPath     string `json:"api_keys_path"`
+	UsersPath       string `json:"users_path"`
+	SessionsPath    string `json:"sessions_path"`
+	AuditPath       string `json:"audit_path"`
+	QuotasPath      string `json:"quotas_path"`
+}
+
+type UploadConfig struct {
+	MaxSizeMB int `json:"max_size_mb"` // Largest source archive or executable accepted
+}
+
+type GitConfig struct {
+	Backend string        `json:"backend"` // cli or go-git
+	Timeout time.Duration `json:"timeout"` // Per clone/update or remote lookup
+	Schemes []string      `json:"schemes"` // Repository URL schemes binaries may use; ssh includes git@host:path
+}
+
+type WebhookConfig struct {
+	Secret string `json:"-"` // HMAC key (GitLab token) shared with the git provider
+}
+
+type WatcherConfig struct {
+	DefaultInterval time.Duration `json:"default_interval"` // Poll interval when a binary does not set one
+	MinInterval     time.Duration `json:"min_interval"`
+	MaxBackoff      time.Duration `json:"max_backoff"` // Upper bound of the poll delay after failures
+	MaxConcurrent   int           `json:"max_concurrent"`
+	Timeout         time.Duration `json:"timeout"` // Per remote lookup
+}
+
+type ToolchainConfig struct {
+	Path        string `json:"path"`
+	OfflinePath string `json:"offline_path"`
+	DownloadURL string `json:"download_url"`
+}
+
+type CacheConfig struct {
+	ModPath        string `json:"mod_path"`
+	BuildPath      string `json:"build_path"`
+	GOPROXY        string `json:"goproxy"`
+	GOSUMDB        string `json:"gosumdb"`
+	MaxModSizeMB   int    `json:"max_mod_size_mb"`
+	MaxBuildSizeMB int    `json:"max_build_size_mb"`
 }
 
 type ExecutorConfig struct {
@@ -35,9 +130,47 @@ type ExecutorConfig struct {
 	MaxMemoryMB   int           `json:"max_memory_mb"`
 }
 
+type SigningConfig struct {
+	KeyPath string `json:"key_path"` // ed25519 key for provenance signatures, created if missing
+}
+
+type VulnConfig struct {
+	DBPath string `json:"db_path"` // Unpacked copy of the Go vulnerability database, unset to disable
+	Block  bool   `json:"block"`   // Refuse to promote builds that link vulnerable modules
+}
+
 type AuthConfig struct {
-	AdminToken string `json:"admin_token"`
-	APIKeys    bool   `json:"api_keys_enabled"`
+	AdminToken    string        `json:"admin_token"`
+	QueryToken    bool          `json:"query_token"` // Also accept the admin token as ?token=
+	APIKeys       bool          `json:"api_keys_enabled"`
+	SessionIdle   time.Duration `json:"session_idle"`    // Sign-ins end after this long without requests
+	SessionMaxAge time.Duration `json:"session_max_age"` // and this long after signing in
+}
+
+type OIDCConfig struct {
+	Issuer        string            `json:"issuer"` // unset to disable single sign-on
+	ClientID      string            `json:"client_id"`
+	ClientSecret  string            `json:"-"`
+	RedirectURL   string            `json:"redirect_url"` // https://<host>/login/oidc/callback
+	Scopes        []string          `json:"scopes"`
+	Audience      string            `json:"audience"` // of bearer tokens, the client ID by default
+	UsernameClaim string            `json:"username_claim"`
+	GroupsClaim   string            `json:"groups_claim"` // dotted path, e.g. realm_access.roles
+	RoleMap       map[string]string `json:"role_map"`     // group to role
+	DefaultRole   string            `json:"default_role"` // for users in no mapped group, unset to refuse them
+	JWKSCacheTTL  time.Duration     `json:"jwks_cache_ttl"`
+}
+
+// RateLimitConfig limits how often executions may be started and, per API
+// key, how much may be executed. Zero disables a limit.
+type RateLimitConfig struct {
+	KeyPerMinute    float64      `json:"key_per_minute"` // executions per API key
+	KeyBurst        int          `json:"key_burst"`
+	IPPerMinute     float64      `json:"ip_per_minute"` // executions per client IP
+	IPBurst         int          `json:"ip_burst"`
+	BinaryPerMinute float64      `json:"binary_per_minute"` // executions per binary, whoever starts them
+	BinaryBurst     int          `json:"binary_burst"`
+	Quota           models.Quota `json:"quota"` // default for keys without their own
 }
 
 // LoadConfig loads configuration from environment variables
@@ -57,26 +190,183 @@ func LoadConfig() (*Config, error) {
 	config.Server.ReadTimeout = getDurationOrDefault("SERVER_READ_TIMEOUT", 10*time.Second)
 	config.Server.WriteTimeout = getDurationOrDefault("SERVER_WRITE_TIMEOUT", 10*time.Second)
 
+	config.Server.TrustedProxies = getListOrDefault("TRUSTED_PROXIES", "")
+	for _, proxy := range config.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP or CIDR", proxy)
+		}
+	}
+
+	// TLS configuration
+	config.Server.TLS.CertFile = os.Getenv("TLS_CERT_FILE")
+	config.Server.TLS.KeyFile = os.Getenv("TLS_KEY_FILE")
+	config.Server.TLS.ClientCAFile = os.Getenv("TLS_CLIENT_CA_FILE")
+	config.Server.TLS.ClientAuth = getEnvOrDefault("TLS_CLIENT_AUTH", "optional")
+	config.Server.TLS.ReloadInterval = getDurationOrDefault("TLS_RELOAD_INTERVAL", 10*time.Second)
+	clientRoles, err := parseRoleMap("TLS_CLIENT_ROLE_MAP", os.Getenv("TLS_CLIENT_ROLE_MAP"))
+	if err != nil {
+		return nil, err
+	}
+	config.Server.TLS.ClientRoleMap = clientRoles
+	if (config.Server.TLS.CertFile == "") != (config.Server.TLS.KeyFile == "") {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if config.Server.TLS.ClientCAFile != "" && !config.Server.TLS.Enabled() {
+		return nil, errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if config.Server.TLS.ClientAuth != "optional" && config.Server.TLS.ClientAuth != "require" {
+		return nil, errors.New("TLS_CLIENT_AUTH must be optional or require")
+	}
+	if config.Server.TLS.ClientAuth == "require" && config.Server.TLS.ClientCAFile == "" {
+		return nil, errors.New("TLS_CLIENT_AUTH=require needs TLS_CLIENT_CA_FILE")
+	}
+
+	// CORS configuration
+	config.Server.CORS.Admin = CORSPolicy{
+		AllowedOrigins: getListOrDefault("CORS_ADMIN_ORIGINS", ""),
+		AllowedMethods: getListOrDefault("CORS_ADMIN_METHODS", "GET,POST,PUT,DELETE"),
+		AllowedHeaders: getListOrDefault("CORS_ADMIN_HEADERS", "Accept,Authorization,Content-Type,X-CSRF-Token"),
+		MaxAge:         getDurationOrDefault("CORS_MAX_AGE", 5*time.Minute),
+	}
+	config.Server.CORS.API = CORSPolicy{
+		AllowedOrigins: getListOrDefault("CORS_API_ORIGINS", "*"),
+		AllowedMethods: getListOrDefault("CORS_API_METHODS", "GET,POST,DELETE"),
+		AllowedHeaders: getListOrDefault("CORS_API_HEADERS", "Accept,Authorization,Content-Type,X-API-Key"),
+		MaxAge:         config.Server.CORS.Admin.MaxAge,
+	}
+	for _, origin := range config.Server.CORS.Admin.AllowedOrigins {
+		// Admin routes accept cookies, which must never be sent for any site
+		if origin == "*" {
+			return nil, errors.New("CORS_ADMIN_ORIGINS must list origins, not *")
+		}
+	}
+
 	// Storage configuration
 	config.Storage.Path = getEnvOrDefault("STORAGE_PATH", "./data")
 	config.Storage.RepoPath = getEnvOrDefault("REPO_PATH", "./data/repos")
 	config.Storage.BinaryPath = getEnvOrDefault("BINARY_PATH", "./data/binaries")
+	config.Storage.CredentialsPath = getEnvOrDefault("CREDENTIALS_PATH", filepath.Join(config.Storage.Path, "credentials"))
+	config.Storage.CredentialsKey = os.Getenv("CREDENTIALS_KEY")
+	config.Storage.APIKeysPath = getEnvOrDefault("API_KEYS_PATH", filepath.Join(config.Storage.Path, "apikeys"))
+	config.Storage.UsersPath = getEnvOrDefault("USERS_PATH", filepath.Join(config.Storage.Path, "users"))
+	config.Storage.SessionsPath = getEnvOrDefault("SESSIONS_PATH", filepath.Join(config.Storage.Path, "sessions"))
+	config.Storage.AuditPath = getEnvOrDefault("AUDIT_PATH", filepath.Join(config.Storage.Path, "audit"))
+	config.Storage.QuotasPath = getEnvOrDefault("QUOTAS_PATH", filepath.Join(config.Storage.Path, "quotas"))
+
+	// Upload configuration
+	config.Upload.MaxSizeMB = getIntOrDefault("UPLOAD_MAX_SIZE_MB", 256)
+
+	// Git configuration
+	config.Git.Backend = getEnvOrDefault("GIT_BACKEND", "cli")
+	if config.Git.Backend != "cli" && config.Git.Backend != "go-git" {
+		return nil, errors.New("GIT_BACKEND must be cli or go-git")
+	}
+	config.Git.Timeout = getDurationOrDefault("GIT_TIMEOUT", 10*time.Minute)
+	config.Git.Schemes = getListOrDefault("GIT_ALLOWED_SCHEMES", "https,ssh")
+
+	// Webhook configuration
+	config.Webhook.Secret = os.Getenv("WEBHOOK_SECRET")
+
+	// Auto-build watcher configuration
+	config.Watcher.DefaultInterval = getDurationOrDefault("WATCH_DEFAULT_INTERVAL", 5*time.Minute)
+	config.Watcher.MinInterval = getDurationOrDefault("WATCH_MIN_INTERVAL", 30*time.Second)
+	config.Watcher.MaxBackoff = getDurationOrDefault("WATCH_MAX_BACKOFF", time.Hour)
+	config.Watcher.MaxConcurrent = getIntOrDefault("WATCH_MAX_CONCURRENT", 4)
+	config.Watcher.Timeout = getDurationOrDefault("WATCH_TIMEOUT", 30*time.Second)
+
+	// Toolchain configuration
+	config.Toolchain.Path = getEnvOrDefault("TOOLCHAIN_PATH", filepath.Join(config.Storage.Path, "toolchains"))
+	config.Toolchain.OfflinePath = os.Getenv("TOOLCHAIN_OFFLINE_PATH")
+	config.Toolchain.DownloadURL = getEnvOrDefault("TOOLCHAIN_DOWNLOAD_URL", "https://dl.google.com/go")
+
+	// Go module and build cache configuration
+	cachePath := getEnvOrDefault("CACHE_PATH", filepath.Join(config.Storage.Path, "cache"))
+	config.Cache.ModPath = filepath.Join(cachePath, "mod")
+	config.Cache.BuildPath = filepath.Join(cachePath, "build")
+	config.Cache.GOPROXY = os.Getenv("BUILD_GOPROXY")
+	config.Cache.GOSUMDB = os.Getenv("BUILD_GOSUMDB")
+	config.Cache.MaxModSizeMB = getIntOrDefault("CACHE_MAX_MOD_SIZE_MB", 0)
+	config.Cache.MaxBuildSizeMB = getIntOrDefault("CACHE_MAX_BUILD_SIZE_MB", 2048)
 
 	// Executor configuration
 	config.Executor.MaxConcurrent = getIntOrDefault("EXECUTOR_MAX_CONCURRENT", 10)
 	config.Executor.Timeout = getDurationOrDefault("EXECUTOR_TIMEOUT", 5*time.Minute)
 	config.Executor.MaxMemoryMB = getIntOrDefault("EXECUTOR_MAX_MEMORY_MB", 512)
 
+	// Provenance signing configuration
+	config.Signing.KeyPath = os.Getenv("SIGNING_KEY_PATH")
+
+	// Vulnerability scanning configuration
+	config.Vuln.DBPath = os.Getenv("VULNDB_PATH")
+	config.Vuln.Block = getBoolOrDefault("VULN_BLOCK", false)
+
 	// Auth configuration
 	config.Auth.AdminToken = os.Getenv("ADMIN_TOKEN")
 	if config.Auth.AdminToken == "" {
 		return nil, errors.New("ADMIN_TOKEN is required")
 	}
+	config.Auth.QueryToken = getBoolOrDefault("ADMIN_TOKEN_QUERY", false)
 	config.Auth.APIKeys = getBoolOrDefault("API_KEYS_ENABLED", true)
+	config.Auth.SessionIdle = getDurationOrDefault("SESSION_IDLE_TIMEOUT", time.Hour)
+	config.Auth.SessionMaxAge = getDurationOrDefault("SESSION_MAX_AGE", 24*time.Hour)
+
+	// Rate limit and quota configuration
+	config.RateLimit.KeyPerMinute = getFloatOrDefault("RATE_LIMIT_KEY_PER_MINUTE", 60)
+	config.RateLimit.KeyBurst = getIntOrDefault("RATE_LIMIT_KEY_BURST", 10)
+	config.RateLimit.IPPerMinute = getFloatOrDefault("RATE_LIMIT_IP_PER_MINUTE", 60)
+	config.RateLimit.IPBurst = getIntOrDefault("RATE_LIMIT_IP_BURST", 20)
+	config.RateLimit.BinaryPerMinute = getFloatOrDefault("RATE_LIMIT_BINARY_PER_MINUTE", 0)
+	config.RateLimit.BinaryBurst = getIntOrDefault("RATE_LIMIT_BINARY_BURST", 5)
+	config.RateLimit.Quota = models.Quota{
+		DailyExecutions:   int64(getIntOrDefault("QUOTA_DAILY_EXECUTIONS", 0)),
+		MonthlyExecutions: int64(getIntOrDefault("QUOTA_MONTHLY_EXECUTIONS", 0)),
+		DailyCPUSeconds:   int64(getIntOrDefault("QUOTA_DAILY_CPU_SECONDS", 0)),
+		MonthlyCPUSeconds: int64(getIntOrDefault("QUOTA_MONTHLY_CPU_SECONDS", 0)),
+	}
+	if err := config.RateLimit.Quota.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Single sign-on configuration
+	config.OIDC.Issuer = os.Getenv("OIDC_ISSUER")
+	config.OIDC.ClientID = os.Getenv("OIDC_CLIENT_ID")
+	config.OIDC.ClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	config.OIDC.RedirectURL = os.Getenv("OIDC_REDIRECT_URL")
+	config.OIDC.Scopes = strings.Fields(strings.ReplaceAll(getEnvOrDefault("OIDC_SCOPES", "openid profile email"), ",", " "))
+	config.OIDC.Audience = getEnvOrDefault("OIDC_AUDIENCE", config.OIDC.ClientID)
+	config.OIDC.UsernameClaim = getEnvOrDefault("OIDC_USERNAME_CLAIM", "preferred_username")
+	config.OIDC.GroupsClaim = getEnvOrDefault("OIDC_GROUPS_CLAIM", "groups")
+	config.OIDC.DefaultRole = os.Getenv("OIDC_DEFAULT_ROLE")
+	config.OIDC.JWKSCacheTTL = getDurationOrDefault("OIDC_JWKS_CACHE_TTL", time.Hour)
+	roleMap, err := parseRoleMap("OIDC_ROLE_MAP", os.Getenv("OIDC_ROLE_MAP"))
+	if err != nil {
+		return nil, err
+	}
+	config.OIDC.RoleMap = roleMap
+	if config.OIDC.Issuer != "" && (config.OIDC.ClientID == "" || config.OIDC.RedirectURL == "") {
+		return nil, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
 
 	return config, nil
 }
 
+// parseRoleMap parses name=role pairs separated by commas. Names may
+// contain '=' themselves, as in certificate URIs.
+func parseRoleMap(key, value string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 || strings.TrimSpace(pair[:i]) == "" {
+			return nil, fmt.Errorf("%s entry %q must be name=role", key, pair)
+		}
+		roles[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+	return roles, nil
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -93,6 +383,31 @@ func getIntOrDefault(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getListOrDefault splits a comma-separated variable, dropping empty
+// entries. Unlike the other getters, a variable set to "" is an empty list.
+func getListOrDefault(key, defaultValue string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getFloatOrDefault(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
 func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if d, err := time.ParseDuration(value); err == nil {
@@ -0,0 +1,243 @@
+// internal/credentials/store.go
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go_runner/internal/models"
+)
+
+var (
+	ErrCredentialNotFound = errors.New("credential not found")
+	ErrInvalidCredential  = errors.New("invalid credential")
+)
+
+// encryptedPrefix marks secrets sealed with the store key
+const encryptedPrefix = "enc:v1:"
+
+// Store interface for repository credentials
+type Store interface {
+	Save(cred *models.Credential) error
+	Get(id string) (*models.Credential, error)
+	List() ([]*models.Credential, error)
+	Delete(id string) error
+}
+
+// FileStore keeps credentials in a single 0600 JSON file. When a key is
+// configured, secrets are sealed with AES-256-GCM before they touch the disk.
+type FileStore struct {
+	path        string
+	aead        cipher.AEAD
+	mu          sync.RWMutex
+	credentials map[string]*models.Credential
+}
+
+// NewFileStore creates a credential store under dir. key may be empty.
+func NewFileStore(dir, key string) (*FileStore, error) {
+	fs := &FileStore{
+		path:        filepath.Join(dir, "credentials.json"),
+		credentials: make(map[string]*models.Credential),
+	}
+
+	if key != "" {
+		// Any passphrase is stretched to an AES-256 key
+		sum := sha256.Sum256([]byte(key))
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			return nil, err
+		}
+		if fs.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// Validate checks that a credential has the fields its type requires
+func Validate(cred *models.Credential) error {
+	if strings.TrimSpace(cred.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCredential)
+	}
+	switch cred.Type {
+	case models.CredentialToken:
+		if cred.Secret == "" {
+			return fmt.Errorf("%w: token credentials require a secret", ErrInvalidCredential)
+		}
+	case models.CredentialBasic:
+		if cred.Username == "" || cred.Secret == "" {
+			return fmt.Errorf("%w: basic credentials require a username and secret", ErrInvalidCredential)
+		}
+	case models.CredentialSSH:
+		if !strings.Contains(cred.Secret, "PRIVATE KEY") {
+			return fmt.Errorf("%w: ssh credentials require a PEM private key", ErrInvalidCredential)
+		}
+		if strings.TrimSpace(cred.KnownHosts) == "" {
+			return fmt.Errorf("%w: ssh credentials require known_hosts to pin the host key", ErrInvalidCredential)
+		}
+	default:
+		return fmt.Errorf("%w: type must be one of token, basic, ssh", ErrInvalidCredential)
+	}
+	return nil
+}
+
+// Save creates or replaces a credential, refusing ones Validate rejects
+func (fs *FileStore) Save(cred *models.Credential) error {
+	if err := Validate(cred); err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := fs.credentials[cred.ID]; ok {
+		cred.CreatedAt = existing.CreatedAt
+	} else {
+		cred.CreatedAt = now
+	}
+	cred.UpdatedAt = now
+
+	stored := *cred
+	fs.credentials[cred.ID] = &stored
+	return fs.save()
+}
+
+// Get retrieves a credential including its secret
+func (fs *FileStore) Get(id string) (*models.Credential, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	cred, ok := fs.credentials[id]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	c := *cred
+	return &c, nil
+}
+
+// List returns all credentials ordered by name
+func (fs *FileStore) List() ([]*models.Credential, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	creds := make([]*models.Credential, 0, len(fs.credentials))
+	for _, cred := range fs.credentials {
+		c := *cred
+		creds = append(creds, &c)
+	}
+	sort.Slice(creds, func(i, j int) bool { return creds[i].Name < creds[j].Name })
+	return creds, nil
+}
+
+// Delete removes a credential
+func (fs *FileStore) Delete(id string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.credentials[id]; !ok {
+		return ErrCredentialNotFound
+	}
+	delete(fs.credentials, id)
+	return fs.save()
+}
+
+func (fs *FileStore) load() error {
+	data, err := os.ReadFile(fs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var creds map[string]*models.Credential
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return err
+	}
+	for id, cred := range creds {
+		if cred.Secret, err = fs.open(cred.Secret); err != nil {
+			return fmt.Errorf("failed to decrypt credential %s: %w", id, err)
+		}
+	}
+	fs.credentials = creds
+	return nil
+}
+
+// save must be called with the lock held
+func (fs *FileStore) save() error {
+	sealed := make(map[string]*models.Credential, len(fs.credentials))
+	for id, cred := range fs.credentials {
+		c := *cred
+		secret, err := fs.seal(c.Secret)
+		if err != nil {
+			return err
+		}
+		c.Secret = secret
+		sealed[id] = &c
+	}
+
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.path)
+}
+
+func (fs *FileStore) seal(secret string) (string, error) {
+	if fs.aead == nil || secret == "" {
+		return secret, nil
+	}
+	nonce := make([]byte, fs.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := fs.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (fs *FileStore) open(secret string) (string, error) {
+	if !strings.HasPrefix(secret, encryptedPrefix) {
+		return secret, nil
+	}
+	if fs.aead == nil {
+		return "", errors.New("secret is encrypted but no CREDENTIALS_KEY is configured")
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(data) < fs.aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plain, err := fs.aead.Open(nil, data[:fs.aead.NonceSize()], data[fs.aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -4,36 +4,58 @@ package executor
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"runtime"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"go_runner/internal/artifacts"
 	"go_runner/internal/config"
 	"go_runner/internal/models"
 )
 
+var (
+	ErrNoDigest       = errors.New("binary has no recorded digest; rebuild it")
+	ErrDigestMismatch = errors.New("binary does not match its recorded digest")
+)
+
 // Executor handles binary execution
 type Executor struct {
-	binaryPath  string
+	store       artifacts.Store
 	config      config.ExecutorConfig
 	runningJobs map[string]*exec.Cmd
 	mu          sync.RWMutex
 }
 
-// NewExecutor creates a new executor
-func NewExecutor(binaryPath string, config config.ExecutorConfig) *Executor {
+// NewExecutor creates a new executor that runs binaries installed in store
+func NewExecutor(store artifacts.Store, config config.ExecutorConfig) *Executor {
 	return &Executor{
-		binaryPath:  binaryPath,
+		store:       store,
 		config:      config,
 		runningJobs: make(map[string]*exec.Cmd),
 	}
 }
 
-// Execute runs a binary with the given parameters
-func (e *Executor) Execute(ctx context.Context, binaryPath string, req *models.ExecutionRequest, started chan<- string) (*models.ExecutionResult, error) {
+// Execute runs the active executable of req.BinaryID with the given parameters
+// after checking that its SHA-256 matches digest, the hash recorded when it was built
+func (e *Executor) Execute(ctx context.Context, digest string, req *models.ExecutionRequest, started chan<- string) (*models.ExecutionResult, error) {
+	binaryPath := e.store.BinaryPath(req.BinaryID)
+
+	// Run the verified file itself, so it cannot be swapped after the check
+	f, err := openVerified(binaryPath, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
 	// Create execution result
 	result := &models.ExecutionResult{
 		ID:        generateID(),
@@ -52,7 +74,8 @@ func (e *Executor) Execute(ctx context.Context, binaryPath string, req *models.E
 	defer cancel()
 
 	// Create command
-	cmd := exec.CommandContext(execCtx, binaryPath, req.Args...)
+	cmd := exec.CommandContext(execCtx, execPath(f, binaryPath), req.Args...)
+	cmd.Args[0] = binaryPath
 
 	// Set environment variables
 	if len(req.Env) > 0 {
@@ -86,10 +109,13 @@ func (e *Executor) Execute(ctx context.Context, binaryPath string, req *models.E
 	}()
 
 	// Execute command
-	err := cmd.Run()
+	err = cmd.Run()
 
 	result.FinishedAt = time.Now()
 	result.Duration = result.FinishedAt.Sub(result.StartedAt).Milliseconds()
+	if cmd.ProcessState != nil {
+		result.CPUTime = (cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()).Milliseconds()
+	}
 	result.Stdout = stdout.String()
 	result.Stderr = stderr.String()
 
@@ -134,6 +160,42 @@ func (e *Executor) StopExecution(executionID string) error {
 	return nil
 }
 
+// openVerified opens the binary and checks its SHA-256 against digest
+func openVerified(binaryPath, digest string) (*os.File, error) {
+	if digest == "" {
+		return nil, ErrNoDigest
+	}
+
+	f, err := os.Open(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open binary: %w", err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to hash binary: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != digest {
+		f.Close()
+		return nil, fmt.Errorf("%w: expected sha256 %s, got %s", ErrDigestMismatch, digest, got)
+	}
+	return f, nil
+}
+
+// execPath returns a path that executes the already opened file. On Linux the
+// kernel resolves /proc/self/fd in the child before close-on-exec descriptors
+// are closed; elsewhere the original path is used.
+func execPath(f *os.File, binaryPath string) string {
+	if runtime.GOOS == "linux" {
+		fdPath := fmt.Sprintf("/proc/self/fd/%d", f.Fd())
+		if _, err := os.Stat(fdPath); err == nil {
+			return fdPath
+		}
+	}
+	return binaryPath
+}
+
 func generateID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
 }
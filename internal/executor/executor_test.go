@@ -3,19 +3,25 @@ package executor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/artifacts"
 	"go_runner/internal/config"
 	"go_runner/internal/models"
 )
 
-var testBinPath string
+var (
+	testStore                 *artifacts.LocalStore
+	testBinPath, testBinDigest string
+)
 
 func TestMain(m *testing.M) {
 	// Create a dummy binary for testing
@@ -26,7 +32,8 @@ func TestMain(m *testing.M) {
 	}
 	defer os.RemoveAll(binDir)
 
-	testBinPath = filepath.Join(binDir, "test_binary")
+	testStore = artifacts.NewLocalStore(binDir)
+	testBinPath = testStore.BinaryPath("test-binary")
 	cmd := exec.Command("go", "build", "-o", testBinPath, "./testdata/main.go")
 	err = cmd.Run()
 	if err != nil {
@@ -34,6 +41,14 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
+	content, err := os.ReadFile(testBinPath)
+	if err != nil {
+		fmt.Println("Error reading test binary:", err)
+		os.Exit(1)
+	}
+	sum := sha256.Sum256(content)
+	testBinDigest = hex.EncodeToString(sum[:])
+
 	// Run tests
 	exitCode := m.Run()
 
@@ -42,13 +57,13 @@ func TestMain(m *testing.M) {
 
 func TestExecutor_Execute_Success(t *testing.T) {
 	t.Parallel()
-	executor := NewExecutor(testBinPath, config.ExecutorConfig{Timeout: 5 * time.Second})
+	executor := NewExecutor(testStore, config.ExecutorConfig{Timeout: 5 * time.Second})
 	req := &models.ExecutionRequest{
 		BinaryID: "test-binary",
 		Args:     []string{"hello"},
 	}
 
-	result, err := executor.Execute(context.Background(), testBinPath, req, nil)
+	result, err := executor.Execute(context.Background(), testBinDigest, req, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, "completed", result.Status)
 	assert.Equal(t, 0, result.ExitCode)
@@ -57,14 +72,14 @@ func TestExecutor_Execute_Success(t *testing.T) {
 
 func TestExecutor_Execute_Timeout(t *testing.T) {
 	t.Parallel()
-	executor := NewExecutor(testBinPath, config.ExecutorConfig{Timeout: 1 * time.Second})
+	executor := NewExecutor(testStore, config.ExecutorConfig{Timeout: 1 * time.Second})
 	req := &models.ExecutionRequest{
 		BinaryID: "test-binary",
 		Args:     []string{"sleep"},
 		Timeout:  1,
 	}
 
-	result, err := executor.Execute(context.Background(), testBinPath, req, nil)
+	result, err := executor.Execute(context.Background(), testBinDigest, req, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, "timeout", result.Status)
 	assert.Equal(t, -1, result.ExitCode)
@@ -72,7 +87,7 @@ func TestExecutor_Execute_Timeout(t *testing.T) {
 
 func TestExecutor_StopExecution(t *testing.T) {
 	t.Parallel()
-	executor := NewExecutor(testBinPath, config.ExecutorConfig{Timeout: 5 * time.Second})
+	executor := NewExecutor(testStore, config.ExecutorConfig{Timeout: 5 * time.Second})
 	req := &models.ExecutionRequest{
 		BinaryID: "test-binary",
 		Args:     []string{"sleep"},
@@ -84,7 +99,7 @@ func TestExecutor_StopExecution(t *testing.T) {
 	started := make(chan string)
 
 	go func() {
-		result, err = executor.Execute(context.Background(), testBinPath, req, started)
+		result, err = executor.Execute(context.Background(), testBinDigest, req, started)
 		close(done)
 	}()
 
@@ -105,3 +120,34 @@ func TestExecutor_StopExecution(t *testing.T) {
 
 	assert.Equal(t, "failed", result.Status)
 }
+
+func TestExecutor_Execute_RefusesTamperedBinary(t *testing.T) {
+	t.Parallel()
+	executor := NewExecutor(testStore, config.ExecutorConfig{Timeout: 5 * time.Second})
+	req := &models.ExecutionRequest{BinaryID: "test-binary", Args: []string{"hello"}}
+
+	// A copy of the binary with a byte appended no longer matches the digest
+	tampered := testStore.BinaryPath("tampered")
+	content, err := os.ReadFile(testBinPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(tampered, append(content, 0), 0755))
+	t.Cleanup(func() { os.Remove(tampered) })
+
+	tamperedReq := &models.ExecutionRequest{BinaryID: "tampered", Args: []string{"hello"}}
+	result, err := executor.Execute(context.Background(), testBinDigest, tamperedReq, nil)
+	assert.ErrorIs(t, err, ErrDigestMismatch)
+	assert.Nil(t, result)
+
+	_, err = executor.Execute(context.Background(), "", req, nil)
+	assert.ErrorIs(t, err, ErrNoDigest)
+}
+
+func TestExecutor_Execute_KeepsArgv0(t *testing.T) {
+	t.Parallel()
+	executor := NewExecutor(testStore, config.ExecutorConfig{Timeout: 5 * time.Second})
+	req := &models.ExecutionRequest{BinaryID: "test-binary", Args: []string{"argv0"}}
+
+	result, err := executor.Execute(context.Background(), testBinDigest, req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testBinPath, result.Stdout)
+}
@@ -11,6 +11,8 @@ func main() {
 	if len(os.Args) > 1 {
 		if os.Args[1] == "sleep" {
 			time.Sleep(10 * time.Second)
+		} else if os.Args[1] == "argv0" {
+			fmt.Print(os.Args[0])
 		} else {
 			fmt.Print(os.Args[1])
 		}
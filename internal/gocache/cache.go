@@ -0,0 +1,353 @@
+// internal/gocache/cache.go
+package gocache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+)
+
+// Purge targets
+const (
+	TargetModule = "mod"
+	TargetBuild  = "build"
+	TargetAll    = "all"
+)
+
+var ErrInvalidTarget = errors.New("invalid cache target")
+
+// trimRatio is the fraction of the limit a cache is trimmed down to, so that
+// a cache sitting at its limit is not trimmed again after every build
+const trimRatio = 0.9
+
+// Cache manages the GOMODCACHE and GOCACHE shared by all builds
+type Cache struct {
+	modDir        string
+	buildDir      string
+	proxy         string
+	sumDB         string
+	maxModBytes   int64
+	maxBuildBytes int64
+
+	// Builds hold a read lock; trimming and purging take the write lock
+	mu       sync.RWMutex
+	trimming atomic.Bool
+}
+
+// NewCache creates a new cache manager
+func NewCache(cfg config.CacheConfig) *Cache {
+	return &Cache{
+		modDir:        cfg.ModPath,
+		buildDir:      cfg.BuildPath,
+		proxy:         cfg.GOPROXY,
+		sumDB:         cfg.GOSUMDB,
+		maxModBytes:   int64(cfg.MaxModSizeMB) << 20,
+		maxBuildBytes: int64(cfg.MaxBuildSizeMB) << 20,
+	}
+}
+
+// Init creates the cache directories
+func (c *Cache) Init() error {
+	for _, dir := range []string{c.modDir, c.buildDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// Env returns the environment overrides that point the go command at the cache
+func (c *Cache) Env() []string {
+	modDir, _ := filepath.Abs(c.modDir)
+	buildDir, _ := filepath.Abs(c.buildDir)
+
+	// -modcacherw keeps the module cache deletable by Trim and Purge
+	goflags := strings.TrimSpace(os.Getenv("GOFLAGS") + " -modcacherw")
+
+	env := []string{
+		"GOMODCACHE=" + modDir,
+		"GOCACHE=" + buildDir,
+		"GOFLAGS=" + goflags,
+	}
+	if c.proxy != "" {
+		env = append(env, "GOPROXY="+c.proxy)
+	}
+	if c.sumDB != "" {
+		env = append(env, "GOSUMDB="+c.sumDB)
+	}
+	return env
+}
+
+// Acquire marks the cache as in use by a build until the returned func is called
+func (c *Cache) Acquire() (release func()) {
+	c.mu.RLock()
+	return c.mu.RUnlock
+}
+
+// Stats walks both caches and reports their usage
+func (c *Cache) Stats() (*models.CacheStats, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	modSize, modFiles, err := dirUsage(c.modDir)
+	if err != nil {
+		return nil, err
+	}
+	buildSize, buildFiles, err := dirUsage(c.buildDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CacheStats{
+		Module: models.CacheUsage{Path: c.modDir, SizeBytes: modSize, Files: modFiles, MaxBytes: c.maxModBytes},
+		Build:  models.CacheUsage{Path: c.buildDir, SizeBytes: buildSize, Files: buildFiles, MaxBytes: c.maxBuildBytes},
+		Proxy:  c.proxy,
+	}, nil
+}
+
+// Purge empties the module cache, the build cache or both
+func (c *Cache) Purge(target string) error {
+	var dirs []string
+	switch target {
+	case TargetModule:
+		dirs = []string{c.modDir}
+	case TargetBuild:
+		dirs = []string{c.buildDir}
+	case TargetAll, "":
+		dirs = []string{c.modDir, c.buildDir}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidTarget, target)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, dir := range dirs {
+		if err := removeAll(dir); err != nil {
+			return fmt.Errorf("failed to purge %s: %w", dir, err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TrimAsync trims the caches in the background unless a trim is already running
+func (c *Cache) TrimAsync() {
+	if !c.trimming.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer c.trimming.Store(false)
+		if err := c.Trim(); err != nil {
+			slog.Warn("Failed to trim go cache", slog.String("error", err.Error()))
+		}
+	}()
+}
+
+// Trim evicts the least recently used entries of any cache over its limit
+func (c *Cache) Trim() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxBuildBytes > 0 {
+		entries, err := buildCacheEntries(c.buildDir)
+		if err != nil {
+			return err
+		}
+		if err := evict(entries, c.maxBuildBytes); err != nil {
+			return err
+		}
+	}
+
+	if c.maxModBytes > 0 {
+		entries, err := modCacheEntries(c.modDir)
+		if err != nil {
+			return err
+		}
+		if err := evict(entries, c.maxModBytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entry is a unit of eviction: one build cache file or one module version
+type entry struct {
+	paths   []string
+	size    int64
+	lastUse time.Time
+}
+
+// evict removes the oldest entries until the total is below trimRatio of max
+func evict(entries []*entry, max int64) error {
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	if total <= max {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].lastUse.Before(entries[j].lastUse)
+	})
+
+	target := int64(float64(max) * trimRatio)
+	for _, e := range entries {
+		if total <= target {
+			break
+		}
+		for _, p := range e.paths {
+			if err := removeAll(p); err != nil {
+				return err
+			}
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+// buildCacheEntries lists GOCACHE entries. The go command refreshes the mtime of
+// entries it reuses, so mtime approximates last use.
+func buildCacheEntries(dir string) ([]*entry, error) {
+	var entries []*entry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Top-level files (README, trim.txt) are cache bookkeeping
+		if d.IsDir() || filepath.Dir(path) == dir {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, &entry{paths: []string{path}, size: info.Size(), lastUse: info.ModTime()})
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return entries, err
+}
+
+// modCacheEntries groups GOMODCACHE content per module version, combining the
+// extracted <module>@<version> tree with its cache/download files
+func modCacheEntries(dir string) ([]*entry, error) {
+	byKey := make(map[string]*entry)
+	add := func(key, path string, size int64, mtime time.Time) {
+		e, ok := byKey[key]
+		if !ok {
+			e = &entry{}
+			byKey[key] = e
+		}
+		e.paths = append(e.paths, path)
+		e.size += size
+		if mtime.After(e.lastUse) {
+			e.lastUse = mtime
+		}
+	}
+
+	downloadDir := filepath.Join(dir, "cache", "download")
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+
+		if d.IsDir() {
+			if strings.Contains(d.Name(), "@") && !strings.HasPrefix(rel, "cache"+string(filepath.Separator)) {
+				size, _, err := dirUsage(path)
+				if err != nil {
+					return err
+				}
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				add(filepath.ToSlash(rel), path, size, info.ModTime())
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		// cache/download/<module>/@v/<version>.{info,mod,zip,ziphash}
+		if dlRel, err := filepath.Rel(downloadDir, path); err == nil && !strings.HasPrefix(dlRel, "..") {
+			modDir, file := filepath.Split(dlRel)
+			if filepath.Base(modDir) != "@v" || file == "list" || strings.HasSuffix(file, ".lock") {
+				return nil
+			}
+			version := strings.TrimSuffix(file, filepath.Ext(file))
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			module := filepath.ToSlash(filepath.Dir(filepath.Clean(modDir)))
+			add(module+"@"+version, path, info.Size(), info.ModTime())
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*entry, 0, len(byKey))
+	for _, e := range byKey {
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func dirUsage(dir string) (size int64, files int, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		files++
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	return size, files, err
+}
+
+// removeAll is os.RemoveAll that also copes with the read-only directories the
+// go command creates in the module cache when -modcacherw was not in effect
+func removeAll(path string) error {
+	if err := os.RemoveAll(path); err == nil {
+		return nil
+	}
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chmod(p, 0755)
+		}
+		return nil
+	})
+	return os.RemoveAll(path)
+}
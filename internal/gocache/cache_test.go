@@ -0,0 +1,83 @@
+package gocache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/config"
+)
+
+func writeAged(t *testing.T, path string, size int, age time.Duration) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	mtime := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, mtime, mtime))
+}
+
+func TestTrim_BuildCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	root := t.TempDir()
+	c := NewCache(config.CacheConfig{
+		ModPath:        filepath.Join(root, "mod"),
+		BuildPath:      filepath.Join(root, "build"),
+		MaxBuildSizeMB: 1,
+	})
+	require.NoError(t, c.Init())
+
+	writeAged(t, filepath.Join(root, "build", "README"), 10, 0)
+	writeAged(t, filepath.Join(root, "build", "aa", "old-a"), 600<<10, 3*time.Hour)
+	writeAged(t, filepath.Join(root, "build", "bb", "new-a"), 600<<10, time.Minute)
+
+	require.NoError(t, c.Trim())
+
+	assert.NoFileExists(t, filepath.Join(root, "build", "aa", "old-a"))
+	assert.FileExists(t, filepath.Join(root, "build", "bb", "new-a"))
+	assert.FileExists(t, filepath.Join(root, "build", "README"))
+}
+
+func TestTrim_ModuleCacheEvictsWholeModuleVersions(t *testing.T) {
+	root := t.TempDir()
+	mod := filepath.Join(root, "mod")
+	c := NewCache(config.CacheConfig{
+		ModPath:      mod,
+		BuildPath:    filepath.Join(root, "build"),
+		MaxModSizeMB: 1,
+	})
+	require.NoError(t, c.Init())
+
+	writeAged(t, filepath.Join(mod, "example.com", "old@v1.0.0", "big.go"), 700<<10, 48*time.Hour)
+	writeAged(t, filepath.Join(mod, "cache", "download", "example.com", "old", "@v", "v1.0.0.zip"), 100<<10, 48*time.Hour)
+	writeAged(t, filepath.Join(mod, "cache", "download", "example.com", "old", "@v", "list"), 10, 48*time.Hour)
+	writeAged(t, filepath.Join(mod, "example.com", "new@v2.0.0", "big.go"), 700<<10, time.Hour)
+	writeAged(t, filepath.Join(mod, "cache", "download", "example.com", "new", "@v", "v2.0.0.zip"), 100<<10, time.Hour)
+
+	require.NoError(t, c.Trim())
+
+	assert.NoDirExists(t, filepath.Join(mod, "example.com", "old@v1.0.0"))
+	assert.NoFileExists(t, filepath.Join(mod, "cache", "download", "example.com", "old", "@v", "v1.0.0.zip"))
+	assert.DirExists(t, filepath.Join(mod, "example.com", "new@v2.0.0"))
+	assert.FileExists(t, filepath.Join(mod, "cache", "download", "example.com", "new", "@v", "v2.0.0.zip"))
+}
+
+func TestPurge(t *testing.T) {
+	root := t.TempDir()
+	c := NewCache(config.CacheConfig{ModPath: filepath.Join(root, "mod"), BuildPath: filepath.Join(root, "build")})
+	require.NoError(t, c.Init())
+
+	// Module cache directories are read-only unless -modcacherw was used
+	ro := filepath.Join(root, "mod", "example.com", "m@v1.0.0")
+	writeAged(t, filepath.Join(ro, "m.go"), 10, 0)
+	require.NoError(t, os.Chmod(ro, 0555))
+	writeAged(t, filepath.Join(root, "build", "aa", "x-a"), 10, 0)
+
+	require.NoError(t, c.Purge(TargetModule))
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Module.Files)
+	assert.Equal(t, 1, stats.Build.Files)
+
+	assert.ErrorIs(t, c.Purge("everything"), ErrInvalidTarget)
+}
@@ -0,0 +1,120 @@
+// internal/models/apikey.go
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Scopes an API key can hold. Besides these, execute:<binary-id> and
+// execute:tag=<label> allow executing single binaries or labelled groups,
+// and build:<binary-id> allows triggering builds of a binary.
+const (
+	ScopeExecuteAll     = "execute:*"
+	ScopeReadExecutions = "read:executions"
+)
+
+// DefaultScopes are granted to keys created without scopes, and to keys
+// stored before scopes existed
+var DefaultScopes = []string{ScopeExecuteAll, ScopeReadExecutions}
+
+var scopeTargetPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// APIKey authorizes calls to the execution endpoints. The key itself is only
+// shown once; the server keeps a salted SHA-256 of it.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`         // first characters of the key, to recognise it
+	Team       string     `json:"team,omitempty"` // keys of a team share their executions
+	Scopes     []string   `json:"scopes"`
+	Salt       string     `json:"salt,omitempty"`
+	Hash       string     `json:"hash,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	Quota      *Quota     `json:"quota,omitempty"` // replaces the server's default quota
+
+	// The key replaced by the last rotation stays valid until PreviousExpiresAt
+	PreviousSalt      string     `json:"previous_salt,omitempty"`
+	PreviousHash      string     `json:"previous_hash,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+}
+
+// Redacted returns a copy without hashes, safe to return from the API
+func (k *APIKey) Redacted() *APIKey {
+	r := *k
+	r.Salt, r.Hash = "", ""
+	r.PreviousSalt, r.PreviousHash = "", ""
+	return &r
+}
+
+// Expired reports whether the key has passed its expiry at now
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Revoked reports whether the key has been revoked
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// HasScope reports whether the key holds scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CanExecute reports whether the key may execute b, either through
+// execute:*, execute:<id> or execute:tag=<label> for one of its labels
+func (k *APIKey) CanExecute(b *Binary) bool {
+	if k.HasScope(ScopeExecuteAll) || k.HasScope("execute:"+b.ID) {
+		return true
+	}
+	for _, label := range b.Labels {
+		if k.HasScope("execute:tag=" + label) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanBuild reports whether the key may trigger builds of the binary id
+func (k *APIKey) CanBuild(id string) bool {
+	return k.HasScope("build:" + id)
+}
+
+// CanAccess reports whether the key may see and stop an execution started by
+// the key keyID on behalf of team
+func (k *APIKey) CanAccess(keyID, team string) bool {
+	return keyID == k.ID || (k.Team != "" && team == k.Team)
+}
+
+// ValidateScopes checks every scope is one of the supported forms
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		kind, target, _ := strings.Cut(scope, ":")
+		switch {
+		case scope == ScopeExecuteAll, scope == ScopeReadExecutions:
+		case kind == "execute" && strings.HasPrefix(target, "tag="):
+			if !labelPattern.MatchString(strings.TrimPrefix(target, "tag=")) {
+				return fmt.Errorf("invalid label in scope %q", scope)
+			}
+		case kind == "execute", kind == "build":
+			if !scopeTargetPattern.MatchString(target) {
+				return fmt.Errorf("invalid binary id in scope %q", scope)
+			}
+		default:
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
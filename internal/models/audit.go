@@ -0,0 +1,90 @@
+// internal/models/audit.go
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Actor types of audit events
+const (
+	ActorUser       = "user"
+	ActorAdminToken = "admin_token"
+	ActorSSO        = "sso"
+	ActorCert       = "certificate"
+	ActorAPIKey     = "api_key"
+	ActorWebhook    = "webhook"
+	ActorAnonymous  = "anonymous"
+)
+
+// Outcomes of audited requests
+const (
+	OutcomeSuccess = "success"
+	OutcomeDenied  = "denied" // 401 or 403
+	OutcomeFailure = "failure"
+)
+
+// AuditEvent records who did what to which target. Each event's hash covers
+// the previous event's hash, so changing or removing an event breaks the chain.
+type AuditEvent struct {
+	Seq       int64             `json:"seq"`
+	Time      time.Time         `json:"time"`
+	Actor     string            `json:"actor"`
+	ActorID   string            `json:"actor_id,omitempty"`
+	ActorType string            `json:"actor_type"`
+	Action    string            `json:"action"`           // e.g. binary.create
+	Target    string            `json:"target,omitempty"` // ID of the binary, user, key, ... acted on
+	Details   map[string]string `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Status    int               `json:"status"`
+	Outcome   string            `json:"outcome"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
+
+// AuditFilter selects audit events. Empty fields match everything.
+type AuditFilter struct {
+	Actor   string
+	Action  string // exact, or a prefix ending in '.' such as binary.
+	Target  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	Limit   int // keep only the most recent matching events
+}
+
+// Matches reports whether e passes the filter, ignoring Limit
+func (f *AuditFilter) Matches(e *AuditEvent) bool {
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action &&
+		!(strings.HasSuffix(f.Action, ".") && strings.HasPrefix(e.Action, f.Action)) {
+		return false
+	}
+	if f.Target != "" && e.Target != f.Target {
+		return false
+	}
+	if f.Outcome != "" && e.Outcome != f.Outcome {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// AuditVerification is the result of checking the hash chain
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Events   int64  `json:"events"`
+	HeadHash string `json:"head_hash,omitempty"` // hash of the last event, to anchor elsewhere
+	BrokenAt int64  `json:"broken_at,omitempty"` // seq of the first event that does not verify
+	Error    string `json:"error,omitempty"`
+}
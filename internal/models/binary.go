@@ -2,43 +2,166 @@
 package models
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
 // Binary represents a managed Go binary
 type Binary struct {
-	ID          string    `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name" validate:"required,min=1,max=255"`
-	Description string    `json:"description" db:"description" validate:"max=1000"`
-	RepoURL     string    `json:"repo_url" db:"repo_url" validate:"required,url"`
-	Branch      string    `json:"branch" db:"branch" validate:"required"`
-	BuildPath   string    `json:"build_path" db:"build_path"` // Path within repo to build
-	BinaryPath  string    `json:"binary_path" db:"binary_path"`
-	Version     string    `json:"version" db:"version"`
-	Status      string    `json:"status" db:"status"` // pending, building, ready, failed
-	LastBuilt   time.Time `json:"last_built" db:"last_built"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID           string        `json:"id" db:"id"`
+	Name         string        `json:"name" db:"name" validate:"required,min=1,max=255"`
+	Description  string        `json:"description" db:"description" validate:"max=1000"`
+	Labels       []string      `json:"labels,omitempty" db:"labels"`                                                           // Grouping for execute:tag=<label> key scopes
+	Owner        string        `json:"owner,omitempty" db:"owner"`                                                             // Username of the maintainer who may change it
+	SourceType   string        `json:"source_type,omitempty" db:"source_type" validate:"omitempty,oneof=git archive prebuilt"` // git, archive or prebuilt
+	RepoURL      string        `json:"repo_url" db:"repo_url" validate:"required_unless=SourceType archive prebuilt,max=2048"`
+	Branch       string        `json:"branch" db:"branch" validate:"required_unless=SourceType archive prebuilt,max=255"`
+	CredentialID string        `json:"credential_id,omitempty" db:"credential_id"`     // Credential used to clone private repos
+	BuildPath    string        `json:"build_path" db:"build_path" validate:"max=1024"` // Path within repo to build
+	CloneOptions *CloneOptions `json:"clone_options,omitempty" db:"clone_options"`
+	BuildOptions *BuildOptions `json:"build_options,omitempty" db:"build_options"`
+	Targets      []Target      `json:"targets,omitempty" db:"targets"`       // Cross-compilation matrix
+	GoVersion    string        `json:"go_version,omitempty" db:"go_version"` // Overrides the go.mod go/toolchain directives
+	Gates        *Gates        `json:"gates,omitempty" db:"gates"`           // Checks that must pass before a build is promoted
+	AutoBuild    *AutoBuild    `json:"auto_build,omitempty" db:"auto_build"`
+	Upload       *Upload       `json:"upload,omitempty" db:"upload"` // Last uploaded archive or executable
+	BinaryPath   string        `json:"binary_path" db:"binary_path"`
+	SHA256       string        `json:"sha256,omitempty" db:"sha256"` // Digest the executor verifies before every run
+	Version      string        `json:"version" db:"version"`
+	Commit       string        `json:"commit,omitempty" db:"commit"` // Full commit hash of the active version
+	Status       string        `json:"status" db:"status"`           // pending, building, ready, failed
+	LastBuilt    time.Time     `json:"last_built" db:"last_built"`
+	LastBuild    *BuildRecord  `json:"last_build,omitempty" db:"last_build"`
+	Artifacts    []Artifact    `json:"artifacts,omitempty" db:"artifacts"` // Artifacts of the active version
+	CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// ApplySettings copies the fields clients may set from src onto b. Build
+// results, digests, uploads, status and timestamps are only ever written by
+// the server.
+func (b *Binary) ApplySettings(src *Binary) {
+	b.Name = src.Name
+	b.Description = src.Description
+	b.Labels = src.Labels
+	b.Owner = src.Owner
+	b.SourceType = src.SourceType
+	b.RepoURL = src.RepoURL
+	b.Branch = src.Branch
+	b.CredentialID = src.CredentialID
+	b.BuildPath = src.BuildPath
+	b.CloneOptions = src.CloneOptions
+	b.BuildOptions = src.BuildOptions
+	b.Targets = src.Targets
+	b.GoVersion = src.GoVersion
+	b.Gates = src.Gates
+	b.AutoBuild = src.AutoBuild
+}
+
+// Source types
+const (
+	SourceGit      = "git"
+	SourceArchive  = "archive"
+	SourcePrebuilt = "prebuilt"
+)
+
+// Upload describes the file last uploaded for an archive or prebuilt binary
+type Upload struct {
+	Filename   string    `json:"filename,omitempty"`
+	SHA256     string    `json:"sha256"`
+	Size       int64     `json:"size"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+var (
+	labelPattern  = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+	branchPattern = regexp.MustCompile(`^[A-Za-z0-9_.+/-]+$`)
+)
+
+// ValidateLabels checks labels are short identifiers without duplicates
+func ValidateLabels(labels []string) error {
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		if !labelPattern.MatchString(label) {
+			return fmt.Errorf("invalid label %q", label)
+		}
+		if seen[label] {
+			return fmt.Errorf("duplicate label %q", label)
+		}
+		seen[label] = true
+	}
+	return nil
+}
+
+// ValidateBranch checks a branch name follows git's ref format, limited to
+// characters that are safe to substitute into ldflags
+func ValidateBranch(branch string) error {
+	if branch == "" {
+		return nil
+	}
+	if !branchPattern.MatchString(branch) || strings.Contains(branch, "..") || strings.HasSuffix(branch, ".") {
+		return fmt.Errorf("invalid branch name %q", branch)
+	}
+	for _, part := range strings.Split(branch, "/") {
+		if part == "" || strings.HasPrefix(part, ".") || strings.HasPrefix(part, "-") || strings.HasSuffix(part, ".lock") {
+			return fmt.Errorf("invalid branch name %q", branch)
+		}
+	}
+	return nil
+}
+
+// IsGit reports whether the binary is built from a git repository
+func (b *Binary) IsGit() bool {
+	return b.SourceType == "" || b.SourceType == SourceGit
+}
+
+// ValidateSourceType checks the source type is one of git, archive or prebuilt
+func ValidateSourceType(sourceType string) error {
+	switch sourceType {
+	case "", SourceGit, SourceArchive, SourcePrebuilt:
+		return nil
+	}
+	return fmt.Errorf("source_type must be git, archive or prebuilt")
+}
+
+// AutoBuild rebuilds a binary when its upstream branch moves
+type AutoBuild struct {
+	Enabled  bool `json:"enabled"`
+	Interval int  `json:"interval,omitempty"` // seconds between polls, 0 for the server default
+}
+
+// Validate checks the poll interval
+func (a *AutoBuild) Validate() error {
+	if a != nil && a.Interval < 0 {
+		return fmt.Errorf("interval must not be negative")
+	}
+	return nil
 }
 
 // ExecutionRequest represents a request to execute a binary
 type ExecutionRequest struct {
 	BinaryID string   `json:"binary_id" validate:"required"`
-	Args     []string `json:"args"`
-	Env      []string `json:"env"`
+	Args     []string `json:"args" validate:"max=256"`
+	Env      []string `json:"env" validate:"max=256"` // KEY=value
 	Stdin    string   `json:"stdin"`
-	Timeout  int      `json:"timeout"` // seconds
+	Timeout  int      `json:"timeout" validate:"min=0"` // seconds
 }
 
 // ExecutionResult represents the result of executing a binary
 type ExecutionResult struct {
 	ID         string    `json:"id"`
 	BinaryID   string    `json:"binary_id"`
-	Status     string    `json:"status"` // running, completed, failed, timeout
+	APIKeyID   string    `json:"api_key_id,omitempty"` // Key that requested the execution
+	APIKeyName string    `json:"api_key_name,omitempty"`
+	Team       string    `json:"team,omitempty"` // Team of that key, whose other keys may also see the execution
+	Status     string    `json:"status"`         // running, completed, failed, timeout
 	ExitCode   int       `json:"exit_code"`
 	Stdout     string    `json:"stdout"`
 	Stderr     string    `json:"stderr"`
 	StartedAt  time.Time `json:"started_at"`
 	FinishedAt time.Time `json:"finished_at"`
 	Duration   int64     `json:"duration_ms"`
+	CPUTime    int64     `json:"cpu_ms"` // user and system CPU time of the process
 }
@@ -0,0 +1,207 @@
+// internal/models/build.go
+package models
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BuildOptions controls how `go build` is invoked for a binary
+type BuildOptions struct {
+	Tags       []string `json:"tags,omitempty"`
+	LDFlags    string   `json:"ldflags,omitempty"` // supports ${COMMIT}, ${SHORT_COMMIT}, ${BRANCH}, ${BUILD_TIME}
+	TrimPath   bool     `json:"trimpath,omitempty"`
+	Race       bool     `json:"race,omitempty"` // implies CGO
+	CGOEnabled bool     `json:"cgo_enabled,omitempty"`
+	GOOS       string   `json:"goos,omitempty"`
+	GOARCH     string   `json:"goarch,omitempty"`
+}
+
+// Target is a GOOS/GOARCH pair to cross-compile for
+type Target struct {
+	GOOS   string `json:"goos"`
+	GOARCH string `json:"goarch"`
+}
+
+// String returns the target in os-arch form, e.g. linux-amd64
+func (t Target) String() string {
+	return t.GOOS + "-" + t.GOARCH
+}
+
+// Artifact is a downloadable build output for a single target
+type Artifact struct {
+	Target
+	BuildID string `json:"build_id"` // Build that produced it, which names its directory in the artifact store
+	Path    string `json:"-"`        // Set while building; downloads resolve the file from the store
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+}
+
+// Toolchain identifies the Go toolchain a build ran with
+type Toolchain struct {
+	Version string `json:"version"`
+	Source  string `json:"source"` // system, cache, offline, downloaded
+	GOROOT  string `json:"goroot,omitempty"`
+}
+
+// BuildRecord describes a single build of a binary
+type BuildRecord struct {
+	ID           string       `json:"id"`
+	Commit       string       `json:"commit"`
+	SourceSHA256 string       `json:"source_sha256,omitempty"` // Uploaded archive or executable the build came from
+	Status       string       `json:"status"`                  // building, succeeded, failed
+	Options      BuildOptions `json:"options"`
+	SHA256       string       `json:"sha256,omitempty"` // Digest of the built executable
+	Toolchain    *Toolchain   `json:"toolchain,omitempty"`
+	Artifacts    []Artifact   `json:"artifacts,omitempty"`
+	Gates        []GateResult `json:"gates,omitempty"`
+	VulnReport   *VulnReport  `json:"vuln_report,omitempty"`
+	Error        string       `json:"error,omitempty"`
+	StartedAt    time.Time    `json:"started_at"`
+	FinishedAt   time.Time    `json:"finished_at"`
+}
+
+// LDFlagsVars are the placeholders that may be used inside BuildOptions.LDFlags
+var LDFlagsVars = []string{"COMMIT", "SHORT_COMMIT", "BRANCH", "BUILD_TIME"}
+
+var (
+	goVersionPattern = regexp.MustCompile(`^(go)?1\.\d+(\.\d+)?((rc|beta)\d+)?$`)
+	buildTagPattern  = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+	ldflagsXPattern  = regexp.MustCompile(`^[A-Za-z0-9_./-]+\.[A-Za-z_][A-Za-z0-9_]*=[A-Za-z0-9_.:+/@${}-]*$`)
+
+	validGOOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+		"illumos": true, "ios": true, "js": true, "linux": true, "netbsd": true, "openbsd": true,
+		"plan9": true, "solaris": true, "wasip1": true, "windows": true,
+	}
+	validGOARCH = map[string]bool{
+		"386": true, "amd64": true, "arm": true, "arm64": true, "loong64": true, "mips": true,
+		"mips64": true, "mips64le": true, "mipsle": true, "ppc64": true, "ppc64le": true,
+		"riscv64": true, "s390x": true, "wasm": true,
+	}
+	// validTargets mirrors `go tool dist list`
+	validTargets = map[string]bool{
+		"aix-ppc64": true, "android-386": true, "android-amd64": true, "android-arm": true,
+		"android-arm64": true, "darwin-amd64": true, "darwin-arm64": true, "dragonfly-amd64": true,
+		"freebsd-386": true, "freebsd-amd64": true, "freebsd-arm": true, "freebsd-arm64": true,
+		"illumos-amd64": true, "ios-amd64": true, "ios-arm64": true, "js-wasm": true,
+		"linux-386": true, "linux-amd64": true, "linux-arm": true, "linux-arm64": true,
+		"linux-loong64": true, "linux-mips": true, "linux-mips64": true, "linux-mips64le": true,
+		"linux-mipsle": true, "linux-ppc64": true, "linux-ppc64le": true, "linux-riscv64": true,
+		"linux-s390x": true, "netbsd-386": true, "netbsd-amd64": true, "netbsd-arm": true,
+		"netbsd-arm64": true, "openbsd-386": true, "openbsd-amd64": true, "openbsd-arm": true,
+		"openbsd-arm64": true, "openbsd-ppc64": true, "openbsd-riscv64": true, "plan9-386": true,
+		"plan9-amd64": true, "plan9-arm": true, "solaris-amd64": true, "wasip1-wasm": true,
+		"windows-386": true, "windows-amd64": true, "windows-arm64": true,
+	}
+)
+
+// ValidateGoVersion checks an explicit toolchain request such as "1.22" or "go1.22.3"
+func ValidateGoVersion(v string) error {
+	if v != "" && !goVersionPattern.MatchString(v) {
+		return fmt.Errorf("invalid go version %q", v)
+	}
+	return nil
+}
+
+// ValidateTargets checks that every target is a supported, unique GOOS/GOARCH pair
+func ValidateTargets(targets []Target) error {
+	seen := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		if !validTargets[t.String()] {
+			return fmt.Errorf("unsupported target %q", t.String())
+		}
+		if seen[t.String()] {
+			return fmt.Errorf("duplicate target %q", t.String())
+		}
+		seen[t.String()] = true
+	}
+	return nil
+}
+
+// Validate rejects options that could be used to smuggle arbitrary flags into the
+// go command. Values are passed as discrete arguments (never through a shell), so
+// the checks focus on keeping each option within its documented meaning.
+func (o *BuildOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	for _, tag := range o.Tags {
+		if !buildTagPattern.MatchString(tag) {
+			return fmt.Errorf("invalid build tag %q", tag)
+		}
+	}
+
+	if err := validateLDFlags(o.LDFlags); err != nil {
+		return err
+	}
+
+	if o.GOOS != "" && !validGOOS[o.GOOS] {
+		return fmt.Errorf("unsupported GOOS %q", o.GOOS)
+	}
+	if o.GOARCH != "" && !validGOARCH[o.GOARCH] {
+		return fmt.Errorf("unsupported GOARCH %q", o.GOARCH)
+	}
+
+	return nil
+}
+
+// validateLDFlags only allows -s, -w and -X importpath.name=value
+func validateLDFlags(ldflags string) error {
+	fields := strings.Fields(ldflags)
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "-s", "-w":
+		case "-X":
+			if i+1 >= len(fields) {
+				return fmt.Errorf("ldflags: -X requires importpath.name=value")
+			}
+			i++
+			if !ldflagsXPattern.MatchString(fields[i]) {
+				return fmt.Errorf("ldflags: invalid -X value %q", fields[i])
+			}
+			if err := validateLDFlagsVars(fields[i]); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("ldflags: flag %q is not allowed", fields[i])
+		}
+	}
+	return nil
+}
+
+func validateLDFlagsVars(value string) error {
+	var unknown string
+	os.Expand(value, func(name string) string {
+		for _, v := range LDFlagsVars {
+			if v == name {
+				return ""
+			}
+		}
+		if unknown == "" {
+			unknown = name
+		}
+		return ""
+	})
+	if unknown != "" {
+		return fmt.Errorf("ldflags: unknown variable ${%s}", unknown)
+	}
+	return nil
+}
+
+// Expand returns a copy of the options with LDFlags placeholders replaced by
+// vars. The result is validated again, so a value cannot add flags of its own.
+func (o BuildOptions) Expand(vars map[string]string) (BuildOptions, error) {
+	o.Tags = append([]string(nil), o.Tags...)
+	o.LDFlags = os.Expand(o.LDFlags, func(name string) string {
+		return vars[name]
+	})
+	if err := validateLDFlags(o.LDFlags); err != nil {
+		return o, err
+	}
+	return o, nil
+}
@@ -0,0 +1,17 @@
+// internal/models/cache.go
+package models
+
+// CacheUsage describes the disk usage of one Go cache directory
+type CacheUsage struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+	Files     int    `json:"files"`
+	MaxBytes  int64  `json:"max_bytes"` // 0 means unlimited
+}
+
+// CacheStats reports usage of the shared module and build caches
+type CacheStats struct {
+	Module CacheUsage `json:"module"`
+	Build  CacheUsage `json:"build"`
+	Proxy  string     `json:"goproxy,omitempty"`
+}
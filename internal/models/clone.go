@@ -0,0 +1,59 @@
+// internal/models/clone.go
+package models
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// CloneOptions controls how much of a binary's repository is fetched and checked out
+type CloneOptions struct {
+	Depth       int      `json:"depth,omitempty"`        // Number of commits to fetch, 0 for full history
+	Sparse      bool     `json:"sparse,omitempty"`       // Only check out the build path and its module root
+	SparsePaths []string `json:"sparse_paths,omitempty"` // Extra directories to check out when sparse
+	Filter      string   `json:"filter,omitempty"`       // Partial clone filter, e.g. blob:none
+	Submodules  bool     `json:"submodules,omitempty"`   // Recursively initialize submodules
+}
+
+var cloneFilterPattern = regexp.MustCompile(`^(blob:none|blob:limit=[0-9]+[kmg]?|tree:[0-9]+)$`)
+
+// Validate checks the options are safe to hand to git
+func (o *CloneOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	if o.Depth < 0 {
+		return fmt.Errorf("depth must not be negative")
+	}
+
+	if o.Filter != "" && !cloneFilterPattern.MatchString(o.Filter) {
+		return fmt.Errorf("unsupported filter %q", o.Filter)
+	}
+
+	for _, p := range o.SparsePaths {
+		if err := validateRepoPath(p); err != nil {
+			return fmt.Errorf("sparse path %q: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// validateRepoPath requires a relative path that stays inside the repository
+func validateRepoPath(p string) error {
+	clean := path.Clean(strings.TrimPrefix(p, "./"))
+	switch {
+	case p == "" || clean == ".":
+		return fmt.Errorf("must not be empty")
+	case path.IsAbs(p):
+		return fmt.Errorf("must be relative")
+	case clean == ".." || strings.HasPrefix(clean, "../"):
+		return fmt.Errorf("must stay inside the repository")
+	case strings.HasPrefix(clean, "-"):
+		return fmt.Errorf("must not start with '-'")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+// internal/models/credential.go
+package models
+
+import (
+	"time"
+)
+
+// Credential types
+const (
+	CredentialToken = "token" // HTTPS personal access / deploy token
+	CredentialBasic = "basic" // HTTPS username and password
+	CredentialSSH   = "ssh"   // SSH deploy key with pinned host keys
+)
+
+// Credential is a secret used to clone private repositories
+type Credential struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name" validate:"required,max=255"`
+	Type       string    `json:"type" validate:"required,oneof=token basic ssh"`
+	Username   string    `json:"username,omitempty" validate:"max=255"`
+	Secret     string    `json:"secret,omitempty"`      // token, password or PEM private key
+	KnownHosts string    `json:"known_hosts,omitempty"` // known_hosts lines pinning the SSH host keys
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Redacted returns a copy safe to return from the API
+func (c *Credential) Redacted() *Credential {
+	r := *c
+	r.Secret = ""
+	return &r
+}
@@ -0,0 +1,69 @@
+// internal/models/gate.go
+package models
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Gates are checks a commit must pass before its build is promoted to ready
+type Gates struct {
+	Vet         bool    `json:"vet,omitempty"`          // Run go vet ./... in the build path
+	Test        bool    `json:"test,omitempty"`         // Run go test ./... in the build path
+	TestTimeout int     `json:"test_timeout,omitempty"` // seconds, 0 for the default of 10 minutes
+	Checks      []Check `json:"checks,omitempty"`
+}
+
+// Check is a custom gate command, run without a shell from the build path
+type Check struct {
+	Name    string   `json:"name"`
+	Command []string `json:"command"`           // argv, e.g. ["golangci-lint", "run"]
+	Timeout int      `json:"timeout,omitempty"` // seconds, 0 for the default of 10 minutes
+}
+
+// GateResult is the outcome of a single gate
+type GateResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"` // passed, failed, timeout
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output,omitempty"` // tail of the combined output
+	Duration int64  `json:"duration_ms"`
+}
+
+// Passed reports whether the gate succeeded
+func (r GateResult) Passed() bool {
+	return r.Status == "passed"
+}
+
+var checkNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Validate checks timeouts and that every custom check has a unique name and a command
+func (g *Gates) Validate() error {
+	if g == nil {
+		return nil
+	}
+
+	if g.TestTimeout < 0 {
+		return fmt.Errorf("test_timeout must not be negative")
+	}
+
+	seen := map[string]bool{"vet": true, "test": true}
+	for _, c := range g.Checks {
+		if !checkNamePattern.MatchString(c.Name) {
+			return fmt.Errorf("invalid check name %q", c.Name)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("duplicate check name %q", c.Name)
+		}
+		seen[c.Name] = true
+
+		if len(c.Command) == 0 || c.Command[0] == "" {
+			return fmt.Errorf("check %q: command must not be empty", c.Name)
+		}
+		if c.Timeout < 0 {
+			return fmt.Errorf("check %q: timeout must not be negative", c.Name)
+		}
+	}
+
+	return nil
+}
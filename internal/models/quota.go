@@ -0,0 +1,78 @@
+// internal/models/quota.go
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Quota limits how much an API key may execute per UTC day and month.
+// Zero means unlimited.
+type Quota struct {
+	DailyExecutions   int64 `json:"daily_executions,omitempty"`
+	MonthlyExecutions int64 `json:"monthly_executions,omitempty"`
+	DailyCPUSeconds   int64 `json:"daily_cpu_seconds,omitempty"`
+	MonthlyCPUSeconds int64 `json:"monthly_cpu_seconds,omitempty"`
+}
+
+// Validate checks that no limit is negative
+func (q *Quota) Validate() error {
+	if q == nil {
+		return nil
+	}
+	if q.DailyExecutions < 0 || q.MonthlyExecutions < 0 || q.DailyCPUSeconds < 0 || q.MonthlyCPUSeconds < 0 {
+		return errors.New("quota limits must not be negative")
+	}
+	return nil
+}
+
+// QuotaUsage counts what an API key has executed in the current day and month
+type QuotaUsage struct {
+	Day             string `json:"day"` // 2006-01-02, UTC
+	DayExecutions   int64  `json:"day_executions"`
+	DayCPUMillis    int64  `json:"day_cpu_ms"`
+	Month           string `json:"month"` // 2006-01, UTC
+	MonthExecutions int64  `json:"month_executions"`
+	MonthCPUMillis  int64  `json:"month_cpu_ms"`
+}
+
+// Roll starts new periods once now has left the recorded day or month
+func (u *QuotaUsage) Roll(now time.Time) {
+	now = now.UTC()
+	if day := now.Format("2006-01-02"); u.Day != day {
+		u.Day, u.DayExecutions, u.DayCPUMillis = day, 0, 0
+	}
+	if month := now.Format("2006-01"); u.Month != month {
+		u.Month, u.MonthExecutions, u.MonthCPUMillis = month, 0, 0
+	}
+}
+
+// QuotaExceededError reports the limit an execution would go over
+type QuotaExceededError struct {
+	Limit string    // e.g. daily_executions
+	Max   int64     // the limit, in executions or CPU seconds
+	Reset time.Time // when the period ends
+}
+
+func (e *QuotaExceededError) Error() string {
+	return "quota exceeded: " + e.Limit
+}
+
+// Exceeded reports the first limit of q that u has used up at now, or nil
+func (q Quota) Exceeded(u *QuotaUsage, now time.Time) *QuotaExceededError {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+	switch {
+	case q.DailyExecutions > 0 && u.DayExecutions >= q.DailyExecutions:
+		return &QuotaExceededError{Limit: "daily_executions", Max: q.DailyExecutions, Reset: day}
+	case q.DailyCPUSeconds > 0 && u.DayCPUMillis >= q.DailyCPUSeconds*1000:
+		return &QuotaExceededError{Limit: "daily_cpu_seconds", Max: q.DailyCPUSeconds, Reset: day}
+	case q.MonthlyExecutions > 0 && u.MonthExecutions >= q.MonthlyExecutions:
+		return &QuotaExceededError{Limit: "monthly_executions", Max: q.MonthlyExecutions, Reset: month}
+	case q.MonthlyCPUSeconds > 0 && u.MonthCPUMillis >= q.MonthlyCPUSeconds*1000:
+		return &QuotaExceededError{Limit: "monthly_cpu_seconds", Max: q.MonthlyCPUSeconds, Reset: month}
+	}
+	return nil
+}
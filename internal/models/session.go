@@ -0,0 +1,34 @@
+// internal/models/session.go
+package models
+
+import (
+	"time"
+)
+
+// Session is a sign-in to the admin UI. The browser holds a random token;
+// the server keeps only its SHA-256.
+type Session struct {
+	ID         string    `json:"id"` // public, used to list and revoke sessions
+	TokenHash  string    `json:"token_hash,omitempty"`
+	UserID     string    `json:"user_id,omitempty"` // empty when signed in with the admin token
+	Username   string    `json:"username"`
+	Role       string    `json:"role,omitempty"`       // of single sign-on users, who are not in the user store
+	CSRFToken  string    `json:"csrf_token,omitempty"` // must accompany POST, PUT and DELETE requests
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // absolute expiry, however active the session is
+}
+
+// Redacted returns a copy without the token hash and CSRF token
+func (s *Session) Redacted() *Session {
+	r := *s
+	r.TokenHash, r.CSRFToken = "", ""
+	return &r
+}
+
+// IsAdminToken reports whether the session was started with the admin token
+func (s *Session) IsAdminToken() bool {
+	return s.UserID == ""
+}
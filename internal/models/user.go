@@ -0,0 +1,105 @@
+// internal/models/user.go
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Roles, from least to most privileged
+const (
+	RoleViewer     = "viewer"     // read binaries and cache statistics
+	RoleOperator   = "operator"   // also build and execute binaries
+	RoleMaintainer = "maintainer" // also create binaries and change the ones they own
+	RoleAdmin      = "admin"      // everything, including users, credentials and API keys
+)
+
+// Permission is an action a role may perform
+type Permission string
+
+const (
+	PermReadBinaries      Permission = "binaries:read"
+	PermCreateBinaries    Permission = "binaries:create"
+	PermWriteBinaries     Permission = "binaries:write" // update, upload and delete owned binaries
+	PermWriteAllBinaries  Permission = "binaries:write:all"
+	PermBuildBinaries     Permission = "binaries:build"
+	PermExecute           Permission = "executions:run"
+	PermReadCache         Permission = "cache:read"
+	PermManageCache       Permission = "cache:manage"
+	PermManageCredentials Permission = "credentials:manage"
+	PermManageAPIKeys     Permission = "apikeys:manage"
+	PermManageUsers       Permission = "users:manage"
+	PermReadAudit         Permission = "audit:read"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleViewer: {PermReadBinaries, PermReadCache},
+	RoleOperator: {PermReadBinaries, PermReadCache,
+		PermBuildBinaries, PermExecute},
+	RoleMaintainer: {PermReadBinaries, PermReadCache, PermBuildBinaries, PermExecute,
+		PermCreateBinaries, PermWriteBinaries},
+	RoleAdmin: {PermReadBinaries, PermReadCache, PermBuildBinaries, PermExecute,
+		PermCreateBinaries, PermWriteBinaries, PermWriteAllBinaries,
+		PermManageCache, PermManageCredentials, PermManageAPIKeys, PermManageUsers, PermReadAudit},
+}
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.@-]{1,64}$`)
+
+// User is an account that signs in to the admin UI and API
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"password_hash,omitempty"` // bcrypt
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Redacted returns a copy without the password hash, safe to return from the API
+func (u *User) Redacted() *User {
+	r := *u
+	r.PasswordHash = ""
+	return &r
+}
+
+// Can reports whether the user's role grants perm
+func (u *User) Can(perm Permission) bool {
+	for _, p := range rolePermissions[u.Role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// CanWriteBinary reports whether the user may update, upload to or delete b
+func (u *User) CanWriteBinary(b *Binary) bool {
+	return u.Can(PermWriteAllBinaries) || (u.Can(PermWriteBinaries) && b.Owner != "" && b.Owner == u.Username)
+}
+
+// RoleRank orders roles from viewer (1) to admin (4). Unknown roles rank 0.
+func RoleRank(role string) int {
+	for i, r := range []string{RoleViewer, RoleOperator, RoleMaintainer, RoleAdmin} {
+		if r == role {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ValidateRole checks role is one of viewer, operator, maintainer or admin
+func ValidateRole(role string) error {
+	if _, ok := rolePermissions[role]; !ok {
+		return fmt.Errorf("role must be viewer, operator, maintainer or admin")
+	}
+	return nil
+}
+
+// ValidateUsername checks a username is 1-64 letters, digits or _.@-
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("username must be 1-64 letters, digits or _.@-")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+// internal/models/vuln.go
+package models
+
+import "time"
+
+// Vulnerability is a known vulnerability affecting a module linked into a binary
+type Vulnerability struct {
+	ID      string   `json:"id"` // Go vulnerability ID, e.g. GO-2023-2186
+	Aliases []string `json:"aliases,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	Module  string   `json:"module"` // "stdlib" for the standard library
+	Version string   `json:"version"`
+	Fixed   string   `json:"fixed,omitempty"` // Earliest version with a fix, if any
+}
+
+// VulnReport is the result of checking a binary against the vulnerability database
+type VulnReport struct {
+	DatabaseModified time.Time       `json:"database_modified"`
+	ScannedAt        time.Time       `json:"scanned_at"`
+	Vulnerabilities  []Vulnerability `json:"vulnerabilities"`
+}
@@ -0,0 +1,256 @@
+// internal/oidc/jwt.go
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // register the hashes of the supported algorithms
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minJWKSRefresh limits how often an unknown key ID triggers a JWKS fetch
+const minJWKSRefresh = time.Minute
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verifyJWT checks the signature of a compact JWS against keys and returns
+// its claims. Only asymmetric algorithms are accepted.
+func verifyJWT(ctx context.Context, raw string, keys *keySet) (map[string]interface{}, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad header encoding", ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidToken)
+	}
+
+	key, err := keys.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad payload encoding", ErrInvalidToken)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: bad payload", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// verifySignature checks sig over signed with the algorithm alg names
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	var hash crypto.Hash
+	if len(alg) == 5 {
+		switch alg[2:] {
+		case "256":
+			hash = crypto.SHA256
+		case "384":
+			hash = crypto.SHA384
+		case "512":
+			hash = crypto.SHA512
+		}
+	}
+	if hash == 0 {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	var err error
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, sig)
+		case "PS":
+			err = rsa.VerifyPSS(pub, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		default:
+			return fmt.Errorf("%w: algorithm %q does not match an RSA key", ErrInvalidToken, alg)
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if alg[:2] != "ES" || len(sig) != 2*size {
+			return fmt.Errorf("%w: algorithm %q does not match an EC key", ErrInvalidToken, alg)
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			err = fmt.Errorf("ecdsa verification failed")
+		}
+	default:
+		return fmt.Errorf("%w: unsupported key type", ErrInvalidToken)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return nil
+}
+
+// keySet caches the issuer's signing keys. Keys are refetched after ttl, or
+// early when a token names a key ID the cache does not know.
+type keySet struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+func (ks *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := ks.now()
+	key, ok := ks.lookupLocked(kid)
+	stale := now.Sub(ks.fetched) >= ks.ttl
+	if ok && !stale {
+		return key, nil
+	}
+	if !ok && !stale && now.Sub(ks.fetched) < minJWKSRefresh {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+	}
+
+	keys, err := ks.fetch(ctx)
+	if err != nil {
+		if ok {
+			// Keep trusting a known key while the issuer is unreachable
+			slog.Warn("Failed to refresh OIDC signing keys", slog.String("error", err.Error()))
+			return key, nil
+		}
+		return nil, err
+	}
+	ks.keys, ks.fetched = keys, now
+	if key, ok = ks.lookupLocked(kid); !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// lookupLocked finds kid, or the only key when the token names none
+func (ks *keySet) lookupLocked(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (ks *keySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, ks.client, ks.url, &doc); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			slog.Warn("Skipping OIDC signing key", slog.String("kid", k.Kid), slog.String("error", err.Error()))
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("bad RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, fmt.Errorf("bad EC point")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		copy(point[1+size-len(x):], x)
+		copy(point[1+2*size-len(y):], y)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
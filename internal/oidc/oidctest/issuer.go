@@ -0,0 +1,225 @@
+// internal/oidc/oidctest/issuer.go
+
+// Package oidctest provides a local OpenID Connect issuer for tests.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Issuer is an OpenID provider that signs in whoever SetClaims describes
+// without asking. It implements the authorization-code flow with PKCE and
+// serves its signing keys as a JWKS.
+type Issuer struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu           sync.Mutex
+	key          *rsa.PrivateKey
+	kid          string
+	claims       map[string]interface{}
+	codes        map[string]authRequest
+	jwksRequests int
+}
+
+type authRequest struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+// NewIssuer starts an issuer for one client. Close it when done.
+func NewIssuer(clientID, clientSecret string) *Issuer {
+	iss := &Issuer{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		claims:       make(map[string]interface{}),
+		codes:        make(map[string]authRequest),
+	}
+	iss.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", iss.discoveryHandler)
+	mux.HandleFunc("/jwks", iss.jwksHandler)
+	mux.HandleFunc("/authorize", iss.authorizeHandler)
+	mux.HandleFunc("/token", iss.tokenHandler)
+	iss.Server = httptest.NewServer(mux)
+	return iss
+}
+
+// SetClaims sets the claims of the next sign-in, such as sub and groups
+func (iss *Issuer) SetClaims(claims map[string]interface{}) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.claims = claims
+}
+
+// RotateKey replaces the signing key with a new one under a new key ID
+func (iss *Issuer) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.key = key
+	iss.kid = randomString()
+}
+
+// JWKSRequests returns how often the signing keys were fetched
+func (iss *Issuer) JWKSRequests() int {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	return iss.jwksRequests
+}
+
+// Token signs a token for the client, valid for an hour. claims override
+// and extend the default iss, aud, iat and exp claims.
+func (iss *Issuer) Token(claims map[string]interface{}) string {
+	now := time.Now()
+	all := map[string]interface{}{
+		"iss": iss.URL,
+		"aud": iss.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		all[name] = value
+	}
+
+	iss.mu.Lock()
+	key, kid := iss.key, iss.kid
+	iss.mu.Unlock()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(all)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (iss *Issuer) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 iss.URL,
+		"authorization_endpoint": iss.URL + "/authorize",
+		"token_endpoint":         iss.URL + "/token",
+		"jwks_uri":               iss.URL + "/jwks",
+	})
+}
+
+func (iss *Issuer) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	iss.mu.Lock()
+	iss.jwksRequests++
+	pub, kid := iss.key.PublicKey, iss.kid
+	iss.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorizeHandler signs the user in at once and redirects back with a code
+func (iss *Issuer) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != iss.ClientID ||
+		q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "bad authorization request", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Host == "" {
+		http.Error(w, "bad redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	iss.mu.Lock()
+	iss.codes[code] = authRequest{
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+	}
+	iss.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// tokenHandler redeems a code once, checking the client secret and PKCE verifier
+func (iss *Issuer) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	id, secret, _ := r.BasicAuth()
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	if id != iss.ClientID || secret != iss.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	iss.mu.Lock()
+	req, ok := iss.codes[code]
+	delete(iss.codes, code)
+	claims := make(map[string]interface{}, len(iss.claims)+1)
+	for name, value := range iss.claims {
+		claims[name] = value
+	}
+	iss.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != req.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != req.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims["nonce"] = req.nonce
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     iss.Token(claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
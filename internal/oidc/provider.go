@@ -0,0 +1,317 @@
+// internal/oidc/provider.go
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrNoRole       = errors.New("no go_runner role for this user")
+)
+
+// clockSkew is how far the issuer's clock may be off from ours
+const clockSkew = time.Minute
+
+// discovery is the part of the issuer's openid-configuration that is used
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider signs users in with an OpenID Connect issuer and maps their
+// claims onto go_runner roles. The issuer's metadata is fetched on first use.
+type Provider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu   sync.Mutex
+	meta *discovery
+	keys *keySet
+}
+
+// NewProvider checks cfg and creates a provider for its issuer
+func NewProvider(cfg config.OIDCConfig) (*Provider, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("issuer, client ID and redirect URL are required")
+	}
+	for group, role := range cfg.RoleMap {
+		if err := models.ValidateRole(role); err != nil {
+			return nil, fmt.Errorf("role of group %q: %w", group, err)
+		}
+	}
+	if cfg.DefaultRole != "" {
+		if err := models.ValidateRole(cfg.DefaultRole); err != nil {
+			return nil, fmt.Errorf("default role: %w", err)
+		}
+	}
+	if cfg.Audience == "" {
+		cfg.Audience = cfg.ClientID
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	if cfg.JWKSCacheTTL <= 0 {
+		cfg.JWKSCacheTTL = time.Hour
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid"}
+	}
+
+	return &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+// AuthCodeURL returns where to send the browser to sign in. The PKCE
+// challenge is derived from verifier, which must be kept for Exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange redeems the authorization code for an ID token, verifies it and
+// returns the user it describes
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*models.User, error) {
+	meta, keys, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("bad token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.IDToken == "" {
+		return nil, fmt.Errorf("token request failed (status %d): %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+
+	claims, err := p.verify(ctx, token.IDToken, meta, keys, p.cfg.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+	return p.identity(claims)
+}
+
+// Authenticate verifies a bearer JWT issued for the configured audience and
+// returns the user it describes
+func (p *Provider) Authenticate(ctx context.Context, token string) (*models.User, error) {
+	meta, keys, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := p.verify(ctx, token, meta, keys, p.cfg.Audience)
+	if err != nil {
+		return nil, err
+	}
+	return p.identity(claims)
+}
+
+// verify checks the signature, issuer, audience and validity period of a token
+func (p *Provider) verify(ctx context.Context, token string, meta *discovery, keys *keySet, audience string) (map[string]interface{}, error) {
+	claims, err := verifyJWT(ctx, token, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); iss != meta.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q is not trusted", ErrInvalidToken, iss)
+	}
+	if !containsString(stringsClaim(claims["aud"]), audience) {
+		return nil, fmt.Errorf("%w: token is not for audience %q", ErrInvalidToken, audience)
+	}
+
+	now := p.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: token has no expiry", ErrInvalidToken)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: token has expired", ErrInvalidToken)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// identity maps verified claims onto a user. The user gets the highest role
+// of their mapped groups, or the default role. Usernames are chosen at the
+// issuer, so they are prefixed with oidc: to never match a local user, whose
+// names cannot contain a colon.
+func (p *Provider) identity(claims map[string]interface{}) (*models.User, error) {
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+	username, _ := claimPath(claims, p.cfg.UsernameClaim).(string)
+	if username == "" {
+		username = sub
+	}
+	if err := models.ValidateUsername(username); err != nil {
+		return nil, fmt.Errorf("%w: %s claim: %v", ErrInvalidToken, p.cfg.UsernameClaim, err)
+	}
+
+	role := p.cfg.DefaultRole
+	if p.cfg.GroupsClaim != "" {
+		for _, group := range stringsClaim(claimPath(claims, p.cfg.GroupsClaim)) {
+			if mapped, ok := p.cfg.RoleMap[group]; ok && models.RoleRank(mapped) > models.RoleRank(role) {
+				role = mapped
+			}
+		}
+	}
+	if role == "" {
+		return nil, ErrNoRole
+	}
+
+	return &models.User{ID: "oidc:" + sub, Username: "oidc:" + username, Role: role}, nil
+}
+
+// discover fetches the issuer's metadata once it is first needed, so the
+// server starts even while the issuer is unreachable
+func (p *Provider) discover(ctx context.Context) (*discovery, *keySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, p.keys, nil
+	}
+
+	var meta discovery
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, p.client, wellKnown, &meta); err != nil {
+		return nil, nil, fmt.Errorf("failed to discover OIDC issuer: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != strings.TrimSuffix(p.cfg.Issuer, "/") {
+		return nil, nil, fmt.Errorf("OIDC issuer reports itself as %q", meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, nil, errors.New("OIDC issuer metadata is incomplete")
+	}
+
+	p.meta = &meta
+	p.keys = &keySet{url: meta.JWKSURI, client: p.client, ttl: p.cfg.JWKSCacheTTL, now: p.now}
+	return p.meta, p.keys, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// claimPath looks up a dotted path such as realm_access.roles
+func claimPath(claims map[string]interface{}, path string) interface{} {
+	var v interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+// stringsClaim reads a claim that is a string or a list of strings
+func stringsClaim(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/oidc/oidctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Issuer) {
+	t.Helper()
+	issuer := oidctest.NewIssuer("go-runner", "client secret")
+	t.Cleanup(issuer.Close)
+
+	p, err := NewProvider(config.OIDCConfig{
+		Issuer:        issuer.URL,
+		ClientID:      "go-runner",
+		ClientSecret:  "client secret",
+		RedirectURL:   "http://runner.example.com/login/oidc/callback",
+		Scopes:        []string{"openid", "profile"},
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+		RoleMap:       map[string]string{"devs": models.RoleMaintainer, "ops": models.RoleOperator, "platform": models.RoleAdmin},
+	})
+	require.NoError(t, err)
+	return p, issuer
+}
+
+func TestNewProvider_Validation(t *testing.T) {
+	_, err := NewProvider(config.OIDCConfig{Issuer: "https://idp.example.com", ClientID: "x"})
+	assert.Error(t, err)
+
+	_, err = NewProvider(config.OIDCConfig{
+		Issuer: "https://idp.example.com", ClientID: "x", RedirectURL: "https://runner/cb",
+		RoleMap: map[string]string{"devs": "root"},
+	})
+	assert.Error(t, err)
+}
+
+func TestProvider_AuthCodeFlow(t *testing.T) {
+	p, issuer := newTestProvider(t)
+	ctx := context.Background()
+	issuer.SetClaims(map[string]interface{}{"sub": "u-1", "preferred_username": "alice", "groups": []string{"ops", "devs"}})
+
+	signIn := func(verifier, nonce string) string {
+		authURL, err := p.AuthCodeURL(ctx, "state-1", nonce, verifier)
+		require.NoError(t, err)
+		u, _ := url.Parse(authURL)
+		assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+		assert.NotContains(t, authURL, verifier)
+		assert.Equal(t, "openid profile", u.Query().Get("scope"))
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Get(authURL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		callback, err := resp.Location()
+		require.NoError(t, err)
+		assert.Equal(t, "state-1", callback.Query().Get("state"))
+		return callback.Query().Get("code")
+	}
+
+	// The highest mapped group wins
+	code := signIn("verifier-verifier-verifier-verifier-verifier", "nonce-1")
+	user, err := p.Exchange(ctx, code, "verifier-verifier-verifier-verifier-verifier", "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "oidc:u-1", user.ID)
+	assert.Equal(t, "oidc:alice", user.Username)
+	assert.Equal(t, models.RoleMaintainer, user.Role)
+
+	// Codes are single use
+	_, err = p.Exchange(ctx, code, "verifier-verifier-verifier-verifier-verifier", "nonce-1")
+	assert.Error(t, err)
+
+	// The PKCE verifier must match the challenge
+	code = signIn("verifier-verifier-verifier-verifier-verifier", "nonce-2")
+	_, err = p.Exchange(ctx, code, "another-verifier-another-verifier-another", "nonce-2")
+	assert.Error(t, err)
+
+	// The ID token must carry the nonce of this sign-in
+	code = signIn("verifier-verifier-verifier-verifier-verifier", "nonce-3")
+	_, err = p.Exchange(ctx, code, "verifier-verifier-verifier-verifier-verifier", "nonce-other")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestProvider_Authenticate(t *testing.T) {
+	p, issuer := newTestProvider(t)
+	ctx := context.Background()
+
+	user, err := p.Authenticate(ctx, issuer.Token(map[string]interface{}{"sub": "u-2", "preferred_username": "bob", "groups": "platform"}))
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+	assert.Equal(t, "oidc:bob", user.Username)
+
+	// The subject is the username when the claim is missing
+	_, err = p.Authenticate(ctx, issuer.Token(map[string]interface{}{"sub": "u-3", "groups": []string{"ops"}}))
+	require.NoError(t, err)
+
+	// Users in no mapped group have no role unless a default is configured
+	_, err = p.Authenticate(ctx, issuer.Token(map[string]interface{}{"sub": "u-4", "groups": []string{"sales"}}))
+	assert.ErrorIs(t, err, ErrNoRole)
+	p.cfg.DefaultRole = models.RoleViewer
+	user, err = p.Authenticate(ctx, issuer.Token(map[string]interface{}{"sub": "u-4", "groups": []string{"sales"}}))
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleViewer, user.Role)
+
+	// Groups may be nested, as with Keycloak realm roles
+	p.cfg.GroupsClaim = "realm_access.roles"
+	user, err = p.Authenticate(ctx, issuer.Token(map[string]interface{}{"sub": "u-5", "realm_access": map[string]interface{}{"roles": []string{"devs"}}}))
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleMaintainer, user.Role)
+
+	invalid := map[string]string{
+		"expired":        issuer.Token(map[string]interface{}{"sub": "u-6", "exp": time.Now().Add(-time.Hour).Unix()}),
+		"not yet valid":  issuer.Token(map[string]interface{}{"sub": "u-6", "nbf": time.Now().Add(time.Hour).Unix()}),
+		"other audience": issuer.Token(map[string]interface{}{"sub": "u-6", "aud": "someone-else"}),
+		"other issuer":   issuer.Token(map[string]interface{}{"sub": "u-6", "iss": "https://evil.example.com"}),
+		"no subject":     issuer.Token(nil),
+		"not a jwt":      "opaque-access-token",
+	}
+	// A token whose payload was changed after signing
+	parts := strings.Split(issuer.Token(map[string]interface{}{"sub": "u-6"}), ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"u-6","groups":["platform"]}`))
+	invalid["tampered"] = strings.Join(parts, ".")
+	// alg=none must never be accepted
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	invalid["alg none"] = none + "." + parts[1] + "."
+
+	for name, token := range invalid {
+		_, err := p.Authenticate(ctx, token)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+}
+
+func TestProvider_JWKSCache(t *testing.T) {
+	p, issuer := newTestProvider(t)
+	ctx := context.Background()
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	p.cfg.DefaultRole = models.RoleViewer
+
+	// Keys are fetched once and reused
+	for i := 0; i < 3; i++ {
+		_, err := p.Authenticate(ctx, issuer.Token(map[string]interface{}{"sub": "u-1"}))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, issuer.JWKSRequests())
+
+	// A new key ID triggers a refresh
+	issuer.RotateKey()
+	now = now.Add(2 * time.Minute)
+	_, err := p.Authenticate(ctx, issuer.Token(map[string]interface{}{"sub": "u-1"}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, issuer.JWKSRequests())
+
+	// Unknown key IDs cannot force refetching more than once a minute
+	issuer.RotateKey()
+	_, err = p.Authenticate(ctx, issuer.Token(map[string]interface{}{"sub": "u-1"}))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, 2, issuer.JWKSRequests())
+
+	// The cache expires after its TTL
+	now = now.Add(time.Hour)
+	_, err = p.Authenticate(ctx, issuer.Token(map[string]interface{}{"sub": "u-1", "exp": now.Add(time.Hour).Unix()}))
+	require.NoError(t, err)
+	assert.Equal(t, 3, issuer.JWKSRequests())
+}
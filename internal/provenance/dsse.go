@@ -0,0 +1,177 @@
+// internal/provenance/dsse.go
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrUnsigned         = errors.New("provenance is not signed")
+	ErrInvalidSignature = errors.New("provenance signature is invalid")
+)
+
+// Envelope is a DSSE envelope around a statement. Signatures is empty when
+// the server has no signing key.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"` // base64 encoded statement
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a DSSE signature
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"` // base64 encoded
+}
+
+// Seal wraps a statement in an envelope, signing it when signer is non-nil
+func Seal(stmt *Statement, signer *Signer) (*Envelope, error) {
+	payload, err := json.Marshal(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []Signature{},
+	}
+	if signer != nil {
+		sig := ed25519.Sign(signer.key, pae(PayloadType, payload))
+		env.Signatures = append(env.Signatures, Signature{
+			KeyID: signer.keyID,
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		})
+	}
+	return env, nil
+}
+
+// Statement decodes the payload without checking signatures
+func (e *Envelope) Statement() (*Statement, error) {
+	if e.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", e.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload encoding: %w", err)
+	}
+	var stmt Statement
+	if err := json.Unmarshal(payload, &stmt); err != nil {
+		return nil, fmt.Errorf("invalid statement: %w", err)
+	}
+	return &stmt, nil
+}
+
+// pae is the DSSE pre-authentication encoding that is actually signed
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// Signer signs provenance with a server-held ed25519 key
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner wraps an ed25519 private key
+func NewSigner(key ed25519.PrivateKey) *Signer {
+	sum := sha256.Sum256(key.Public().(ed25519.PublicKey))
+	return &Signer{key: key, keyID: hex.EncodeToString(sum[:8])}
+}
+
+// LoadOrCreateSigner reads a PKCS#8 PEM ed25519 key from path, generating
+// one with owner-only permissions if the file does not exist
+func LoadOrCreateSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createSigner(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: expected a PKCS#8 PEM private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 key", path)
+	}
+	return NewSigner(key), nil
+}
+
+func createSigner(path string) (*Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	return NewSigner(key), nil
+}
+
+// KeyID identifies the signing key in envelopes
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKeyPEM returns the verification key as a PKIX PEM block
+func (s *Signer) PublicKeyPEM() ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(s.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// Verify checks the envelope carries a valid signature by this key and returns its statement
+func (s *Signer) Verify(env *Envelope) (*Statement, error) {
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload encoding: %w", err)
+	}
+
+	signed := false
+	for _, sig := range env.Signatures {
+		if sig.KeyID != s.keyID {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			return nil, ErrInvalidSignature
+		}
+		if !ed25519.Verify(s.key.Public().(ed25519.PublicKey), pae(env.PayloadType, payload), raw) {
+			return nil, ErrInvalidSignature
+		}
+		signed = true
+	}
+	if !signed {
+		return nil, ErrUnsigned
+	}
+	return env.Statement()
+}
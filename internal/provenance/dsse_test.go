@@ -0,0 +1,96 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSigner(t *testing.T) *Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return NewSigner(key)
+}
+
+func testStatement() *Statement {
+	stmt := NewStatement(BuildTypeGo, "build-1", time.Now(), time.Now())
+	stmt.AddSubject("tool", "aaaa")
+	stmt.AddSubject("linux-arm64", "bbbb")
+	return stmt
+}
+
+func TestSealAndVerify(t *testing.T) {
+	signer := newTestSigner(t)
+
+	env, err := Seal(testStatement(), signer)
+	require.NoError(t, err)
+	require.Len(t, env.Signatures, 1)
+	assert.Equal(t, signer.KeyID(), env.Signatures[0].KeyID)
+
+	stmt, err := signer.Verify(env)
+	require.NoError(t, err)
+	assert.Equal(t, StatementType, stmt.Type)
+	assert.Equal(t, PredicateType, stmt.PredicateType)
+	digest, ok := stmt.SubjectDigest("linux-arm64")
+	assert.True(t, ok)
+	assert.Equal(t, "bbbb", digest)
+
+	// Editing the payload invalidates the signature
+	tampered := *env
+	forged := testStatement()
+	forged.Subject[0].Digest["sha256"] = "cccc"
+	forgedEnv, err := Seal(forged, nil)
+	require.NoError(t, err)
+	tampered.Payload = forgedEnv.Payload
+	_, err = signer.Verify(&tampered)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// Unsigned envelopes and envelopes signed by another key are not trusted
+	_, err = signer.Verify(forgedEnv)
+	assert.ErrorIs(t, err, ErrUnsigned)
+	other, err := Seal(testStatement(), newTestSigner(t))
+	require.NoError(t, err)
+	_, err = signer.Verify(other)
+	assert.ErrorIs(t, err, ErrUnsigned)
+}
+
+func TestPAE(t *testing.T) {
+	// Example from the DSSE specification
+	assert.Equal(t, "DSSEv1 29 http://example.com/HelloWorld 11 hello world",
+		string(pae("http://example.com/HelloWorld", []byte("hello world"))))
+}
+
+func TestLoadOrCreateSigner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "signing.pem")
+
+	created, err := LoadOrCreateSigner(path)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := LoadOrCreateSigner(path)
+	require.NoError(t, err)
+	assert.Equal(t, created.KeyID(), loaded.KeyID())
+
+	env, err := Seal(testStatement(), created)
+	require.NoError(t, err)
+	_, err = loaded.Verify(env)
+	assert.NoError(t, err)
+
+	pub, err := loaded.PublicKeyPEM()
+	require.NoError(t, err)
+	assert.Contains(t, string(pub), "BEGIN PUBLIC KEY")
+
+	require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString([]byte("junk"))), 0600))
+	_, err = LoadOrCreateSigner(path)
+	assert.Error(t, err)
+}
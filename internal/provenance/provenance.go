@@ -0,0 +1,141 @@
+// internal/provenance/provenance.go
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// In-toto and SLSA identifiers
+const (
+	StatementType     = "https://in-toto.io/Statement/v1"
+	PredicateType     = "https://slsa.dev/provenance/v1"
+	PayloadType       = "application/vnd.in-toto+json"
+	BuilderID         = "https://github.com/About80Ninjas/go_runner"
+	BuildTypeGo       = "https://github.com/About80Ninjas/go_runner/go-build@v1"
+	BuildTypeUploaded = "https://github.com/About80Ninjas/go_runner/prebuilt-upload@v1"
+)
+
+// Statement is an in-toto statement carrying SLSA provenance
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is an artifact the provenance describes
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate is the SLSA v1 provenance predicate
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition records the inputs of a build
+type BuildDefinition struct {
+	BuildType            string                 `json:"buildType"`
+	ExternalParameters   map[string]interface{} `json:"externalParameters"`
+	InternalParameters   map[string]interface{} `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor   `json:"resolvedDependencies,omitempty"`
+}
+
+// ResourceDescriptor identifies a build input such as the source commit
+type ResourceDescriptor struct {
+	URI    string            `json:"uri,omitempty"`
+	Name   string            `json:"name,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// RunDetails records who ran the build and when
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// Builder identifies the build platform
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// Metadata holds the timing of a build
+type Metadata struct {
+	InvocationID string    `json:"invocationId"`
+	StartedOn    time.Time `json:"startedOn"`
+	FinishedOn   time.Time `json:"finishedOn"`
+}
+
+// NewStatement returns a statement with the standard types and builder filled in
+func NewStatement(buildType, invocationID string, started, finished time.Time) *Statement {
+	return &Statement{
+		Type:          StatementType,
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			BuildDefinition: BuildDefinition{
+				BuildType:          buildType,
+				ExternalParameters: map[string]interface{}{},
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{ID: BuilderID},
+				Metadata: Metadata{
+					InvocationID: invocationID,
+					StartedOn:    started.UTC(),
+					FinishedOn:   finished.UTC(),
+				},
+			},
+		},
+	}
+}
+
+// AddSubject records an artifact by its SHA-256
+func (s *Statement) AddSubject(name, sha256 string) {
+	s.Subject = append(s.Subject, Subject{Name: name, Digest: map[string]string{"sha256": sha256}})
+}
+
+// SubjectDigest returns the SHA-256 recorded for the named subject
+func (s *Statement) SubjectDigest(name string) (string, bool) {
+	for _, subject := range s.Subject {
+		if subject.Name == name {
+			digest, ok := subject.Digest["sha256"]
+			return digest, ok
+		}
+	}
+	return "", false
+}
+
+// WriteFile stores an envelope as JSON, replacing path atomically
+func WriteFile(path string, env *Envelope) error {
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadFile loads an envelope written by WriteFile
+func ReadFile(path string) (*Envelope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid provenance envelope: %w", err)
+	}
+	return &env, nil
+}
@@ -0,0 +1,107 @@
+// internal/ratelimit/limiter.go
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have refilled are forgotten
+const sweepInterval = time.Minute
+
+// Limiter is a set of token buckets, one per key such as an API key ID or
+// client IP. Each holds up to burst tokens and refills at a steady rate; a
+// request takes one token.
+type Limiter struct {
+	rate  float64 // tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Decision is the outcome of taking a token, with what to report in
+// RateLimit-* headers
+type Decision struct {
+	Allowed    bool
+	Limit      int           // the burst
+	Remaining  int           // whole tokens left
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, when not allowed
+}
+
+// NewLimiter allows perMinute requests a minute per key, in bursts of up to
+// burst. It returns nil, which allows everything, when perMinute is not positive.
+func NewLimiter(perMinute float64, burst int) *Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    perMinute / 60,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket if it has one
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweepLocked(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	d := Decision{Limit: int(l.burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.wait(1 - b.tokens)
+	}
+	d.Remaining = int(math.Floor(b.tokens))
+	d.Reset = l.wait(l.burst - b.tokens)
+	return d
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+}
+
+// wait returns how long refilling tokens takes
+func (l *Limiter) wait(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweepLocked drops full buckets, which behave like new ones, so clients
+// that have gone away do not accumulate
+func (l *Limiter) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	l := NewLimiter(60, 3) // a token a second
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	for i := 2; i >= 0; i-- {
+		d := l.Allow("ci")
+		assert.True(t, d.Allowed)
+		assert.Equal(t, 3, d.Limit)
+		assert.Equal(t, i, d.Remaining)
+	}
+
+	d := l.Allow("ci")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, time.Second, d.RetryAfter)
+	assert.Equal(t, 3*time.Second, d.Reset)
+
+	// Other keys have their own bucket
+	assert.True(t, l.Allow("other").Allowed)
+
+	// Tokens come back at the configured rate, up to the burst
+	now = now.Add(1500 * time.Millisecond)
+	assert.True(t, l.Allow("ci").Allowed)
+	assert.False(t, l.Allow("ci").Allowed)
+	now = now.Add(time.Hour)
+	assert.Equal(t, 2, l.Allow("ci").Remaining)
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	l := NewLimiter(60, 2)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("b")
+	now = now.Add(sweepInterval + 1500*time.Millisecond)
+	l.Allow("c")
+	assert.Len(t, l.buckets, 1, "refilled buckets are dropped")
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	assert.Nil(t, NewLimiter(0, 10))
+}
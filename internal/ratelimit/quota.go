@@ -0,0 +1,133 @@
+// internal/ratelimit/quota.go
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go_runner/internal/models"
+)
+
+// QuotaStore counts executions and CPU time per API key in a 0600 JSON
+// file, so restarts do not reset quotas
+type QuotaStore struct {
+	path  string
+	now   func() time.Time
+	mu    sync.Mutex
+	usage map[string]*models.QuotaUsage
+}
+
+// NewQuotaStore creates a quota store under dir
+func NewQuotaStore(dir string) (*QuotaStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create quota directory: %w", err)
+	}
+	qs := &QuotaStore{
+		path:  filepath.Join(dir, "quotas.json"),
+		now:   time.Now,
+		usage: make(map[string]*models.QuotaUsage),
+	}
+	if err := qs.load(); err != nil {
+		return nil, err
+	}
+	return qs, nil
+}
+
+// Reserve counts an execution by keyID, unless that would go over q, in
+// which case it returns a *models.QuotaExceededError. CPU time is only known
+// afterwards, so a run may take a key over its CPU quota once.
+func (qs *QuotaStore) Reserve(keyID string, q models.Quota) (*models.QuotaUsage, error) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	now := qs.now()
+	u := qs.usageLocked(keyID, now)
+	if exceeded := q.Exceeded(u, now); exceeded != nil {
+		c := *u
+		return &c, exceeded
+	}
+
+	u.DayExecutions++
+	u.MonthExecutions++
+	if err := qs.save(); err != nil {
+		u.DayExecutions--
+		u.MonthExecutions--
+		return nil, err
+	}
+	c := *u
+	return &c, nil
+}
+
+// Release gives back an execution reserved by keyID that did not run
+func (qs *QuotaStore) Release(keyID string) error {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	u := qs.usageLocked(keyID, qs.now())
+	if u.DayExecutions > 0 {
+		u.DayExecutions--
+	}
+	if u.MonthExecutions > 0 {
+		u.MonthExecutions--
+	}
+	return qs.save()
+}
+
+// AddCPU counts CPU time used by an execution of keyID
+func (qs *QuotaStore) AddCPU(keyID string, cpu time.Duration) error {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	u := qs.usageLocked(keyID, qs.now())
+	u.DayCPUMillis += cpu.Milliseconds()
+	u.MonthCPUMillis += cpu.Milliseconds()
+	return qs.save()
+}
+
+// Usage returns what keyID has used in the current day and month
+func (qs *QuotaStore) Usage(keyID string) (*models.QuotaUsage, error) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	c := *qs.usageLocked(keyID, qs.now())
+	return &c, nil
+}
+
+func (qs *QuotaStore) usageLocked(keyID string, now time.Time) *models.QuotaUsage {
+	u, ok := qs.usage[keyID]
+	if !ok {
+		u = &models.QuotaUsage{}
+		qs.usage[keyID] = u
+	}
+	u.Roll(now)
+	return u
+}
+
+func (qs *QuotaStore) load() error {
+	data, err := os.ReadFile(qs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, &qs.usage)
+}
+
+// save must be called with the lock held
+func (qs *QuotaStore) save() error {
+	data, err := json.MarshalIndent(qs.usage, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := qs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, qs.path)
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"go_runner/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaStore_ExecutionsAndRestart(t *testing.T) {
+	dir := t.TempDir()
+	qs, err := NewQuotaStore(dir)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 30, 23, 0, 0, 0, time.UTC)
+	qs.now = func() time.Time { return now }
+	quota := models.Quota{DailyExecutions: 2, MonthlyExecutions: 3}
+
+	_, err = qs.Reserve("k1", quota)
+	require.NoError(t, err)
+	usage, err := qs.Reserve("k1", quota)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.DayExecutions)
+
+	info, err := os.Stat(qs.path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Counters survive a restart
+	qs, err = NewQuotaStore(dir)
+	require.NoError(t, err)
+	qs.now = func() time.Time { return now }
+	_, err = qs.Reserve("k1", quota)
+	var exceeded *models.QuotaExceededError
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, "daily_executions", exceeded.Limit)
+	assert.Equal(t, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), exceeded.Reset)
+
+	// Other keys are counted separately
+	_, err = qs.Reserve("k2", quota)
+	assert.NoError(t, err)
+
+	// A new day allows more, until the month runs out
+	now = now.Add(2 * time.Hour)
+	_, err = qs.Reserve("k1", quota)
+	assert.NoError(t, err)
+	_, err = qs.Reserve("k1", quota)
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, "monthly_executions", exceeded.Limit)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), exceeded.Reset)
+
+	// A new month resets both
+	now = time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)
+	usage, err = qs.Reserve("k1", quota)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-02", usage.Month)
+	assert.Equal(t, int64(1), usage.MonthExecutions)
+}
+
+func TestQuotaStore_CPUSeconds(t *testing.T) {
+	qs, err := NewQuotaStore(t.TempDir())
+	require.NoError(t, err)
+	quota := models.Quota{MonthlyCPUSeconds: 10}
+
+	_, err = qs.Reserve("k1", quota)
+	require.NoError(t, err)
+	require.NoError(t, qs.AddCPU("k1", 10*time.Second+500*time.Millisecond))
+
+	_, err = qs.Reserve("k1", quota)
+	var exceeded *models.QuotaExceededError
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, "monthly_cpu_seconds", exceeded.Limit)
+
+	usage, err := qs.Usage("k1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10500), usage.MonthCPUMillis)
+	assert.Equal(t, int64(1), usage.MonthExecutions, "refused executions are not counted")
+
+	// Unlimited quotas only count
+	_, err = qs.Reserve("k1", models.Quota{})
+	assert.NoError(t, err)
+}
+
+func TestQuotaStore_Release(t *testing.T) {
+	qs, err := NewQuotaStore(t.TempDir())
+	require.NoError(t, err)
+	quota := models.Quota{DailyExecutions: 1}
+
+	_, err = qs.Reserve("k1", quota)
+	require.NoError(t, err)
+	require.NoError(t, qs.Release("k1"))
+	usage, err := qs.Reserve("k1", quota)
+	require.NoError(t, err, "a released execution is not counted")
+	assert.Equal(t, int64(1), usage.DayExecutions)
+	assert.Equal(t, int64(1), usage.MonthExecutions)
+
+	// Counts never go below zero
+	require.NoError(t, qs.Release("k2"))
+	usage, err = qs.Usage("k2")
+	require.NoError(t, err)
+	assert.Zero(t, usage.DayExecutions)
+}
@@ -0,0 +1,195 @@
+// internal/repository/builder.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go_runner/internal/gocache"
+	"go_runner/internal/models"
+	"go_runner/internal/toolchain"
+	"go_runner/internal/upload"
+)
+
+// Builder compiles Go code checked out under basePath. It is shared by the git
+// backends, which only differ in how they fetch sources.
+type Builder struct {
+	basePath   string
+	toolchains *toolchain.Manager
+	cache      *gocache.Cache
+	gitTimeout time.Duration
+}
+
+// Option configures optional Builder dependencies
+type Option func(*Builder)
+
+// WithToolchains resolves the Go toolchain per build instead of using the go on PATH
+func WithToolchains(tm *toolchain.Manager) Option {
+	return func(b *Builder) {
+		b.toolchains = tm
+	}
+}
+
+// WithBuildCache points builds at a managed GOMODCACHE/GOCACHE
+func WithBuildCache(c *gocache.Cache) Option {
+	return func(b *Builder) {
+		b.cache = c
+	}
+}
+
+// WithGitTimeout bounds each clone/update and remote lookup
+func WithGitTimeout(d time.Duration) Option {
+	return func(b *Builder) {
+		b.gitTimeout = d
+	}
+}
+
+func newBuilder(basePath string, opts []Option) *Builder {
+	b := &Builder{
+		basePath: basePath,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// gitContext applies the configured git timeout to ctx
+func (b *Builder) gitContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.gitTimeout > 0 {
+		return context.WithTimeout(ctx, b.gitTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// ResolveToolchain selects the Go toolchain for a build, installing it if needed.
+// It returns nil when no toolchain manager is configured and the go on PATH is used.
+func (b *Builder) ResolveToolchain(repoPath, buildPath, goVersion string) (*models.Toolchain, error) {
+	if _, err := b.buildDir(repoPath, buildPath); err != nil {
+		return nil, err
+	}
+	if b.toolchains == nil {
+		return nil, nil
+	}
+	return b.toolchains.Resolve(filepath.Join(b.basePath, repoPath), buildPath, goVersion)
+}
+
+// buildDir returns the directory of buildPath in the checkout at repoPath.
+// Symlinks are resolved, so that a repository cannot point the build at
+// files outside its checkout.
+func (b *Builder) buildDir(repoPath, buildPath string) (string, error) {
+	root, err := filepath.EvalSymlinks(filepath.Join(b.basePath, repoPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve checkout: %w", err)
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Join(root, buildPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve build path: %w", err)
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("build path %q resolves outside the repository", buildPath)
+	}
+	return dir, nil
+}
+
+// ExtractSource replaces the checkout at repoPath with the contents of an
+// uploaded tar.gz or zip archive
+func (b *Builder) ExtractSource(repoPath, archivePath string) error {
+	return upload.Extract(archivePath, filepath.Join(b.basePath, repoPath))
+}
+
+// RemoveCheckout deletes the working copy at repoPath
+func (b *Builder) RemoveCheckout(repoPath string) error {
+	if repoPath == "." || repoPath == ".." || filepath.Base(repoPath) != repoPath {
+		return fmt.Errorf("invalid checkout path %q", repoPath)
+	}
+	return os.RemoveAll(filepath.Join(b.basePath, repoPath))
+}
+
+// BuildGoBinary builds a Go binary from the repository
+func (b *Builder) BuildGoBinary(repoPath, buildPath, outputPath string, tc *models.Toolchain, opts models.BuildOptions) error {
+	fullBuildPath, err := b.buildDir(repoPath, buildPath)
+	if err != nil {
+		return err
+	}
+
+	// Ensure output directory exists
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Build the binary
+	cmd := exec.Command(goCommand(tc), buildArgs(outputPath, opts)...)
+	cmd.Dir = fullBuildPath
+	cmd.Env = append(os.Environ(), buildEnv(opts)...)
+	if tc != nil {
+		// Never let the go command switch to yet another toolchain on its own
+		cmd.Env = append(cmd.Env, "GOTOOLCHAIN=local")
+	}
+	if b.cache != nil {
+		cmd.Env = append(cmd.Env, b.cache.Env()...)
+		release := b.cache.Acquire()
+		defer b.cache.TrimAsync()
+		defer release()
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("go build failed: %w\nOutput: %s", err, string(output))
+	}
+
+	// Make binary executable
+	if err := os.Chmod(outputPath, 0755); err != nil {
+		return fmt.Errorf("failed to set executable permissions: %w", err)
+	}
+
+	return nil
+}
+
+// goCommand returns the go binary of a resolved toolchain
+func goCommand(tc *models.Toolchain) string {
+	if tc == nil || tc.GOROOT == "" {
+		return "go"
+	}
+	return filepath.Join(tc.GOROOT, "bin", "go")
+}
+
+// buildArgs assembles the go build arguments. Options must already be validated.
+func buildArgs(outputPath string, opts models.BuildOptions) []string {
+	args := []string{"build", "-o", outputPath}
+	if len(opts.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(opts.Tags, ","))
+	}
+	if opts.LDFlags != "" {
+		args = append(args, "-ldflags", opts.LDFlags)
+	}
+	if opts.TrimPath {
+		args = append(args, "-trimpath")
+	}
+	if opts.Race {
+		args = append(args, "-race")
+	}
+	return append(args, ".")
+}
+
+// buildEnv returns the environment overrides for a build
+func buildEnv(opts models.BuildOptions) []string {
+	cgo := "CGO_ENABLED=0"
+	if opts.CGOEnabled || opts.Race {
+		cgo = "CGO_ENABLED=1"
+	}
+	env := []string{cgo}
+	if opts.GOOS != "" {
+		env = append(env, "GOOS="+opts.GOOS)
+	}
+	if opts.GOARCH != "" {
+		env = append(env, "GOARCH="+opts.GOARCH)
+	}
+	return env
+}
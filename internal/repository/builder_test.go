@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/models"
+)
+
+func TestBuilder_BuildPathSymlinks(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	writeModule(t, filepath.Join(base, "repo_links", "cmd", "app"), map[string]string{"main.go": "package main\n"})
+	require.NoError(t, os.Symlink("../../..", filepath.Join(base, "repo_links", "escape")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(base, "repo_links", "abs")))
+	require.NoError(t, os.Symlink("cmd/app", filepath.Join(base, "repo_links", "app")))
+
+	b := newBuilder(base, nil)
+	for _, buildPath := range []string{"escape", "abs", "cmd/../abs"} {
+		_, err := b.ResolveToolchain("repo_links", buildPath, "")
+		assert.ErrorContains(t, err, "outside the repository", buildPath)
+
+		err = b.BuildGoBinary("repo_links", buildPath, filepath.Join(t.TempDir(), "bin"), nil, models.BuildOptions{})
+		assert.ErrorContains(t, err, "outside the repository", buildPath)
+
+		results := b.RunGates(context.Background(), "repo_links", buildPath, nil, models.BuildOptions{}, models.Gates{Vet: true})
+		require.Len(t, results, 1)
+		assert.False(t, results[0].Passed())
+	}
+
+	// Links that stay inside the checkout are followed
+	dir, err := b.buildDir("repo_links", "app")
+	require.NoError(t, err)
+	want, _ := filepath.EvalSymlinks(filepath.Join(base, "repo_links", "cmd", "app"))
+	assert.Equal(t, want, dir)
+}
@@ -0,0 +1,97 @@
+// internal/repository/errors.go
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error kinds shared by all git backends. Use errors.Is to test for them.
+var (
+	ErrAuthFailed         = errors.New("authentication failed")
+	ErrRepoNotFound       = errors.New("repository not found")
+	ErrRefNotFound        = errors.New("ref not found")
+	ErrNetworkUnreachable = errors.New("remote unreachable")
+	ErrTimeout            = errors.New("operation timed out")
+)
+
+// GitError describes a failed git operation
+type GitError struct {
+	Op     string // clone, fetch, checkout, ...
+	Kind   error  // one of the Err* kinds above, or nil if unclassified
+	Err    error
+	Output string // redacted command output, CLI backend only
+}
+
+func (e *GitError) Error() string {
+	msg := fmt.Sprintf("git %s failed", e.Op)
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+	msg += fmt.Sprintf(": %v", e.Err)
+	if e.Output != "" {
+		msg += "\nOutput: " + e.Output
+	}
+	return redactURLs(msg)
+}
+
+func (e *GitError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// cliErrorPatterns maps git CLI messages to error kinds. Order matters: the
+// generic "Could not read from remote repository" follows auth failures over SSH.
+var cliErrorPatterns = []struct {
+	kind     error
+	patterns []string
+}{
+	{ErrAuthFailed, []string{
+		"authentication failed",
+		"could not read username",
+		"could not read password",
+		"terminal prompts disabled",
+		"permission denied (publickey",
+		"host key verification failed",
+		"http basic: access denied",
+		"the requested url returned error: 401",
+		"the requested url returned error: 403",
+	}},
+	{ErrRepoNotFound, []string{
+		"repository not found",
+		"does not appear to be a git repository",
+		"the requested url returned error: 404",
+	}},
+	{ErrRefNotFound, []string{
+		"remote branch",
+		"couldn't find remote ref",
+		"did not match any file(s) known to git",
+		"unknown revision",
+		"invalid reference",
+	}},
+	{ErrNetworkUnreachable, []string{
+		"could not resolve host",
+		"connection refused",
+		"connection timed out",
+		"network is unreachable",
+		"no route to host",
+		"failed to connect",
+		"could not read from remote repository",
+	}},
+}
+
+// classifyOutput guesses the error kind from git CLI output
+func classifyOutput(output string) error {
+	lower := strings.ToLower(output)
+	for _, c := range cliErrorPatterns {
+		for _, p := range c.patterns {
+			if strings.Contains(lower, p) {
+				return c.kind
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// internal/repository/gates.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go_runner/internal/models"
+)
+
+// defaultGateTimeout applies to gates that do not set their own timeout
+const defaultGateTimeout = 10 * time.Minute
+
+// maxGateOutput is how much of the end of a gate's output is kept on the build record
+const maxGateOutput = 16 << 10
+
+// RunGates runs the enabled gates in the build path and returns one result per
+// gate. Every gate runs even if an earlier one failed, so a single build
+// reports all problems at once.
+func (b *Builder) RunGates(ctx context.Context, repoPath, buildPath string, tc *models.Toolchain, opts models.BuildOptions, gates models.Gates) []models.GateResult {
+	dir, err := b.buildDir(repoPath, buildPath)
+	if err != nil {
+		return []models.GateResult{{Name: "build_path", Status: "failed", ExitCode: -1, Output: err.Error()}}
+	}
+
+	// Gates run on the host, so drop any cross-compilation target
+	opts.GOOS, opts.GOARCH = "", ""
+	env := append(os.Environ(), buildEnv(opts)...)
+	if tc != nil {
+		env = append(env, "GOTOOLCHAIN=local")
+		if tc.GOROOT != "" {
+			// Custom checks that call go should get the build's toolchain
+			env = append(env, "PATH="+filepath.Join(tc.GOROOT, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+		}
+	}
+	if b.cache != nil {
+		env = append(env, b.cache.Env()...)
+		release := b.cache.Acquire()
+		defer release()
+	}
+
+	var results []models.GateResult
+	if gates.Vet {
+		argv := append([]string{goCommand(tc), "vet"}, tagArgs(opts)...)
+		results = append(results, runGate(ctx, "vet", dir, env, defaultGateTimeout, append(argv, "./...")))
+	}
+	if gates.Test {
+		timeout := gateTimeout(gates.TestTimeout)
+		argv := append([]string{goCommand(tc), "test"}, tagArgs(opts)...)
+		if opts.Race {
+			argv = append(argv, "-race")
+		}
+		// Let the test binary fail with a stack dump before the gate is killed
+		argv = append(argv, "-timeout", timeout.String(), "./...")
+		results = append(results, runGate(ctx, "test", dir, env, timeout+waitDelay, argv))
+	}
+	for _, check := range gates.Checks {
+		results = append(results, runGate(ctx, check.Name, dir, env, gateTimeout(check.Timeout), check.Command))
+	}
+	return results
+}
+
+// runGate runs a single gate command and records its outcome
+func runGate(ctx context.Context, name, dir string, env []string, timeout time.Duration, argv []string) models.GateResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output := &tailBuffer{max: maxGateOutput}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Test binaries and other children may outlive a killed command and hold its pipes
+	cmd.WaitDelay = waitDelay
+
+	start := time.Now()
+	err := cmd.Run()
+	result := models.GateResult{
+		Name:     name,
+		Status:   "passed",
+		Duration: time.Since(start).Milliseconds(),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Status = "timeout"
+		result.ExitCode = -1
+		fmt.Fprintf(output, "\ngate timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		result.Status = "failed"
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		result.Status = "failed"
+		result.ExitCode = -1
+		fmt.Fprintf(output, "\n%v", err)
+	}
+	result.Output = output.String()
+	return result
+}
+
+// gateTimeout converts a timeout in seconds, falling back to the default
+func gateTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		return defaultGateTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// tagArgs returns the -tags flag for opts, if any
+func tagArgs(opts models.BuildOptions) []string {
+	if len(opts.Tags) == 0 {
+		return nil
+	}
+	return []string{"-tags", strings.Join(opts.Tags, ",")}
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	max       int
+	buf       []byte
+	truncated bool
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+		t.truncated = true
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	if t.truncated {
+		return "...\n" + string(t.buf)
+	}
+	return string(t.buf)
+}
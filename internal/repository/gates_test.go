@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/models"
+)
+
+func writeModule(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestRunGates(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+
+	base := t.TempDir()
+	writeModule(t, filepath.Join(base, "repo_gates"), map[string]string{
+		"go.mod":       "module example.com/gates\n\ngo 1.21\n",
+		"main.go":      "package main\n\nfunc main() {\n\treturn\n\tprintln()\n}\n",
+		"main_test.go": "package main\n\nimport \"testing\"\n\nfunc TestFails(t *testing.T) { t.Fatal(\"broken\") }\n",
+	})
+
+	b := newBuilder(base, nil)
+	results := b.RunGates(context.Background(), "repo_gates", ".", nil, models.BuildOptions{}, models.Gates{
+		Vet:  true,
+		Test: true,
+		Checks: []models.Check{
+			{Name: "ok", Command: []string{"true"}},
+			{Name: "exit-3", Command: []string{"sh", "-c", "echo lint error; exit 3"}},
+			{Name: "slow", Command: []string{"sleep", "10"}, Timeout: 1},
+		},
+	})
+
+	byName := make(map[string]models.GateResult)
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+	require.Len(t, byName, 5)
+
+	assert.Equal(t, "failed", byName["vet"].Status)
+	assert.Contains(t, byName["vet"].Output, "unreachable code")
+	assert.Equal(t, "failed", byName["test"].Status)
+	assert.Contains(t, byName["test"].Output, "broken")
+	assert.True(t, byName["ok"].Passed())
+	assert.Equal(t, 3, byName["exit-3"].ExitCode)
+	assert.Contains(t, byName["exit-3"].Output, "lint error")
+	assert.Equal(t, "timeout", byName["slow"].Status)
+}
+
+func TestTailBuffer(t *testing.T) {
+	buf := &tailBuffer{max: 8}
+	buf.Write([]byte("hello "))
+	assert.Equal(t, "hello ", buf.String())
+
+	buf.Write([]byte(strings.Repeat("x", 4) + "world"))
+	assert.Equal(t, "...\nxxxworld", buf.String())
+}
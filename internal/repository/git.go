@@ -2,117 +2,279 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"go_runner/internal/models"
 )
 
-// GitManager handles Git repository operations
+// waitDelay is how long a killed git command may take to release its output
+const waitDelay = 5 * time.Second
+
+// GitManager handles Git repository operations by shelling out to the git CLI.
+// Each repository URL is fetched into a single bare mirror, and every binary
+// gets a detached worktree of that mirror.
 type GitManager struct {
-	basePath string
+	*Builder
+	mirrors *mirrorSet
 }
 
 // NewGitManager creates a new Git manager
-func NewGitManager(basePath string) *GitManager {
+func NewGitManager(basePath string, opts ...Option) *GitManager {
 	return &GitManager{
-		basePath: basePath,
+		Builder: newBuilder(basePath, opts),
+		mirrors: newMirrorSet(basePath),
 	}
 }
 
-// CloneOrUpdate clones a repository or updates it if it exists
-func (gm *GitManager) CloneOrUpdate(repoURL, branch, targetPath string) error {
-	fullPath := filepath.Join(gm.basePath, targetPath)
+// CloneOrUpdate fetches the branch into the mirror for repoURL and checks it out
+// into targetPath, honoring the depth, filter, sparse and submodule options
+func (gm *GitManager) CloneOrUpdate(ctx context.Context, repoURL, branch, targetPath, buildPath string, opts models.CloneOptions, cred *models.Credential) error {
+	auth, err := newGitAuth(cred)
+	if err != nil {
+		return err
+	}
+	defer auth.Close()
 
-	// Check if repo exists
-	if _, err := os.Stat(filepath.Join(fullPath, ".git")); err == nil {
-		// Repository exists, update it
-		return gm.updateRepo(fullPath, branch)
+	mirror, err := gm.mirrors.path(repoURL)
+	if err != nil {
+		return err
+	}
+	worktree, err := filepath.Abs(filepath.Join(gm.basePath, targetPath))
+	if err != nil {
+		return err
 	}
 
-	// Clone the repository
-	return gm.cloneRepo(repoURL, branch, fullPath)
+	ctx, cancel := gm.gitContext(ctx)
+	defer cancel()
+
+	unlock := gm.mirrors.lock(mirror)
+	defer unlock()
+
+	commit, err := gm.fetchMirror(ctx, mirror, repoURL, branch, opts, auth)
+	if err != nil {
+		return err
+	}
+
+	return gm.checkout(ctx, mirror, worktree, commit, buildPath, opts, auth)
 }
 
-func (gm *GitManager) cloneRepo(repoURL, branch, targetPath string) error {
-	// Ensure parent directory exists
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// fetchMirror creates the mirror if needed, fetches the branch into it and
+// returns the fetched commit
+func (gm *GitManager) fetchMirror(ctx context.Context, mirror, repoURL, branch string, opts models.CloneOptions, auth *gitAuth) (string, error) {
+	if _, err := os.Stat(mirror); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(mirror), 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := runGit(ctx, "", auth, "init", "--quiet", "--bare", mirror); err != nil {
+			return "", err
+		}
+		if err := runGit(ctx, mirror, auth, "remote", "add", "origin", repoURL); err != nil {
+			os.RemoveAll(mirror)
+			return "", err
+		}
 	}
 
-	cmd := exec.Command("git", "clone", "-b", branch, repoURL, targetPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git clone failed: %w\nOutput: %s", err, string(output))
+	args := []string{"fetch", "--quiet", "--no-tags"}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	} else if _, err := os.Stat(filepath.Join(mirror, "shallow")); err == nil {
+		// A previous shallow fetch must be deepened for a full-history build
+		args = append(args, "--unshallow")
+	}
+	if opts.Filter != "" {
+		args = append(args, "--filter="+opts.Filter)
 	}
+	ref := "refs/heads/" + branch
+	args = append(args, "origin", "+"+ref+":"+ref)
 
-	return nil
+	if err := runGit(ctx, mirror, auth, args...); err != nil {
+		return "", err
+	}
+	return gitOutput(ctx, mirror, auth, "rev-parse", "--verify", ref+"^{commit}")
 }
 
-func (gm *GitManager) updateRepo(repoPath, branch string) error {
-	// Fetch latest changes
-	cmd := exec.Command("git", "fetch", "origin")
-	cmd.Dir = repoPath
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git fetch failed: %w\nOutput: %s", err, string(output))
+// checkout points the binary's worktree at commit, creating the worktree if it
+// does not exist or belongs to a different mirror
+func (gm *GitManager) checkout(ctx context.Context, mirror, worktree, commit, buildPath string, opts models.CloneOptions, auth *gitAuth) error {
+	if !isWorktreeOf(worktree, mirror) {
+		// Replaces checkouts from before mirrors were used and worktrees of a
+		// previous repository URL
+		if err := os.RemoveAll(worktree); err != nil {
+			return fmt.Errorf("failed to remove old checkout: %w", err)
+		}
+		if err := runGit(ctx, mirror, auth, "worktree", "prune"); err != nil {
+			return err
+		}
+		if err := runGit(ctx, mirror, auth, "worktree", "add", "--quiet", "--detach", "--no-checkout", worktree, commit); err != nil {
+			return err
+		}
 	}
 
-	// Checkout branch
-	cmd = exec.Command("git", "checkout", branch)
-	cmd.Dir = repoPath
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git checkout failed: %w\nOutput: %s", err, string(output))
+	var dirs []string
+	if opts.Sparse {
+		dirs = sparseDirs(buildPath, opts.SparsePaths, func(name string) bool {
+			out, err := gitOutput(ctx, mirror, auth, "ls-tree", "--name-only", commit, "--", name)
+			return err == nil && out != ""
+		})
+	}
+	if len(dirs) > 0 {
+		args := append([]string{"sparse-checkout", "set", "--cone", "--"}, dirs...)
+		if err := runGit(ctx, worktree, auth, args...); err != nil {
+			return err
+		}
+	} else if err := runGit(ctx, worktree, auth, "sparse-checkout", "disable"); err != nil {
+		return err
 	}
 
-	// Pull latest changes
-	cmd = exec.Command("git", "pull", "origin", branch)
-	cmd.Dir = repoPath
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git pull failed: %w\nOutput: %s", err, string(output))
+	// Hard reset rather than pull, so force-pushes and branch switches apply
+	// cleanly, then drop anything a previous build left behind
+	if err := runGit(ctx, worktree, auth, "reset", "--quiet", "--hard", commit); err != nil {
+		return err
+	}
+	if err := runGit(ctx, worktree, auth, "clean", "-ffdxq"); err != nil {
+		return err
 	}
 
+	if opts.Submodules {
+		if err := runGit(ctx, worktree, auth, "submodule", "update", "--quiet", "--init", "--recursive", "--force"); err != nil {
+			return err
+		}
+		return runGit(ctx, worktree, auth, "submodule", "foreach", "--quiet", "--recursive", "git", "clean", "-ffdxq")
+	}
 	return nil
 }
 
-// GetCommitHash returns the current commit hash
-func (gm *GitManager) GetCommitHash(repoPath string) (string, error) {
-	fullPath := filepath.Join(gm.basePath, repoPath)
+// RemoveCheckout deletes the worktree at repoPath and its entry in the
+// mirror, which would otherwise keep the worktree's objects from being
+// garbage collected
+func (gm *GitManager) RemoveCheckout(repoPath string) error {
+	worktree, err := filepath.Abs(filepath.Join(gm.basePath, repoPath))
+	if err != nil {
+		return err
+	}
+	mirror := worktreeMirror(worktree)
+	if err := gm.Builder.RemoveCheckout(repoPath); err != nil || mirror == "" {
+		return err
+	}
 
-	cmd := exec.Command("git", "rev-parse", "HEAD")
-	cmd.Dir = fullPath
-	output, err := cmd.Output()
+	unlock := gm.mirrors.lock(mirror)
+	defer unlock()
+	auth, _ := newGitAuth(nil)
+	ctx, cancel := gm.gitContext(context.Background())
+	defer cancel()
+	return runGit(ctx, mirror, auth, "worktree", "prune")
+}
+
+// worktreeMirror returns the mirror dir is a worktree of, or "" if it is not
+// a worktree of any mirror
+func worktreeMirror(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, ".git"))
 	if err != nil {
-		return "", fmt.Errorf("failed to get commit hash: %w", err)
+		return ""
 	}
+	gitdir := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+	mirror, _, found := strings.Cut(gitdir, string(filepath.Separator)+"worktrees"+string(filepath.Separator))
+	if !found {
+		return ""
+	}
+	return mirror
+}
+
+// isWorktreeOf reports whether dir is a worktree linked to mirror
+func isWorktreeOf(dir, mirror string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, ".git"))
+	if err != nil {
+		return false
+	}
+	gitdir := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+	return strings.HasPrefix(gitdir, filepath.Join(mirror, "worktrees")+string(filepath.Separator))
+}
+
+// runGit runs a git subcommand in dir, keeping secrets out of the returned error
+func runGit(ctx context.Context, dir string, auth *gitAuth, args ...string) error {
+	_, err := gitOutput(ctx, dir, auth, args...)
+	return err
+}
+
+// gitOutput runs a git subcommand in dir and returns its trimmed stdout
+func gitOutput(ctx context.Context, dir string, auth *gitAuth, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), auth.env...)
+	// Helpers such as git-remote-https may outlive a killed git and hold its pipes
+	cmd.WaitDelay = waitDelay
 
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", newGitError(ctx, args[0], err, auth.redact(stderr.String()))
+	}
 	return strings.TrimSpace(string(output)), nil
 }
 
-// BuildGoBinary builds a Go binary from the repository
-func (gm *GitManager) BuildGoBinary(repoPath, buildPath, outputPath string) error {
-	fullRepoPath := filepath.Join(gm.basePath, repoPath)
-	fullBuildPath := filepath.Join(fullRepoPath, buildPath)
+// newGitError wraps a failed git command, preferring the context error when
+// the command was killed by a timeout or cancellation
+func newGitError(ctx context.Context, op string, err error, output string) *GitError {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return &GitError{Op: op, Kind: ErrTimeout, Err: ctx.Err()}
+	case context.Canceled:
+		return &GitError{Op: op, Err: ctx.Err()}
+	}
+	return &GitError{Op: op, Kind: classifyOutput(output), Err: err, Output: output}
+}
 
-	// Ensure output directory exists
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+// RemoteHead returns the commit the remote branch points to, without cloning
+func (gm *GitManager) RemoteHead(ctx context.Context, repoURL, branch string, cred *models.Credential) (string, error) {
+	auth, err := newGitAuth(cred)
+	if err != nil {
+		return "", err
 	}
+	defer auth.Close()
 
-	// Build the binary
-	cmd := exec.Command("go", "build", "-o", outputPath, ".")
-	cmd.Dir = fullBuildPath
-	cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	ctx, cancel := gm.gitContext(ctx)
+	defer cancel()
 
-	output, err := cmd.CombinedOutput()
+	output, err := gitOutput(ctx, "", auth, "ls-remote", "--exit-code", repoURL, "refs/heads/"+branch)
 	if err != nil {
-		return fmt.Errorf("go build failed: %w\nOutput: %s", err, string(output))
+		// --exit-code exits with 2 when the remote has no matching ref
+		var gitErr *GitError
+		var exitErr *exec.ExitError
+		if errors.As(err, &gitErr) && errors.As(gitErr.Err, &exitErr) && exitErr.ExitCode() == 2 {
+			gitErr.Kind = ErrRefNotFound
+		}
+		return "", err
 	}
 
-	// Make binary executable
-	if err := os.Chmod(outputPath, 0755); err != nil {
-		return fmt.Errorf("failed to set executable permissions: %w", err)
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return "", &GitError{Op: "ls-remote", Kind: ErrRefNotFound, Err: fmt.Errorf("no ref for branch %s", branch)}
 	}
+	return fields[0], nil
+}
 
-	return nil
+// GetCommitHash returns the current commit hash
+func (gm *GitManager) GetCommitHash(ctx context.Context, repoPath string) (string, error) {
+	fullPath := filepath.Join(gm.basePath, repoPath)
+
+	ctx, cancel := gm.gitContext(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
+	cmd.Dir = fullPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit hash: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
 }
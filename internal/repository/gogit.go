@@ -0,0 +1,463 @@
+// internal/repository/gogit.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go_runner/internal/models"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// GoGitManager handles Git repository operations with the pure-Go go-git
+// library, so the git CLI does not need to be installed. Each repository URL
+// is fetched into a single bare mirror that binaries clone from locally.
+type GoGitManager struct {
+	*Builder
+	mirrors *mirrorSet
+}
+
+// NewGoGitManager creates a new go-git based manager
+func NewGoGitManager(basePath string, opts ...Option) *GoGitManager {
+	return &GoGitManager{
+		Builder: newBuilder(basePath, opts),
+		mirrors: newMirrorSet(basePath),
+	}
+}
+
+// CloneOrUpdate fetches the branch into the mirror for repoURL and checks it
+// out into targetPath. go-git has no partial clone support, so opts.Filter is
+// ignored.
+func (gm *GoGitManager) CloneOrUpdate(ctx context.Context, repoURL, branch, targetPath, buildPath string, opts models.CloneOptions, cred *models.Credential) error {
+	auth, cleanup, err := goGitAuth(cred)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	mirror, err := gm.mirrors.path(repoURL)
+	if err != nil {
+		return err
+	}
+	fullPath := filepath.Join(gm.basePath, targetPath)
+
+	ctx, cancel := gm.gitContext(ctx)
+	defer cancel()
+
+	unlock := gm.mirrors.lock(mirror)
+	defer unlock()
+
+	if err := gm.fetchMirror(ctx, mirror, repoURL, branch, opts, auth); err != nil {
+		return err
+	}
+
+	repo, err := git.PlainOpen(fullPath)
+	if err == nil && !isCloneOf(repo, mirror) {
+		// Replaces checkouts from before mirrors were used and clones of a
+		// previous repository URL
+		if err := os.RemoveAll(fullPath); err != nil {
+			return fmt.Errorf("failed to remove old checkout: %w", err)
+		}
+		err = git.ErrRepositoryNotExists
+	}
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		repo, err = git.PlainInit(fullPath, false)
+		if err == nil {
+			_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{mirror}})
+		}
+		if err != nil {
+			os.RemoveAll(fullPath)
+			return goGitError("clone", err)
+		}
+	} else if err != nil {
+		return goGitError("open", err)
+	}
+
+	return gm.updateRepo(ctx, repo, branch, buildPath, opts, auth)
+}
+
+// fetchMirror creates the mirror if needed and fetches the branch into it
+func (gm *GoGitManager) fetchMirror(ctx context.Context, mirror, repoURL, branch string, opts models.CloneOptions, auth transport.AuthMethod) error {
+	repo, err := git.PlainOpen(mirror)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		if err := os.MkdirAll(filepath.Dir(mirror), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		repo, err = git.PlainInit(mirror, true)
+		if err == nil {
+			_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{repoURL}})
+		}
+		if err != nil {
+			os.RemoveAll(mirror)
+			return goGitError("clone", err)
+		}
+	} else if err != nil {
+		return goGitError("open", err)
+	}
+
+	ref := plumbing.NewBranchReferenceName(branch)
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("+%s:%s", ref, ref))},
+		Depth:      opts.Depth,
+		Auth:       auth,
+		Tags:       git.NoTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return goGitError("fetch", err)
+	}
+	return nil
+}
+
+// updateRepo fetches the branch from the local mirror, hard resets to it and
+// removes untracked and ignored files
+func (gm *GoGitManager) updateRepo(ctx context.Context, repo *git.Repository, branch, buildPath string, opts models.CloneOptions, auth transport.AuthMethod) error {
+	remoteRef := plumbing.NewRemoteReferenceName("origin", branch)
+	refSpec := gitconfig.RefSpec(fmt.Sprintf("+%s:%s", plumbing.NewBranchReferenceName(branch), remoteRef))
+
+	err := repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []gitconfig.RefSpec{refSpec},
+		Depth:      opts.Depth,
+		Tags:       git.NoTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return goGitError("fetch", err)
+	}
+
+	ref, err := repo.Reference(remoteRef, true)
+	if err != nil {
+		return goGitError("fetch", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return goGitError("checkout", err)
+	}
+
+	checkout := &git.CheckoutOptions{Hash: ref.Hash(), Force: true}
+	if opts.Sparse {
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return goGitError("checkout", err)
+		}
+		tree, err := commit.Tree()
+		if err != nil {
+			return goGitError("checkout", err)
+		}
+		checkout.SparseCheckoutDirectories = goGitSparseDirs(tree, buildPath, opts.SparsePaths)
+	}
+	// A previous sparse checkout leaves skip-worktree entries that a reset
+	// would not materialize, so start again from an empty index
+	if idx, err := repo.Storer.Index(); err == nil && hasSkipWorktree(idx) {
+		if err := repo.Storer.SetIndex(&index.Index{Version: 2}); err != nil {
+			return goGitError("checkout", err)
+		}
+	}
+	if err := wt.Checkout(checkout); err != nil {
+		return goGitError("checkout", err)
+	}
+	if err := removeUntracked(repo); err != nil {
+		return goGitError("clean", err)
+	}
+
+	if opts.Submodules {
+		subs, err := wt.Submodules()
+		if err != nil {
+			return goGitError("submodule", err)
+		}
+		if err := cleanSubmodules(subs); err != nil {
+			return goGitError("submodule", err)
+		}
+		if err := subs.UpdateContext(ctx, &git.SubmoduleUpdateOptions{Init: true, RecurseSubmodules: git.DefaultSubmoduleRecursionDepth, Auth: auth}); err != nil {
+			return goGitError("submodule", err)
+		}
+	}
+	return nil
+}
+
+// goGitSparseDirs adapts sparseDirs to go-git, which matches checkout entries
+// by path prefix, so the files at the repository root are listed explicitly
+func goGitSparseDirs(tree *object.Tree, buildPath string, extra []string) []string {
+	hasFile := func(name string) bool {
+		_, err := tree.File(name)
+		return err == nil
+	}
+	sparse := sparseDirs(buildPath, extra, hasFile)
+	if sparse == nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, e := range tree.Entries {
+		if e.Mode.IsFile() {
+			dirs = append(dirs, e.Name)
+		}
+	}
+	for _, d := range sparse {
+		dirs = append(dirs, d+"/")
+	}
+	return dirs
+}
+
+func hasSkipWorktree(idx *index.Index) bool {
+	for _, e := range idx.Entries {
+		if e.SkipWorktree {
+			return true
+		}
+	}
+	return false
+}
+
+// removeUntracked deletes everything in the worktree of repo that is not in
+// its index. Unlike Worktree.Clean it also removes ignored files, matching
+// git clean -ffdx in the CLI backend.
+func removeUntracked(repo *git.Repository) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	tracked := make(map[string]bool, len(idx.Entries))
+	parents := make(map[string]bool)
+	for _, e := range idx.Entries {
+		name := filepath.FromSlash(e.Name)
+		tracked[name] = true
+		for dir := filepath.Dir(name); dir != "."; dir = filepath.Dir(dir) {
+			parents[dir] = true
+		}
+	}
+
+	root := wt.Filesystem.Root()
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case rel == "." || parents[rel]:
+			return nil
+		case rel == ".git" || tracked[rel]:
+			// Submodule checkouts are tracked as a single entry and cleaned
+			// on their own by cleanSubmodules
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// cleanSubmodules hard resets each checked out submodule and the submodules
+// nested in it, then removes their untracked files. go-git refuses to update
+// a dirty submodule, so this stands in for git submodule update --force
+// followed by git submodule foreach --recursive git clean.
+func cleanSubmodules(subs git.Submodules) error {
+	for _, sub := range subs {
+		status, err := sub.Status()
+		if err != nil {
+			return err
+		}
+		if status.Current.IsZero() {
+			continue
+		}
+		repo, err := sub.Repository()
+		if err != nil {
+			return err
+		}
+		wt, err := repo.Worktree()
+		if err != nil {
+			return err
+		}
+		if err := wt.Reset(&git.ResetOptions{Commit: status.Current, Mode: git.HardReset}); err != nil {
+			return err
+		}
+		if err := removeUntracked(repo); err != nil {
+			return err
+		}
+		nested, err := wt.Submodules()
+		if err != nil {
+			return err
+		}
+		if err := cleanSubmodules(nested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isCloneOf reports whether repo was cloned from mirror
+func isCloneOf(repo *git.Repository, mirror string) bool {
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return false
+	}
+	urls := remote.Config().URLs
+	return len(urls) == 1 && urls[0] == mirror
+}
+
+// GetCommitHash returns the current commit hash
+func (gm *GoGitManager) GetCommitHash(ctx context.Context, repoPath string) (string, error) {
+	repo, err := git.PlainOpen(filepath.Join(gm.basePath, repoPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit hash: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit hash: %w", err)
+	}
+	return head.Hash().String(), nil
+}
+
+// RemoteHead returns the commit the remote branch points to, without cloning
+func (gm *GoGitManager) RemoteHead(ctx context.Context, repoURL, branch string, cred *models.Credential) (string, error) {
+	auth, cleanup, err := goGitAuth(cred)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	ctx, cancel := gm.gitContext(ctx)
+	defer cancel()
+
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return "", goGitError("ls-remote", err)
+	}
+
+	want := plumbing.NewBranchReferenceName(branch)
+	for _, ref := range refs {
+		if ref.Name() == want {
+			return ref.Hash().String(), nil
+		}
+	}
+	return "", &GitError{Op: "ls-remote", Kind: ErrRefNotFound, Err: fmt.Errorf("no ref for branch %s", branch)}
+}
+
+// goGitAuth converts a stored credential into a go-git auth method
+func goGitAuth(cred *models.Credential) (transport.AuthMethod, func(), error) {
+	noop := func() {}
+	if cred == nil {
+		return nil, noop, nil
+	}
+
+	switch cred.Type {
+	case models.CredentialToken, models.CredentialBasic:
+		username := cred.Username
+		if username == "" {
+			username = "x-access-token"
+		}
+		return &githttp.BasicAuth{Username: username, Password: cred.Secret}, noop, nil
+
+	case models.CredentialSSH:
+		user := cred.Username
+		if user == "" {
+			user = "git"
+		}
+		keys, err := gitssh.NewPublicKeys(user, []byte(cred.Secret), "")
+		if err != nil {
+			return nil, noop, fmt.Errorf("invalid ssh key: %w", err)
+		}
+
+		// The known_hosts callback reads from a file, so pin the hosts via a temp file
+		f, err := os.CreateTemp("", "go_runner-known_hosts-")
+		if err != nil {
+			return nil, noop, err
+		}
+		cleanup := func() { os.Remove(f.Name()) }
+		_, err = f.WriteString(cred.KnownHosts)
+		f.Close()
+		if err != nil {
+			cleanup()
+			return nil, noop, err
+		}
+
+		callback, err := gitssh.NewKnownHostsCallback(f.Name())
+		if err != nil {
+			cleanup()
+			return nil, noop, fmt.Errorf("invalid known_hosts: %w", err)
+		}
+		keys.HostKeyCallback = callback
+		return keys, cleanup, nil
+
+	default:
+		return nil, noop, fmt.Errorf("unsupported credential type %q", cred.Type)
+	}
+}
+
+// goGitError classifies go-git errors into the shared error kinds
+func goGitError(op string, err error) error {
+	var kind error
+	var netErr net.Error
+	var urlErr *url.Error
+	var noMatch git.NoMatchingRefSpecError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		kind = ErrTimeout
+	case errors.Is(err, context.Canceled):
+	case errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrInvalidAuthMethod):
+		kind = ErrAuthFailed
+	case errors.Is(err, transport.ErrRepositoryNotFound):
+		kind = ErrRepoNotFound
+	case errors.Is(err, plumbing.ErrReferenceNotFound),
+		errors.As(err, &noMatch):
+		kind = ErrRefNotFound
+	case errors.As(err, &netErr), errors.As(err, &urlErr):
+		kind = ErrNetworkUnreachable
+	default:
+		// SSH handshake failures surface as plain errors
+		msg := strings.ToLower(err.Error())
+		switch {
+		case strings.Contains(msg, "unable to authenticate"),
+			strings.Contains(msg, "knownhosts"),
+			strings.Contains(msg, "key mismatch"):
+			kind = ErrAuthFailed
+		case strings.Contains(msg, "couldn't find remote ref"):
+			kind = ErrRefNotFound
+		}
+	}
+
+	return &GitError{Op: op, Kind: kind, Err: err}
+}
@@ -0,0 +1,74 @@
+// internal/repository/mirror.go
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// mirrorSet keeps one bare mirror per repository URL, shared by every binary
+// that builds from it, and serializes operations on each mirror
+type mirrorSet struct {
+	dir   string
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newMirrorSet(basePath string) *mirrorSet {
+	return &mirrorSet{
+		dir:   filepath.Join(basePath, "mirrors"),
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+// path returns the absolute location of the mirror for repoURL
+func (m *mirrorSet) path(repoURL string) (string, error) {
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(strings.TrimSpace(repoURL), "/")))
+	return filepath.Abs(filepath.Join(m.dir, hex.EncodeToString(sum[:16])+".git"))
+}
+
+// lock takes the lock for a mirror and returns its release function
+func (m *mirrorSet) lock(mirror string) func() {
+	m.mu.Lock()
+	l, ok := m.locks[mirror]
+	if !ok {
+		l = &sync.Mutex{}
+		m.locks[mirror] = l
+	}
+	m.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// sparseDirs returns the directories to check out for buildPath, or nil to
+// check out the whole repository. A module rooted below the repository root
+// is checked out whole, with the extra directories. A package of the root
+// module may import any other, so the repository is not made sparse.
+func sparseDirs(buildPath string, extra []string, hasFile func(name string) bool) []string {
+	module := "."
+	for d := cleanRepoPath(buildPath); d != "."; d = path.Dir(d) {
+		if hasFile(path.Join(d, "go.mod")) {
+			module = d
+			break
+		}
+	}
+	if module == "." {
+		return nil
+	}
+
+	dirs := []string{module}
+	for _, p := range extra {
+		dirs = append(dirs, cleanRepoPath(p))
+	}
+	return dirs
+}
+
+// cleanRepoPath normalizes a path within a repository to slash form without a ./ prefix
+func cleanRepoPath(p string) string {
+	return path.Clean(strings.TrimPrefix(filepath.ToSlash(p), "./"))
+}
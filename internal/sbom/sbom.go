@@ -0,0 +1,224 @@
+// internal/sbom/sbom.go
+package sbom
+
+import (
+	"debug/buildinfo"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MediaType is the content type of a CycloneDX JSON document
+const MediaType = "application/vnd.cyclonedx+json"
+
+// ErrNoBuildInfo is returned for executables that were not built by the go command
+var ErrNoBuildInfo = errors.New("executable has no Go build information")
+
+// BOM is a CycloneDX 1.5 software bill of materials
+type BOM struct {
+	BOMFormat    string       `json:"bomFormat"`
+	SpecVersion  string       `json:"specVersion"`
+	SerialNumber string       `json:"serialNumber"`
+	Version      int          `json:"version"`
+	Metadata     Metadata     `json:"metadata"`
+	Components   []Component  `json:"components"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// Metadata describes the executable the BOM is about
+type Metadata struct {
+	Timestamp time.Time `json:"timestamp"`
+	Tools     Tools     `json:"tools"`
+	Component Component `json:"component"`
+}
+
+// Tools lists the software that produced the BOM
+type Tools struct {
+	Components []Component `json:"components"`
+}
+
+// Component is the executable itself, a Go module or the standard library
+type Component struct {
+	Type       string     `json:"type"` // application or library
+	BOMRef     string     `json:"bom-ref,omitempty"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	PURL       string     `json:"purl,omitempty"`
+	Hashes     []Hash     `json:"hashes,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// Hash is a digest of a component
+type Hash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// Property is a name/value annotation on a component
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Dependency records which components a component links
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// Read returns the module information embedded in an executable
+func Read(path string) (*debug.BuildInfo, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoBuildInfo, err)
+	}
+	return info, nil
+}
+
+// FromBuildInfo builds a BOM for the executable named name with the given
+// SHA-256. The module graph is not recorded in executables, so the main module
+// is listed as depending on every linked module directly.
+func FromBuildInfo(info *debug.BuildInfo, name, sha256 string, now time.Time) *BOM {
+	main := Component{
+		Type:    "application",
+		BOMRef:  purl(info.Main.Path, info.Main.Version),
+		Name:    name,
+		Version: moduleVersion(info.Main.Version),
+		PURL:    purl(info.Main.Path, info.Main.Version),
+		Properties: []Property{
+			{Name: "cdx:gomod:module", Value: info.Main.Path},
+			{Name: "cdx:gomod:package", Value: info.Path},
+		},
+	}
+	if main.BOMRef == "" {
+		main.BOMRef = name
+	}
+	if sha256 != "" {
+		main.Hashes = []Hash{{Alg: "SHA-256", Content: sha256}}
+	}
+	for _, setting := range info.Settings {
+		main.Properties = append(main.Properties, Property{Name: "cdx:gomod:build:" + setting.Key, Value: setting.Value})
+	}
+
+	stdlib := Component{
+		Type:    "library",
+		BOMRef:  "pkg:golang/std@" + goVersion(info.GoVersion),
+		Name:    "std",
+		Version: goVersion(info.GoVersion),
+		PURL:    "pkg:golang/std@" + goVersion(info.GoVersion),
+	}
+
+	bom := &BOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.New().String(),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: now.UTC(),
+			Tools:     Tools{Components: []Component{{Type: "application", Name: "go_runner"}}},
+			Component: main,
+		},
+		Components: []Component{stdlib},
+	}
+
+	depends := []string{stdlib.BOMRef}
+	for _, dep := range info.Deps {
+		c := moduleComponent(dep)
+		bom.Components = append(bom.Components, c)
+		depends = append(depends, c.BOMRef)
+	}
+	bom.Dependencies = []Dependency{{Ref: main.BOMRef, DependsOn: depends}}
+	return bom
+}
+
+// moduleComponent describes a linked module, using its replacement if it has one
+func moduleComponent(dep *debug.Module) Component {
+	mod := dep
+	var props []Property
+	if dep.Replace != nil {
+		mod = dep.Replace
+		props = append(props, Property{Name: "cdx:gomod:replaces", Value: dep.Path + "@" + dep.Version})
+	}
+
+	ref := purl(mod.Path, mod.Version)
+	if ref == "" {
+		// Directory replacements have no version
+		ref = "pkg:golang/" + mod.Path
+	}
+	c := Component{
+		Type:       "library",
+		BOMRef:     ref,
+		Name:       mod.Path,
+		Version:    mod.Version,
+		PURL:       ref,
+		Properties: props,
+	}
+	if sum := goSumHash(mod.Sum); sum != "" {
+		c.Hashes = []Hash{{Alg: "SHA-256", Content: sum}}
+	}
+	return c
+}
+
+// purl returns the package URL of a module version
+func purl(path, version string) string {
+	if path == "" {
+		return ""
+	}
+	if v := moduleVersion(version); v != "" {
+		return "pkg:golang/" + path + "@" + v
+	}
+	return "pkg:golang/" + path
+}
+
+// moduleVersion drops the placeholder version of modules built from a checkout
+func moduleVersion(version string) string {
+	if version == "(devel)" {
+		return ""
+	}
+	return version
+}
+
+// goVersion trims experiment suffixes such as " X:boringcrypto"
+func goVersion(v string) string {
+	v, _, _ = strings.Cut(v, " ")
+	return v
+}
+
+// goSumHash converts a go.sum "h1:" hash to hex
+func goSumHash(sum string) string {
+	b64, ok := strings.CutPrefix(sum, "h1:")
+	if !ok {
+		return ""
+	}
+	raw, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(raw)
+}
+
+// WriteFile stores a BOM as JSON, replacing path atomically
+func WriteFile(path string, bom *BOM) error {
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,85 @@
+package sbom
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.22.3 X:boringcrypto",
+		Path:      "example.com/tool/cmd/tool",
+		Main:      debug.Module{Path: "example.com/tool", Version: "(devel)"},
+		Deps: []*debug.Module{
+			{Path: "github.com/go-chi/chi/v5", Version: "v5.2.2", Sum: "h1:CMwsvRVTbXVytCk1Wd72Zy1LAsAh9GxMmSNWLHCG618="},
+			{Path: "example.com/lib", Version: "v1.0.0", Replace: &debug.Module{Path: "../lib"}},
+		},
+		Settings: []debug.BuildSetting{{Key: "GOOS", Value: "linux"}, {Key: "vcs.revision", Value: "abc123"}},
+	}
+
+	bom := FromBuildInfo(info, "tool", "deadbeef", time.Unix(0, 0))
+
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Equal(t, "1.5", bom.SpecVersion)
+	assert.Regexp(t, `^urn:uuid:[0-9a-f-]{36}$`, bom.SerialNumber)
+
+	main := bom.Metadata.Component
+	assert.Equal(t, "tool", main.Name)
+	assert.Equal(t, "pkg:golang/example.com/tool", main.PURL)
+	assert.Empty(t, main.Version)
+	assert.Equal(t, []Hash{{Alg: "SHA-256", Content: "deadbeef"}}, main.Hashes)
+	assert.Contains(t, main.Properties, Property{Name: "cdx:gomod:build:vcs.revision", Value: "abc123"})
+
+	require.Len(t, bom.Components, 3)
+	assert.Equal(t, "pkg:golang/std@go1.22.3", bom.Components[0].PURL)
+	chi := bom.Components[1]
+	assert.Equal(t, "pkg:golang/github.com/go-chi/chi/v5@v5.2.2", chi.PURL)
+	require.Len(t, chi.Hashes, 1)
+	assert.Len(t, chi.Hashes[0].Content, 64)
+	local := bom.Components[2]
+	assert.Equal(t, "../lib", local.Name)
+	assert.Equal(t, []Property{{Name: "cdx:gomod:replaces", Value: "example.com/lib@v1.0.0"}}, local.Properties)
+
+	require.Len(t, bom.Dependencies, 1)
+	assert.Equal(t, main.BOMRef, bom.Dependencies[0].Ref)
+	assert.Len(t, bom.Dependencies[0].DependsOn, 3)
+}
+
+func TestRead(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/hello\n\ngo 1.21\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	exe := filepath.Join(dir, "hello")
+	cmd := exec.Command("go", "build", "-o", exe, ".")
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	info, err := Read(exe)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/hello", info.Main.Path)
+
+	_, err = Read(filepath.Join(dir, "main.go"))
+	assert.ErrorIs(t, err, ErrNoBuildInfo)
+
+	bom := FromBuildInfo(info, "hello", "", time.Now())
+	path := filepath.Join(dir, "hello.cdx.json")
+	require.NoError(t, WriteFile(path, bom))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var decoded BOM
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, bom.SerialNumber, decoded.SerialNumber)
+}
@@ -0,0 +1,254 @@
+// internal/sessions/store.go
+package sessions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go_runner/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session has expired")
+)
+
+// lastSeenResolution limits how often activity is written to disk
+const lastSeenResolution = time.Minute
+
+// Store interface for admin UI sessions
+type Store interface {
+	Create(sess *models.Session) (string, error)
+	Lookup(token string) (*models.Session, error)
+	List() ([]*models.Session, error)
+	Revoke(id string) error
+	RevokeUser(userID string) error
+}
+
+// FileStore keeps sessions in memory and, when it has a path, in a 0600 JSON
+// file so sign-ins survive restarts. Sessions are keyed by token hash.
+type FileStore struct {
+	path   string
+	idle   time.Duration
+	maxAge time.Duration
+	now    func() time.Time
+	mu     sync.Mutex
+	byHash map[string]*models.Session
+	// lastSaved records when each session's activity was persisted
+	lastSaved map[string]time.Time
+}
+
+// NewFileStore creates a session store under dir. Sessions end after idle
+// without requests, or maxAge after sign-in.
+func NewFileStore(dir string, idle, maxAge time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	fs := NewMemoryStore(idle, maxAge)
+	fs.path = filepath.Join(dir, "sessions.json")
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// NewMemoryStore creates a session store that is lost on restart
+func NewMemoryStore(idle, maxAge time.Duration) *FileStore {
+	return &FileStore{
+		idle:      idle,
+		maxAge:    maxAge,
+		now:       time.Now,
+		byHash:    make(map[string]*models.Session),
+		lastSaved: make(map[string]time.Time),
+	}
+}
+
+// Create starts sess and returns the token for its cookie. ID, CSRF token,
+// timestamps and expiry are set here.
+func (fs *FileStore) Create(sess *models.Session) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	csrf, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := fs.now()
+	sess.ID = uuid.New().String()
+	sess.TokenHash = hashToken(token)
+	sess.CSRFToken = csrf
+	sess.CreatedAt = now
+	sess.LastSeenAt = now
+	sess.ExpiresAt = now.Add(fs.maxAge)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.pruneLocked(now)
+	fs.byHash[sess.TokenHash] = copySession(sess)
+	if err := fs.save(); err != nil {
+		delete(fs.byHash, sess.TokenHash)
+		return "", err
+	}
+	return token, nil
+}
+
+// Lookup returns the live session for token and records the activity
+func (fs *FileStore) Lookup(token string) (*models.Session, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+	hash := hashToken(token)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	sess, ok := fs.byHash[hash]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	now := fs.now()
+	if fs.expired(sess, now) {
+		delete(fs.byHash, hash)
+		fs.save()
+		return nil, ErrSessionExpired
+	}
+
+	sess.LastSeenAt = now
+	if now.Sub(fs.lastSaved[hash]) >= lastSeenResolution {
+		// Activity is informational between saves, so a failed write does not end the session
+		if err := fs.save(); err == nil {
+			fs.lastSaved[hash] = now
+		}
+	}
+	return copySession(sess), nil
+}
+
+// List returns live sessions, most recently active first
+func (fs *FileStore) List() ([]*models.Session, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	now := fs.now()
+	list := make([]*models.Session, 0, len(fs.byHash))
+	for _, sess := range fs.byHash {
+		if !fs.expired(sess, now) {
+			list = append(list, copySession(sess))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastSeenAt.After(list[j].LastSeenAt) })
+	return list, nil
+}
+
+// Revoke ends the session with the given ID
+func (fs *FileStore) Revoke(id string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	for hash, sess := range fs.byHash {
+		if sess.ID == id {
+			delete(fs.byHash, hash)
+			return fs.save()
+		}
+	}
+	return ErrSessionNotFound
+}
+
+// RevokeUser ends every session of a user
+func (fs *FileStore) RevokeUser(userID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	for hash, sess := range fs.byHash {
+		if sess.UserID == userID {
+			delete(fs.byHash, hash)
+		}
+	}
+	return fs.save()
+}
+
+func (fs *FileStore) expired(sess *models.Session, now time.Time) bool {
+	return !now.Before(sess.ExpiresAt) || !now.Before(sess.LastSeenAt.Add(fs.idle))
+}
+
+// pruneLocked drops expired sessions. It must be called with the lock held.
+func (fs *FileStore) pruneLocked(now time.Time) {
+	for hash, sess := range fs.byHash {
+		if fs.expired(sess, now) {
+			delete(fs.byHash, hash)
+			delete(fs.lastSaved, hash)
+		}
+	}
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func copySession(sess *models.Session) *models.Session {
+	s := *sess
+	return &s
+}
+
+func (fs *FileStore) load() error {
+	data, err := os.ReadFile(fs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var list []*models.Session
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	for _, sess := range list {
+		fs.byHash[sess.TokenHash] = sess
+	}
+	return nil
+}
+
+// save must be called with the lock held. Memory stores do not persist.
+func (fs *FileStore) save() error {
+	if fs.path == "" {
+		return nil
+	}
+
+	list := make([]*models.Session, 0, len(fs.byHash))
+	for _, sess := range fs.byHash {
+		list = append(list, sess)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.path)
+}
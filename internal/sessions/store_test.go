@@ -0,0 +1,109 @@
+package sessions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go_runner/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*FileStore, *time.Time) {
+	t.Helper()
+	fs, err := NewFileStore(t.TempDir(), time.Hour, 8*time.Hour)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	fs.now = func() time.Time { return now }
+	return fs, &now
+}
+
+func TestFileStore_CreateAndLookup(t *testing.T) {
+	fs, now := newTestStore(t)
+
+	sess := &models.Session{UserID: "u1", Username: "alice"}
+	token, err := fs.Create(sess)
+	require.NoError(t, err)
+	assert.NotEmpty(t, sess.ID)
+	assert.NotEmpty(t, sess.CSRFToken)
+	assert.Equal(t, now.Add(8*time.Hour), sess.ExpiresAt)
+
+	// Only the token hash reaches the disk
+	data, err := os.ReadFile(fs.path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), token)
+	info, err := os.Stat(fs.path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	got, err := fs.Lookup(token)
+	require.NoError(t, err)
+	assert.Equal(t, sess.ID, got.ID)
+	assert.Equal(t, sess.CSRFToken, got.CSRFToken)
+
+	_, err = fs.Lookup("unknown")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = fs.Lookup(sess.TokenHash)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// Sessions survive a restart
+	reopened, err := NewFileStore(filepath.Dir(fs.path), time.Hour, 8*time.Hour)
+	require.NoError(t, err)
+	reopened.now = fs.now
+	_, err = reopened.Lookup(token)
+	assert.NoError(t, err)
+}
+
+func TestFileStore_Expiry(t *testing.T) {
+	fs, now := newTestStore(t)
+
+	token, err := fs.Create(&models.Session{UserID: "u1"})
+	require.NoError(t, err)
+
+	// Activity keeps the session alive until its absolute expiry
+	for i := 0; i < 7; i++ {
+		*now = now.Add(50 * time.Minute)
+		_, err = fs.Lookup(token)
+		require.NoError(t, err)
+	}
+	*now = now.Add(90 * time.Minute)
+	_, err = fs.Lookup(token)
+	assert.ErrorIs(t, err, ErrSessionExpired)
+
+	// Idle sessions end early
+	token, err = fs.Create(&models.Session{UserID: "u1"})
+	require.NoError(t, err)
+	*now = now.Add(61 * time.Minute)
+	_, err = fs.Lookup(token)
+	assert.ErrorIs(t, err, ErrSessionExpired)
+}
+
+func TestFileStore_Revoke(t *testing.T) {
+	fs, _ := newTestStore(t)
+
+	first := &models.Session{UserID: "u1"}
+	firstToken, err := fs.Create(first)
+	require.NoError(t, err)
+	secondToken, err := fs.Create(&models.Session{UserID: "u1"})
+	require.NoError(t, err)
+	otherToken, err := fs.Create(&models.Session{UserID: "u2"})
+	require.NoError(t, err)
+
+	list, err := fs.List()
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+
+	require.NoError(t, fs.Revoke(first.ID))
+	_, err = fs.Lookup(firstToken)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.ErrorIs(t, fs.Revoke(first.ID), ErrSessionNotFound)
+
+	require.NoError(t, fs.RevokeUser("u1"))
+	_, err = fs.Lookup(secondToken)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = fs.Lookup(otherToken)
+	assert.NoError(t, err)
+}
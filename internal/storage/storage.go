@@ -2,9 +2,12 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -66,7 +69,6 @@ func (fs *FileStorage) Init() error {
 	// Create necessary directories
 	dirs := []string{
 		fs.basePath,
-		filepath.Join(fs.basePath, "binaries"),
 		filepath.Join(fs.basePath, "executions"),
 		filepath.Join(fs.basePath, "metadata"),
 	}
@@ -95,13 +97,50 @@ func (fs *FileStorage) loadMetadata() error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
-	return json.Unmarshal(data, &fs.binaries)
+	if err := json.Unmarshal(data, &fs.binaries); err != nil {
+		return err
+	}
+	return fs.recordDigests()
+}
+
+// recordDigests records the SHA-256 of executables installed before digests
+// were recorded, so they keep running; callers must hold fs.mu
+func (fs *FileStorage) recordDigests() error {
+	changed := false
+	for _, binary := range fs.binaries {
+		if binary.SHA256 != "" || binary.BinaryPath == "" {
+			continue
+		}
+		sum, err := fileSHA256(binary.BinaryPath)
+		if err != nil {
+			continue // Not installed; the next build records it
+		}
+		binary.SHA256 = sum
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return fs.saveMetadata()
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// saveMetadata persists the binaries; callers must hold fs.mu
 func (fs *FileStorage) saveMetadata() error {
-	fs.mu.RLock()
 	data, err := json.MarshalIndent(fs.binaries, "", "  ")
-	fs.mu.RUnlock()
 
 	if err != nil {
 		return err
@@ -111,6 +150,20 @@ func (fs *FileStorage) saveMetadata() error {
 	return os.WriteFile(metadataPath, data, 0644)
 }
 
+// cloneBinary copies a binary the way it is persisted. Storage hands out and
+// keeps its own copies, so callers can change theirs without holding fs.mu.
+func cloneBinary(binary *models.Binary) (*models.Binary, error) {
+	data, err := json.Marshal(binary)
+	if err != nil {
+		return nil, err
+	}
+	var clone models.Binary
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
 // SaveBinary saves a binary to storage
 func (fs *FileStorage) SaveBinary(binary *models.Binary) error {
 	fs.mu.Lock()
@@ -118,7 +171,11 @@ func (fs *FileStorage) SaveBinary(binary *models.Binary) error {
 
 	binary.CreatedAt = time.Now()
 	binary.UpdatedAt = time.Now()
-	fs.binaries[binary.ID] = binary
+	stored, err := cloneBinary(binary)
+	if err != nil {
+		return err
+	}
+	fs.binaries[binary.ID] = stored
 
 	return fs.saveMetadata()
 }
@@ -133,8 +190,7 @@ func (fs *FileStorage) GetBinary(id string) (*models.Binary, error) {
 		return nil, ErrBinaryNotFound
 	}
 
-	return binary,
-			nil
+	return cloneBinary(binary)
 }
 
 // ListBinaries returns all binaries
@@ -144,7 +200,11 @@ func (fs *FileStorage) ListBinaries() ([]*models.Binary, error) {
 
 	binaries := make([]*models.Binary, 0, len(fs.binaries))
 	for _, binary := range fs.binaries {
-		binaries = append(binaries, binary)
+		clone, err := cloneBinary(binary)
+		if err != nil {
+			return nil, err
+		}
+		binaries = append(binaries, clone)
 	}
 
 	return binaries, nil
@@ -160,7 +220,11 @@ func (fs *FileStorage) UpdateBinary(binary *models.Binary) error {
 	}
 
 	binary.UpdatedAt = time.Now()
-	fs.binaries[binary.ID] = binary
+	stored, err := cloneBinary(binary)
+	if err != nil {
+		return err
+	}
+	fs.binaries[binary.ID] = stored
 
 	return fs.saveMetadata()
 }
@@ -186,10 +250,6 @@ func (fs *FileStorage) DeleteBinary(id string) error {
 
 	delete(fs.binaries, id)
 
-	// Remove binary file
-	binaryPath := filepath.Join(fs.basePath, "binaries", id)
-	os.Remove(binaryPath)
-
 	return fs.saveMetadata()
 }
 
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/models"
+)
+
+func TestFileStorage_RecordsMissingDigests(t *testing.T) {
+	dir := t.TempDir()
+	binaryPath := filepath.Join(dir, "bin")
+	require.NoError(t, os.WriteFile(binaryPath, []byte("binary"), 0755))
+	sum := sha256.Sum256([]byte("binary"))
+
+	store := NewFileStorage(dir)
+	require.NoError(t, store.Init())
+	require.NoError(t, store.SaveBinary(&models.Binary{ID: "old", BinaryPath: binaryPath, Status: "ready"}))
+	require.NoError(t, store.SaveBinary(&models.Binary{ID: "missing", BinaryPath: filepath.Join(dir, "gone"), Status: "ready"}))
+	require.NoError(t, store.SaveBinary(&models.Binary{ID: "pending", Status: "building"}))
+
+	// Executables installed before digests were recorded get one on the next start
+	reopened := NewFileStorage(dir)
+	require.NoError(t, reopened.Init())
+	binary, err := reopened.GetBinary("old")
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), binary.SHA256)
+	for _, id := range []string{"missing", "pending"} {
+		binary, err := reopened.GetBinary(id)
+		require.NoError(t, err)
+		assert.Empty(t, binary.SHA256, id)
+	}
+
+	// And keep it
+	again := NewFileStorage(dir)
+	require.NoError(t, again.Init())
+	binary, err = again.GetBinary("old")
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), binary.SHA256)
+}
+
+func TestFileStorage_WritesDoNotDeadlock(t *testing.T) {
+	store := NewFileStorage(t.TempDir())
+	require.NoError(t, store.Init())
+
+	// saveMetadata runs under the write lock; taking the read lock again in
+	// there used to hang every write
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				binary := &models.Binary{ID: uuid.New().String(), Name: "tool"}
+				assert.NoError(t, store.SaveBinary(binary))
+				assert.NoError(t, store.UpdateBinary(binary))
+				_, err := store.ListBinaries()
+				assert.NoError(t, err)
+				assert.NoError(t, store.DeleteBinary(binary.ID))
+			}()
+		}
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("storage writes deadlocked")
+	}
+	binaries, err := store.ListBinaries()
+	require.NoError(t, err)
+	assert.Empty(t, binaries)
+}
+
+func TestFileStorage_ReturnsCopies(t *testing.T) {
+	store := NewFileStorage(t.TempDir())
+	require.NoError(t, store.Init())
+	binary := &models.Binary{ID: "1", Name: "tool", Status: "pending"}
+	require.NoError(t, store.SaveBinary(binary))
+
+	// Changes reach storage only through UpdateBinary
+	binary.Status = "building"
+	got, err := store.GetBinary("1")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", got.Status)
+
+	got.Status = "ready"
+	got.LastBuild = &models.BuildRecord{ID: "b1", Status: "succeeded"}
+	listed, err := store.ListBinaries()
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "pending", listed[0].Status)
+	assert.Nil(t, listed[0].LastBuild)
+
+	require.NoError(t, store.UpdateBinary(got))
+	got.LastBuild.Status = "failed"
+	again, err := store.GetBinary("1")
+	require.NoError(t, err)
+	assert.Equal(t, "ready", again.Status)
+	assert.Equal(t, "succeeded", again.LastBuild.Status)
+}
@@ -0,0 +1,46 @@
+// internal/tlsconfig/identity.go
+package tlsconfig
+
+import (
+	"crypto/x509"
+
+	"go_runner/internal/models"
+)
+
+// Identities lists the names a client certificate proves, in the form used
+// by the client role map: URI:<uri>, email:<address>, DNS:<name> and CN:<name>
+func Identities(cert *x509.Certificate) []string {
+	var ids []string
+	for _, uri := range cert.URIs {
+		ids = append(ids, "URI:"+uri.String())
+	}
+	for _, email := range cert.EmailAddresses {
+		ids = append(ids, "email:"+email)
+	}
+	for _, name := range cert.DNSNames {
+		ids = append(ids, "DNS:"+name)
+	}
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, "CN:"+cert.Subject.CommonName)
+	}
+	return ids
+}
+
+// ClientUser maps a verified client certificate onto a user through roles.
+// The user gets the highest role of the identities the certificate proves,
+// and is named after the first of them; ok is false when none is mapped.
+func ClientUser(cert *x509.Certificate, roles map[string]string) (user *models.User, ok bool) {
+	for _, id := range Identities(cert) {
+		role, mapped := roles[id]
+		if !mapped {
+			continue
+		}
+		if user == nil {
+			user = &models.User{ID: "cert:" + id, Username: id}
+		}
+		if models.RoleRank(role) > models.RoleRank(user.Role) {
+			user.Role = role
+		}
+	}
+	return user, user != nil
+}
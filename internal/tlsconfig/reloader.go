@@ -0,0 +1,155 @@
+// internal/tlsconfig/reloader.go
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+)
+
+// Reloader serves the certificate, key and client CAs of a TLSConfig and
+// picks up changes to their files without a restart, e.g. after renewal.
+type Reloader struct {
+	cfg      config.TLSConfig
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	checked   time.Time
+}
+
+// NewReloader loads the files of cfg, failing if they are not usable
+func NewReloader(cfg config.TLSConfig) (*Reloader, error) {
+	if !cfg.Enabled() {
+		return nil, errors.New("TLS certificate and key are required")
+	}
+	switch cfg.ClientAuth {
+	case "", "optional", "require":
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q", cfg.ClientAuth)
+	}
+	for identity, role := range cfg.ClientRoleMap {
+		if err := models.ValidateRole(role); err != nil {
+			return nil, fmt.Errorf("role of client %q: %w", identity, err)
+		}
+	}
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = 10 * time.Second
+	}
+
+	r := &Reloader{cfg: cfg, interval: cfg.ReloadInterval, now: time.Now}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.checked = r.now()
+	return r, nil
+}
+
+// Config returns a server TLS config that asks for client certificates when
+// client CAs are configured
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) { return r.current(), nil },
+	}
+}
+
+// current returns the config for a handshake, reloading changed files first
+func (r *Reloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.checked) >= r.interval {
+		r.checked = now
+		if r.changedLocked() {
+			// A half-written renewal fails to load; keep serving the old files
+			if err := r.loadLocked(); err != nil {
+				slog.Error("Failed to reload TLS files", slog.String("error", err.Error()))
+			} else {
+				slog.Info("Reloaded TLS certificate")
+			}
+		}
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*r.cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if r.clientCAs != nil {
+		cfg.ClientCAs = r.clientCAs
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if r.cfg.ClientAuth == "require" {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return cfg
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// changedLocked reports whether a file's modification time differs from
+// when it was loaded. It must be called with the lock held.
+func (r *Reloader) changedLocked() bool {
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loadLocked()
+}
+
+// loadLocked must be called with the lock held
+func (r *Reloader) loadLocked() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CAs: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in %s", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.cert, r.clientCAs, r.modTimes = &cert, clientCAs, modTimes
+	return nil
+}
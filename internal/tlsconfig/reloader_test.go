@@ -0,0 +1,87 @@
+package tlsconfig
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+	"go_runner/internal/tlsconfig/tlstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader_HotReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := tlstest.NewCA("test CA")
+	certFile, keyFile := ca.WriteServer(dir)
+	caFile := filepath.Join(dir, "clients.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.PEM, 0600))
+
+	r, err := NewReloader(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ReloadInterval: time.Second})
+	require.NoError(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	first := r.current()
+	require.Len(t, first.Certificates, 1)
+	assert.NotNil(t, first.ClientCAs)
+
+	// A renewed certificate is served once the files are checked again
+	ca.WriteServer(dir)
+	future := now.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	assert.Equal(t, first.Certificates[0].Certificate[0], r.current().Certificates[0].Certificate[0], "not checked before the interval")
+	now = now.Add(2 * time.Second)
+	renewed := r.current()
+	assert.NotEqual(t, first.Certificates[0].Certificate[0], renewed.Certificates[0].Certificate[0])
+
+	// Broken files keep the last good certificate
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0600))
+	later := future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, renewed.Certificates[0].Certificate[0], r.current().Certificates[0].Certificate[0])
+}
+
+func TestNewReloader_Validation(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := tlstest.NewCA("test CA").WriteServer(dir)
+
+	_, err := NewReloader(config.TLSConfig{CertFile: certFile})
+	assert.Error(t, err)
+	_, err = NewReloader(config.TLSConfig{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")})
+	assert.Error(t, err)
+	_, err = NewReloader(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientRoleMap: map[string]string{"CN:bot": "root"}})
+	assert.Error(t, err)
+
+	r, err := NewReloader(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	assert.Nil(t, r.current().ClientCAs)
+}
+
+func TestClientUser(t *testing.T) {
+	ca := tlstest.NewCA("test CA")
+	cert := ca.Client(tlstest.Identity{
+		CommonName: "ci-bot",
+		DNSNames:   []string{"ci.example.com"},
+		URIs:       []string{"spiffe://example.com/ci"},
+	})
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"URI:spiffe://example.com/ci", "DNS:ci.example.com", "CN:ci-bot"}, Identities(leaf))
+
+	// The highest mapped role wins; the user is named after the first match
+	user, ok := ClientUser(leaf, map[string]string{"CN:ci-bot": models.RoleMaintainer, "DNS:ci.example.com": models.RoleOperator})
+	require.True(t, ok)
+	assert.Equal(t, "DNS:ci.example.com", user.Username)
+	assert.Equal(t, models.RoleMaintainer, user.Role)
+
+	_, ok = ClientUser(leaf, map[string]string{"CN:someone-else": models.RoleAdmin})
+	assert.False(t, ok)
+}
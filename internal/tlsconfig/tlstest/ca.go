@@ -0,0 +1,131 @@
+// internal/tlsconfig/tlstest/ca.go
+
+// Package tlstest issues certificates for tests.
+package tlstest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CA is a certificate authority that signs server and client certificates
+type CA struct {
+	Cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	PEM  []byte
+}
+
+// NewCA creates a self-signed CA valid for a day
+func NewCA(name string) *CA {
+	key := newKey()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial(),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &CA{Cert: cert, key: key, PEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// Identity names the holder of a certificate
+type Identity struct {
+	CommonName string
+	DNSNames   []string
+	Emails     []string
+	URIs       []string
+}
+
+// Server issues a certificate for localhost and 127.0.0.1
+func (ca *CA) Server() (certPEM, keyPEM []byte) {
+	return ca.issue(Identity{CommonName: "localhost", DNSNames: []string{"localhost"}}, x509.ExtKeyUsageServerAuth, []net.IP{net.ParseIP("127.0.0.1")})
+}
+
+// Client issues a client certificate for id
+func (ca *CA) Client(id Identity) tls.Certificate {
+	certPEM, keyPEM := ca.issue(id, x509.ExtKeyUsageClientAuth, nil)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		panic(err)
+	}
+	return cert
+}
+
+// WriteServer writes a server certificate and key into dir and returns their paths
+func (ca *CA) WriteServer(dir string) (certFile, keyFile string) {
+	certPEM, keyPEM := ca.Server()
+	certFile, keyFile = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		panic(err)
+	}
+	return certFile, keyFile
+}
+
+func (ca *CA) issue(id Identity, usage x509.ExtKeyUsage, ips []net.IP) (certPEM, keyPEM []byte) {
+	key := newKey()
+	tmpl := &x509.Certificate{
+		SerialNumber:   serial(),
+		Subject:        pkix.Name{CommonName: id.CommonName},
+		DNSNames:       id.DNSNames,
+		EmailAddresses: id.Emails,
+		IPAddresses:    ips,
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(24 * time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{usage},
+	}
+	for _, raw := range id.URIs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			panic(err)
+		}
+		tmpl.URIs = append(tmpl.URIs, u)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Cert, &key.PublicKey, ca.key)
+	if err != nil {
+		panic(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func newKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func serial() *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
@@ -0,0 +1,398 @@
+// internal/toolchain/toolchain.go
+package toolchain
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+)
+
+// Toolchain sources recorded on builds
+const (
+	SourceSystem     = "system"
+	SourceCache      = "cache"
+	SourceOffline    = "offline"
+	SourceDownloaded = "downloaded"
+)
+
+var (
+	ErrNoToolchain      = errors.New("no go toolchain available")
+	ErrDownloadDisabled = errors.New("toolchain downloads are disabled")
+
+	versionPattern = regexp.MustCompile(`^1\.(\d+)(?:\.(\d+))?(?:(rc|beta)(\d+))?$`)
+)
+
+// Manager resolves the Go toolchain for a build and installs missing versions
+type Manager struct {
+	dir         string
+	offlineDir  string
+	downloadURL string
+	client      *http.Client
+
+	mu sync.Mutex
+
+	hostOnce    sync.Once
+	hostVersion string
+}
+
+// NewManager creates a new toolchain manager
+func NewManager(cfg config.ToolchainConfig) *Manager {
+	return &Manager{
+		dir:         cfg.Path,
+		offlineDir:  cfg.OfflinePath,
+		downloadURL: strings.TrimSuffix(cfg.DownloadURL, "/"),
+		client:      &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+// Resolve picks the toolchain for the package at buildPath inside repoDir. An
+// explicit version is used as-is; otherwise the go and toolchain directives of the
+// nearest go.mod are treated as a minimum, which the go on PATH satisfies if new enough.
+func (m *Manager) Resolve(repoDir, buildPath, requested string) (*models.Toolchain, error) {
+	if requested != "" {
+		version, err := Normalize(requested)
+		if err != nil {
+			return nil, err
+		}
+		if host := m.host(); host == version {
+			return &models.Toolchain{Version: host, Source: SourceSystem}, nil
+		}
+		return m.Install(version)
+	}
+
+	required, err := requiredVersion(repoDir, buildPath)
+	if err != nil {
+		return nil, err
+	}
+
+	host := m.host()
+	if host != "" && (required == "" || Compare(host, required) >= 0) {
+		return &models.Toolchain{Version: host, Source: SourceSystem}, nil
+	}
+	if required == "" {
+		return nil, ErrNoToolchain
+	}
+
+	return m.Install(required)
+}
+
+// Install makes the given version available, preferring the local cache, then the
+// offline directory, and finally downloading it
+func (m *Manager) Install(version string) (*models.Toolchain, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	goroot := filepath.Join(m.dir, version, "go")
+	if hasGoBinary(goroot) {
+		return &models.Toolchain{Version: version, Source: SourceCache, GOROOT: goroot}, nil
+	}
+
+	if m.offlineDir != "" {
+		for _, candidate := range []string{
+			filepath.Join(m.offlineDir, version),
+			filepath.Join(m.offlineDir, version, "go"),
+		} {
+			if hasGoBinary(candidate) {
+				return &models.Toolchain{Version: version, Source: SourceOffline, GOROOT: candidate}, nil
+			}
+		}
+
+		archive := filepath.Join(m.offlineDir, archiveName(version))
+		if _, err := os.Stat(archive); err == nil {
+			f, err := os.Open(archive)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			if err := m.extract(version, f); err != nil {
+				return nil, fmt.Errorf("failed to extract %s: %w", archive, err)
+			}
+			return &models.Toolchain{Version: version, Source: SourceOffline, GOROOT: goroot}, nil
+		}
+	}
+
+	if m.downloadURL == "" || m.downloadURL == "off" {
+		return nil, fmt.Errorf("%w: %s is not installed", ErrDownloadDisabled, version)
+	}
+
+	if err := m.download(version); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", version, err)
+	}
+	return &models.Toolchain{Version: version, Source: SourceDownloaded, GOROOT: goroot}, nil
+}
+
+// host returns the version of the go command on PATH, if any
+func (m *Manager) host() string {
+	m.hostOnce.Do(func() {
+		cmd := exec.Command("go", "env", "GOVERSION")
+		cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+		if output, err := cmd.Output(); err == nil {
+			m.hostVersion = strings.TrimSpace(string(output))
+		}
+	})
+	return m.hostVersion
+}
+
+func (m *Manager) download(version string) error {
+	url := m.downloadURL + "/" + archiveName(version)
+
+	want, err := m.fetchChecksum(url + ".sha256")
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(m.dir, ".download-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), resp.Body); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", got, want)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return m.extract(version, tmp)
+}
+
+func (m *Manager) fetchChecksum(url string) (string, error) {
+	resp, err := m.client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum at %s", url)
+	}
+	return fields[0], nil
+}
+
+// extract unpacks a go release tarball into <dir>/<version>, atomically
+func (m *Manager) extract(version string, r io.Reader) error {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(m.dir, ".install-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if !filepath.IsLocal(hdr.Name) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+		target := filepath.Join(tmpDir, hdr.Name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0755)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !hasGoBinary(filepath.Join(tmpDir, "go")) {
+		return errors.New("archive does not contain go/bin/go")
+	}
+
+	return os.Rename(tmpDir, filepath.Join(m.dir, version))
+}
+
+// requiredVersion returns the minimum toolchain demanded by the go.mod closest to
+// buildPath, or "" if there is none
+func requiredVersion(repoDir, buildPath string) (string, error) {
+	dir := filepath.Join(repoDir, buildPath)
+	for {
+		goVersion, toolchainVersion, err := readGoMod(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			required := ""
+			if goVersion != "" {
+				if required, err = Normalize(goVersion); err != nil {
+					return "", fmt.Errorf("go.mod: %w", err)
+				}
+			}
+			if toolchainVersion != "" && toolchainVersion != "default" {
+				v, err := Normalize(toolchainVersion)
+				if err != nil {
+					return "", fmt.Errorf("go.mod: %w", err)
+				}
+				if required == "" || Compare(v, required) > 0 {
+					required = v
+				}
+			}
+			return required, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		if rel, err := filepath.Rel(repoDir, dir); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return "", nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// readGoMod extracts the go and toolchain directives from a go.mod file
+func readGoMod(path string) (goVersion, toolchainVersion string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "go":
+			goVersion = fields[1]
+		case "toolchain":
+			toolchainVersion = fields[1]
+		}
+	}
+	return goVersion, toolchainVersion, scanner.Err()
+}
+
+// Normalize converts "1.22", "go1.22.3" or "1.23rc1" into the name of a released
+// toolchain, e.g. "go1.22.0". Since Go 1.21 a bare language version denotes its .0 release.
+func Normalize(v string) (string, error) {
+	v = strings.TrimPrefix(v, "go")
+	m := versionPattern.FindStringSubmatch(v)
+	if m == nil {
+		return "", fmt.Errorf("invalid go version %q", v)
+	}
+	minor, _ := strconv.Atoi(m[1])
+	if m[2] == "" && m[3] == "" && minor >= 21 {
+		v += ".0"
+	}
+	return "go" + v, nil
+}
+
+// Compare compares two normalized toolchain versions, returning -1, 0 or +1
+func Compare(a, b string) int {
+	pa, pb := parseVersion(a), parseVersion(b)
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseVersion returns minor, patch, kind (0=beta, 1=rc, 2=release) and prerelease number
+func parseVersion(v string) [4]int {
+	m := versionPattern.FindStringSubmatch(strings.TrimPrefix(v, "go"))
+	if m == nil {
+		return [4]int{}
+	}
+	var p [4]int
+	p[0], _ = strconv.Atoi(m[1])
+	p[1], _ = strconv.Atoi(m[2])
+	switch m[3] {
+	case "beta":
+		p[2] = 0
+	case "rc":
+		p[2] = 1
+	default:
+		p[2] = 2
+	}
+	p[3], _ = strconv.Atoi(m[4])
+	return p
+}
+
+func archiveName(version string) string {
+	return fmt.Sprintf("%s.%s-%s.tar.gz", version, runtime.GOOS, runtime.GOARCH)
+}
+
+func hasGoBinary(goroot string) bool {
+	info, err := os.Stat(filepath.Join(goroot, "bin", "go"))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,104 @@
+package toolchain
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/config"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"1.20":      "go1.20",
+		"1.21":      "go1.21.0",
+		"go1.22":    "go1.22.0",
+		"go1.22.3":  "go1.22.3",
+		"1.23rc1":   "go1.23rc1",
+		"go1.24.10": "go1.24.10",
+	}
+	for in, want := range cases {
+		got, err := Normalize(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	_, err := Normalize("latest")
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	assert.Equal(t, -1, Compare("go1.21.0", "go1.22.0"))
+	assert.Equal(t, 1, Compare("go1.22.10", "go1.22.9"))
+	assert.Equal(t, -1, Compare("go1.23rc1", "go1.23.0"))
+	assert.Equal(t, -1, Compare("go1.23beta1", "go1.23rc1"))
+	assert.Equal(t, 0, Compare("go1.22.3", "go1.22.3"))
+}
+
+func TestResolve_UsesHostWhenNewEnough(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "go.mod"), []byte("module example.com/x\n\ngo 1.16\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "cmd", "tool"), 0755))
+
+	m := NewManager(config.ToolchainConfig{Path: t.TempDir(), DownloadURL: "off"})
+	if m.host() == "" {
+		t.Skip("no go command on PATH")
+	}
+
+	tc, err := m.Resolve(repo, "cmd/tool", "")
+	require.NoError(t, err)
+	assert.Equal(t, SourceSystem, tc.Source)
+	assert.Equal(t, m.host(), tc.Version)
+}
+
+func TestResolve_ToolchainDirectiveFromOfflineArchive(t *testing.T) {
+	repo := t.TempDir()
+	goMod := "module example.com/x\n\ngo 1.21\n\ntoolchain go1.99.1 // far future\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "go.mod"), []byte(goMod), 0644))
+
+	offline := t.TempDir()
+	writeFakeRelease(t, filepath.Join(offline, archiveName("go1.99.1")))
+
+	dir := t.TempDir()
+	m := NewManager(config.ToolchainConfig{Path: dir, OfflinePath: offline, DownloadURL: "off"})
+
+	tc, err := m.Resolve(repo, ".", "")
+	require.NoError(t, err)
+	assert.Equal(t, "go1.99.1", tc.Version)
+	assert.Equal(t, SourceOffline, tc.Source)
+	assert.FileExists(t, filepath.Join(dir, "go1.99.1", "go", "bin", "go"))
+
+	// Second resolution is served from the cache
+	tc, err = m.Resolve(repo, ".", "")
+	require.NoError(t, err)
+	assert.Equal(t, SourceCache, tc.Source)
+}
+
+func TestInstall_DownloadDisabled(t *testing.T) {
+	m := NewManager(config.ToolchainConfig{Path: t.TempDir(), DownloadURL: "off"})
+
+	_, err := m.Install("go1.99.2")
+	assert.True(t, errors.Is(err, ErrDownloadDisabled))
+}
+
+// writeFakeRelease creates a minimal tarball laid out like an official go release
+func writeFakeRelease(t *testing.T, path string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	script := []byte("#!/bin/sh\necho fake go\n")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "go/bin/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "go/bin/go", Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(script))}))
+	_, err = tw.Write(script)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+}
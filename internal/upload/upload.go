@@ -0,0 +1,290 @@
+// internal/upload/upload.go
+package upload
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Upload formats recognised by Detect
+const (
+	KindTarGz = "tar.gz"
+	KindZip   = "zip"
+	KindELF   = "elf"
+)
+
+// Limits that protect the server from archive bombs
+const (
+	maxExtractedSize = 2 << 30
+	maxEntries       = 100000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported upload format")
+	ErrInvalidArchive    = errors.New("invalid source archive")
+	ErrInvalidELF        = errors.New("invalid executable")
+)
+
+// elfMachines maps GOARCH to the ELF machine and class it runs
+var elfMachines = map[string]struct {
+	machine elf.Machine
+	class   elf.Class
+}{
+	"386":     {elf.EM_386, elf.ELFCLASS32},
+	"amd64":   {elf.EM_X86_64, elf.ELFCLASS64},
+	"arm":     {elf.EM_ARM, elf.ELFCLASS32},
+	"arm64":   {elf.EM_AARCH64, elf.ELFCLASS64},
+	"loong64": {elf.EM_LOONGARCH, elf.ELFCLASS64},
+	"ppc64le": {elf.EM_PPC64, elf.ELFCLASS64},
+	"riscv64": {elf.EM_RISCV, elf.ELFCLASS64},
+	"s390x":   {elf.EM_S390, elf.ELFCLASS64},
+}
+
+// Detect identifies an upload from its first bytes
+func Detect(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return KindTarGz
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")):
+		return KindZip
+	case bytes.HasPrefix(header, []byte(elf.ELFMAG)):
+		return KindELF
+	}
+	return ""
+}
+
+// ValidateELF checks that path is a statically linked Linux executable for goarch
+func ValidateELF(path, goarch string) error {
+	f, err := elf.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidELF, err)
+	}
+	defer f.Close()
+
+	if f.OSABI != elf.ELFOSABI_NONE && f.OSABI != elf.ELFOSABI_LINUX {
+		return fmt.Errorf("%w: OS ABI %s is not Linux", ErrInvalidELF, f.OSABI)
+	}
+	// Static PIE binaries are ET_DYN without an interpreter
+	if f.Type != elf.ET_EXEC && f.Type != elf.ET_DYN {
+		return fmt.Errorf("%w: %s is not an executable", ErrInvalidELF, f.Type)
+	}
+
+	want, ok := elfMachines[goarch]
+	if !ok {
+		return fmt.Errorf("%w: unsupported host architecture %s", ErrInvalidELF, goarch)
+	}
+	if f.Machine != want.machine || f.Class != want.class {
+		return fmt.Errorf("%w: built for %s (%s), host is %s", ErrInvalidELF, f.Machine, f.Class, goarch)
+	}
+
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_INTERP {
+			return fmt.Errorf("%w: dynamically linked executables are not supported", ErrInvalidELF)
+		}
+	}
+	if f.Type == elf.ET_DYN && f.Entry == 0 {
+		return fmt.Errorf("%w: shared library is not an executable", ErrInvalidELF)
+	}
+	return nil
+}
+
+// Extract unpacks a tar.gz or zip archive into dest, replacing its contents.
+// An archive whose entries all sit in one top-level directory, as produced
+// by most forges, is unpacked from inside that directory.
+func Extract(archivePath, dest string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := make([]byte, 4)
+	n, _ := io.ReadFull(f, header)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dest), ".extract-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	switch Detect(header[:n]) {
+	case KindTarGz:
+		err = extractTarGz(f, staging)
+	case KindZip:
+		info, statErr := f.Stat()
+		if statErr != nil {
+			return statErr
+		}
+		err = extractZip(f, info.Size(), staging)
+	default:
+		return ErrUnsupportedFormat
+	}
+	if err != nil {
+		return err
+	}
+
+	root, err := sourceRoot(staging)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	return os.Rename(root, dest)
+}
+
+// sourceRoot descends into a lone top-level directory
+func sourceRoot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
+
+func extractTarGz(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	var ex extractor
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = ex.dir(dest, hdr.Name)
+		case tar.TypeReg:
+			err = ex.file(dest, hdr.Name, hdr.FileInfo().Mode(), tr)
+		case tar.TypeXGlobalHeader:
+			// pax comment written by git archive
+		default:
+			err = fmt.Errorf("%w: %s: links and special files are not supported", ErrInvalidArchive, hdr.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(r io.ReaderAt, size int64, dest string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	var ex extractor
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = ex.dir(dest, zf.Name)
+		case mode.IsRegular():
+			err = ex.zipFile(dest, zf)
+		default:
+			err = fmt.Errorf("%w: %s: links and special files are not supported", ErrInvalidArchive, zf.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractor writes archive entries below a directory while enforcing the limits
+type extractor struct {
+	entries int
+	written int64
+}
+
+func (ex *extractor) dir(dest, name string) error {
+	target, err := ex.target(dest, name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(target, 0755)
+}
+
+func (ex *extractor) zipFile(dest string, zf *zip.File) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer rc.Close()
+	return ex.file(dest, zf.Name, zf.Mode(), rc)
+}
+
+func (ex *extractor) file(dest, name string, mode os.FileMode, r io.Reader) error {
+	target, err := ex.target(dest, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	// Read one byte past the remaining budget to detect oversized archives
+	n, err := io.Copy(out, io.LimitReader(r, maxExtractedSize-ex.written+1))
+	ex.written += n
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidArchive, name, err)
+	}
+	if ex.written > maxExtractedSize {
+		return fmt.Errorf("%w: extracted size exceeds %d bytes", ErrInvalidArchive, int64(maxExtractedSize))
+	}
+	return nil
+}
+
+// target resolves an entry name below dest, rejecting names that escape it
+func (ex *extractor) target(dest, name string) (string, error) {
+	ex.entries++
+	if ex.entries > maxEntries {
+		return "", fmt.Errorf("%w: more than %d entries", ErrInvalidArchive, maxEntries)
+	}
+
+	clean := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, ":") {
+		return "", fmt.Errorf("%w: entry %q escapes the archive", ErrInvalidArchive, name)
+	}
+	return filepath.Join(dest, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,173 @@
+package upload
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeELF writes a minimal 64-bit little-endian ELF header with the given program headers
+func writeELF(t *testing.T, path string, machine elf.Machine, progs ...elf.ProgType) {
+	t.Helper()
+	var buf bytes.Buffer
+	hdr := elf.Header64{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Entry:     0x401000,
+		Ehsize:    64,
+		Phentsize: 56,
+		Phnum:     uint16(len(progs)),
+		Shentsize: 64,
+	}
+	copy(hdr.Ident[:], elf.ELFMAG)
+	hdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	hdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	hdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	if len(progs) > 0 {
+		hdr.Phoff = 64
+	}
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, hdr))
+	for _, typ := range progs {
+		require.NoError(t, binary.Write(&buf, binary.LittleEndian, elf.Prog64{Type: uint32(typ)}))
+	}
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func tarGz(t *testing.T, files map[string]string, extra ...*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	for _, hdr := range extra {
+		require.NoError(t, tw.WriteHeader(hdr))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, KindTarGz, Detect(tarGz(t, nil)))
+	assert.Equal(t, KindZip, Detect(zipArchive(t, map[string]string{"a": "b"})))
+	assert.Equal(t, KindELF, Detect([]byte("\x7fELF\x02\x01")))
+	assert.Equal(t, "", Detect([]byte("#!/bin/sh")))
+}
+
+func TestExtract(t *testing.T) {
+	cases := map[string][]byte{
+		"tar.gz": tarGz(t, map[string]string{
+			"tool-1.0/go.mod":           "module example.com/tool\n",
+			"tool-1.0/cmd/tool/main.go": "package main\n",
+		}),
+		"zip": zipArchive(t, map[string]string{
+			"go.mod":           "module example.com/tool\n",
+			"cmd/tool/main.go": "package main\n",
+		}),
+	}
+
+	for name, archive := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "upload")
+			require.NoError(t, os.WriteFile(archivePath, archive, 0644))
+
+			dest := filepath.Join(dir, "repos", "repo_1")
+			require.NoError(t, os.MkdirAll(dest, 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dest, "stale.go"), []byte("package stale\n"), 0644))
+
+			require.NoError(t, Extract(archivePath, dest))
+
+			content, err := os.ReadFile(filepath.Join(dest, "go.mod"))
+			require.NoError(t, err)
+			assert.Equal(t, "module example.com/tool\n", string(content))
+			assert.FileExists(t, filepath.Join(dest, "cmd", "tool", "main.go"))
+			assert.NoFileExists(t, filepath.Join(dest, "stale.go"))
+
+			// Nothing is left behind next to the checkout
+			entries, err := os.ReadDir(filepath.Dir(dest))
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
+
+func TestExtract_RejectsUnsafeEntries(t *testing.T) {
+	cases := map[string][]byte{
+		"traversal": tarGz(t, map[string]string{"../evil.go": "package evil\n"}),
+		"absolute":  zipArchive(t, map[string]string{"/etc/evil": "x"}),
+		"symlink": tarGz(t, map[string]string{"go.mod": "module x\n"},
+			&tar.Header{Name: "link", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink}),
+		"not an archive": []byte("plain text"),
+	}
+
+	for name, archive := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "upload")
+			require.NoError(t, os.WriteFile(archivePath, archive, 0644))
+
+			dest := filepath.Join(dir, "repos", "repo_1")
+			assert.Error(t, Extract(archivePath, dest))
+			assert.NoDirExists(t, dest)
+			assert.NoFileExists(t, filepath.Join(dir, "evil.go"))
+		})
+	}
+}
+
+func TestValidateELF(t *testing.T) {
+	host, ok := elfMachines[runtime.GOARCH]
+	if !ok || host.class != elf.ELFCLASS64 {
+		t.Skip("test writes 64-bit headers only")
+	}
+	other := elf.EM_AARCH64
+	if host.machine == other {
+		other = elf.EM_X86_64
+	}
+
+	dir := t.TempDir()
+	static := filepath.Join(dir, "static")
+	writeELF(t, static, host.machine, elf.PT_LOAD)
+	assert.NoError(t, ValidateELF(static, runtime.GOARCH))
+
+	dynamic := filepath.Join(dir, "dynamic")
+	writeELF(t, dynamic, host.machine, elf.PT_INTERP, elf.PT_LOAD)
+	assert.ErrorIs(t, ValidateELF(dynamic, runtime.GOARCH), ErrInvalidELF)
+
+	foreign := filepath.Join(dir, "foreign")
+	writeELF(t, foreign, other, elf.PT_LOAD)
+	assert.ErrorIs(t, ValidateELF(foreign, runtime.GOARCH), ErrInvalidELF)
+
+	script := filepath.Join(dir, "script")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\n"), 0755))
+	assert.ErrorIs(t, ValidateELF(script, runtime.GOARCH), ErrInvalidELF)
+}
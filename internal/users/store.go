@@ -0,0 +1,274 @@
+// internal/users/store.go
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go_runner/internal/models"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// Password length limits. bcrypt ignores everything past 72 bytes.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// Store interface for user accounts
+type Store interface {
+	Create(user *models.User, password string) error
+	Get(id string) (*models.User, error)
+	List() ([]*models.User, error)
+	Update(user *models.User, password string) error
+	Delete(id string) error
+	Authenticate(username, password string) (*models.User, error)
+}
+
+// FileStore keeps users in a single 0600 JSON file
+type FileStore struct {
+	path  string
+	cost  int
+	mu    sync.Mutex
+	users map[string]*models.User
+	// dummyHash is compared against when a username is unknown, so the
+	// response time does not reveal which usernames exist
+	dummyHash []byte
+}
+
+// NewFileStore creates a user store under dir
+func NewFileStore(dir string) (*FileStore, error) {
+	fs := &FileStore{
+		path:  filepath.Join(dir, "users.json"),
+		cost:  bcrypt.DefaultCost,
+		users: make(map[string]*models.User),
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create user directory: %w", err)
+	}
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// ValidatePassword checks a password fits the length limits
+func ValidatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be %d to %d bytes", minPasswordLength, maxPasswordLength)
+	}
+	return nil
+}
+
+// Create adds a user with the given password. ID and timestamps are set here.
+func (fs *FileStore) Create(user *models.User, password string) error {
+	if err := models.ValidateUsername(user.Username); err != nil {
+		return err
+	}
+	if err := models.ValidateRole(user.Role); err != nil {
+		return err
+	}
+	hash, err := fs.hash(password)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.findLocked(user.Username) != nil {
+		return ErrUsernameTaken
+	}
+
+	user.ID = uuid.New().String()
+	user.PasswordHash = hash
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	fs.users[user.ID] = copyUser(user)
+	if err := fs.save(); err != nil {
+		delete(fs.users, user.ID)
+		return err
+	}
+	return nil
+}
+
+// Get retrieves a user by ID
+func (fs *FileStore) Get(id string) (*models.User, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	user, ok := fs.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return copyUser(user), nil
+}
+
+// List returns all users sorted by username
+func (fs *FileStore) List() ([]*models.User, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	users := make([]*models.User, 0, len(fs.users))
+	for _, user := range fs.users {
+		users = append(users, copyUser(user))
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+// Update changes the role of a user and, if password is not empty, its password
+func (fs *FileStore) Update(user *models.User, password string) error {
+	if err := models.ValidateRole(user.Role); err != nil {
+		return err
+	}
+	var hash string
+	if password != "" {
+		var err error
+		if hash, err = fs.hash(password); err != nil {
+			return err
+		}
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	current, ok := fs.users[user.ID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	updated := *current
+	updated.Role = user.Role
+	if hash != "" {
+		updated.PasswordHash = hash
+	}
+	updated.UpdatedAt = time.Now()
+
+	fs.users[user.ID] = &updated
+	if err := fs.save(); err != nil {
+		fs.users[user.ID] = current
+		return err
+	}
+	*user = updated
+	return nil
+}
+
+// Delete removes a user
+func (fs *FileStore) Delete(id string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	user, ok := fs.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	delete(fs.users, id)
+	if err := fs.save(); err != nil {
+		fs.users[id] = user
+		return err
+	}
+	return nil
+}
+
+// Authenticate returns the user with username if password matches
+func (fs *FileStore) Authenticate(username, password string) (*models.User, error) {
+	fs.mu.Lock()
+	user := fs.findLocked(username)
+	if user != nil {
+		user = copyUser(user)
+	}
+	fs.mu.Unlock()
+
+	if user == nil {
+		bcrypt.CompareHashAndPassword(fs.dummy(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+func (fs *FileStore) hash(password string) (string, error) {
+	if err := ValidatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), fs.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (fs *FileStore) dummy() []byte {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.dummyHash == nil {
+		fs.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("go_runner"), fs.cost)
+	}
+	return fs.dummyHash
+}
+
+// findLocked looks a user up by username, ignoring case. It must be called
+// with the lock held.
+func (fs *FileStore) findLocked(username string) *models.User {
+	for _, user := range fs.users {
+		if strings.EqualFold(user.Username, username) {
+			return user
+		}
+	}
+	return nil
+}
+
+func copyUser(user *models.User) *models.User {
+	u := *user
+	return &u
+}
+
+func (fs *FileStore) load() error {
+	data, err := os.ReadFile(fs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var users map[string]*models.User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return err
+	}
+	if users != nil {
+		fs.users = users
+	}
+	return nil
+}
+
+// save must be called with the lock held
+func (fs *FileStore) save() error {
+	data, err := json.MarshalIndent(fs.users, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.path)
+}
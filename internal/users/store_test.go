@@ -0,0 +1,83 @@
+package users
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go_runner/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestStore(t *testing.T) *FileStore {
+	t.Helper()
+	fs, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	fs.cost = bcrypt.MinCost
+	return fs
+}
+
+func TestFileStore_CreateAndAuthenticate(t *testing.T) {
+	fs := newTestStore(t)
+
+	user := &models.User{Username: "alice", Role: models.RoleMaintainer}
+	require.NoError(t, fs.Create(user, "correct horse"))
+	assert.NotEmpty(t, user.ID)
+
+	// Only the bcrypt hash reaches the disk
+	data, err := os.ReadFile(fs.path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "correct horse")
+	info, err := os.Stat(fs.path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	got, err := fs.Authenticate("Alice", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+
+	_, err = fs.Authenticate("alice", "wrong password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = fs.Authenticate("bob", "correct horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	assert.ErrorIs(t, fs.Create(&models.User{Username: "ALICE", Role: models.RoleViewer}, "another one"), ErrUsernameTaken)
+	assert.Error(t, fs.Create(&models.User{Username: "bob", Role: "root"}, "long enough"))
+	assert.Error(t, fs.Create(&models.User{Username: "bob", Role: models.RoleViewer}, "short"))
+	assert.Error(t, fs.Create(&models.User{Username: "../bob", Role: models.RoleViewer}, "long enough"))
+
+	// Users survive a restart
+	reopened, err := NewFileStore(filepath.Dir(fs.path))
+	require.NoError(t, err)
+	_, err = reopened.Authenticate("alice", "correct horse")
+	assert.NoError(t, err)
+}
+
+func TestFileStore_UpdateAndDelete(t *testing.T) {
+	fs := newTestStore(t)
+
+	user := &models.User{Username: "carol", Role: models.RoleViewer}
+	require.NoError(t, fs.Create(user, "first password"))
+
+	// An empty password keeps the current one
+	require.NoError(t, fs.Update(&models.User{ID: user.ID, Role: models.RoleOperator}, ""))
+	got, err := fs.Authenticate("carol", "first password")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleOperator, got.Role)
+
+	require.NoError(t, fs.Update(&models.User{ID: user.ID, Role: models.RoleOperator}, "second password"))
+	_, err = fs.Authenticate("carol", "first password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = fs.Authenticate("carol", "second password")
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, fs.Update(&models.User{ID: "missing", Role: models.RoleViewer}, ""), ErrUserNotFound)
+
+	require.NoError(t, fs.Delete(user.ID))
+	_, err = fs.Get(user.ID)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.ErrorIs(t, fs.Delete(user.ID), ErrUserNotFound)
+}
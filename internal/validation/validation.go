@@ -0,0 +1,209 @@
+// internal/validation/validation.go
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError reports why one field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"` // JSON name, dotted for nested objects
+	Message string `json:"message"`
+}
+
+// Errors collects the field errors of a request. It is an error once
+// anything has been added.
+type Errors []FieldError
+
+// Add records that field is invalid
+func (e *Errors) Add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check records err, if any, against field
+func (e *Errors) Check(field string, err error) {
+	if err != nil {
+		e.Add(field, "%s", err.Error())
+	}
+}
+
+// Has reports whether field already has an error
+func (e Errors) Has(field string) bool {
+	for _, fe := range e {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Struct checks v, a struct or pointer to one, against the validate tags of
+// its fields, following nested structs. The rules are a subset of those of
+// go-playground/validator:
+//
+//	omitempty                 skip the other rules when the field is empty
+//	required                  not empty; strings must not be blank
+//	required_unless=F a b     required unless sibling field F is one of a, b
+//	min=N, max=N              length of strings (in characters) and slices, or value of numbers
+//	url                       an absolute URL
+//	oneof=a b                 one of the listed values
+func Struct(v interface{}) Errors {
+	var errs Errors
+	checkStruct(reflect.ValueOf(v), "", &errs)
+	return errs
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func checkStruct(v reflect.Value, prefix string, errs *Errors) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.Type() == timeType {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := jsonName(f)
+		if name == "-" {
+			continue
+		}
+		field := prefix + name
+		if tag := f.Tag.Get("validate"); tag != "" {
+			checkField(v, v.Field(i), field, tag, errs)
+		}
+		checkStruct(v.Field(i), field+".", errs)
+	}
+}
+
+func checkField(parent, v reflect.Value, field, tag string, errs *Errors) {
+	rules := strings.Split(tag, ",")
+	if v.IsZero() {
+		for _, rule := range rules {
+			name, param, _ := strings.Cut(rule, "=")
+			switch {
+			case name == "omitempty":
+				return
+			case name == "required":
+				errs.Add(field, "is required")
+				return
+			case name == "required_unless" && !siblingIn(parent, param):
+				errs.Add(field, "is required")
+				return
+			}
+		}
+		return
+	}
+
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "omitempty", "required_unless":
+		case "required":
+			if v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "" {
+				errs.Add(field, "is required")
+				return
+			}
+		case "min", "max":
+			if msg := checkBound(v, name, param); msg != "" {
+				errs.Add(field, "%s", msg)
+				return
+			}
+		case "url":
+			u, err := url.Parse(v.String())
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				errs.Add(field, "must be an absolute URL")
+				return
+			}
+		case "oneof":
+			if !contains(strings.Fields(param), fmt.Sprint(v.Interface())) {
+				errs.Add(field, "must be one of %s", strings.Join(strings.Fields(param), ", "))
+				return
+			}
+		default:
+			panic("validation: unknown rule " + rule + " on " + field)
+		}
+	}
+}
+
+// checkBound applies min or max, returning a message when v is out of bounds
+func checkBound(v reflect.Value, rule, param string) string {
+	limit, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		panic("validation: bad " + rule + " " + param)
+	}
+
+	var n int64
+	var unit string
+	switch v.Kind() {
+	case reflect.String:
+		n, unit = int64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map:
+		n, unit = int64(v.Len()), " entries"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = v.Int()
+	default:
+		panic("validation: " + rule + " on " + v.Kind().String())
+	}
+
+	if rule == "min" && n < limit {
+		return fmt.Sprintf("must be at least %d%s", limit, unit)
+	}
+	if rule == "max" && n > limit {
+		return fmt.Sprintf("must be at most %d%s", limit, unit)
+	}
+	return ""
+}
+
+// siblingIn reports whether the field named in param ("Field a b") holds
+// one of the listed values
+func siblingIn(parent reflect.Value, param string) bool {
+	args := strings.Fields(param)
+	if len(args) < 2 {
+		panic("validation: required_unless needs a field and values")
+	}
+	sibling := parent.FieldByName(args[0])
+	if !sibling.IsValid() {
+		panic("validation: required_unless names unknown field " + args[0])
+	}
+	return contains(args[1:], fmt.Sprint(sibling.Interface()))
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type inner struct {
+	Port int `json:"port" validate:"min=1,max=65535"`
+}
+
+type request struct {
+	Name     string   `json:"name" validate:"required,max=5"`
+	Kind     string   `json:"kind,omitempty" validate:"omitempty,oneof=git archive"`
+	Source   string   `json:"source" validate:"required_unless=Kind archive,url"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Note     string   `validate:"min=2"`
+	Inner    *inner   `json:"inner"`
+	internal string
+}
+
+func TestStruct(t *testing.T) {
+	valid := request{Name: "tool", Kind: "git", Source: "https://example.com/x.git", Note: "ok", Inner: &inner{Port: 80}}
+	assert.Empty(t, Struct(&valid))
+	assert.Empty(t, Struct(request{Name: "tool", Kind: "archive"}), "required_unless and zero values of other rules")
+
+	errs := Struct(&request{
+		Name:   "  ",
+		Kind:   "svn",
+		Source: "example.com/x.git",
+		Tags:   []string{"a", "b", "c"},
+		Note:   "x",
+		Inner:  &inner{Port: 70000},
+	})
+	assert.Equal(t, Errors{
+		{Field: "name", Message: "is required"},
+		{Field: "kind", Message: "must be one of git, archive"},
+		{Field: "source", Message: "must be an absolute URL"},
+		{Field: "tags", Message: "must be at most 2 entries"},
+		{Field: "Note", Message: "must be at least 2 characters"},
+		{Field: "inner.port", Message: "must be at most 65535"},
+	}, errs)
+	assert.Contains(t, errs.Error(), "name: is required")
+
+	errs = Struct(&request{Name: "toolong", Inner: &inner{}})
+	assert.True(t, errs.Has("source"), "required unless kind is archive")
+	assert.True(t, errs.Has("name"))
+	assert.False(t, errs.Has("inner.port"), "zero values only fail required rules")
+}
+
+func TestErrors_Add(t *testing.T) {
+	var errs Errors
+	errs.Check("a", nil)
+	assert.Empty(t, errs)
+	errs.Add("b", "must be %d", 1)
+	assert.Equal(t, Errors{{Field: "b", Message: "must be 1"}}, errs)
+}
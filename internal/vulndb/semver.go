@@ -0,0 +1,86 @@
+// internal/vulndb/semver.go
+package vulndb
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareSemver orders two semantic versions without a leading "v", returning
+// -1, 0 or +1. Build metadata is ignored and prereleases sort before the
+// release, as in the semver spec; this covers Go pseudo-versions too.
+func compareSemver(a, b string) int {
+	a, _, _ = strings.Cut(a, "+")
+	b, _, _ = strings.Cut(b, "+")
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+
+	aParts := strings.Split(aCore, ".")
+	bParts := strings.Split(bCore, ".")
+	for i := 0; i < 3; i++ {
+		if c := compareNumeric(part(aParts, i), part(bParts, i)); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+
+	aIDs := strings.Split(aPre, ".")
+	bIDs := strings.Split(bPre, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := compareIdentifier(aIDs[i], bIDs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(aIDs), len(bIDs))
+}
+
+func part(parts []string, i int) string {
+	if i < len(parts) {
+		return parts[i]
+	}
+	return "0"
+}
+
+// compareIdentifier orders prerelease identifiers: numeric ones numerically
+// and before alphanumeric ones, which compare lexically
+func compareIdentifier(a, b string) int {
+	_, aErr := strconv.ParseUint(a, 10, 64)
+	_, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareNumeric(a, b)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareNumeric compares decimal strings of any length
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if c := compareInt(len(a), len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
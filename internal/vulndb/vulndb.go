@@ -0,0 +1,280 @@
+// internal/vulndb/vulndb.go
+package vulndb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go_runner/internal/models"
+)
+
+// stdlibModule is the module path the database uses for the standard library
+const stdlibModule = "stdlib"
+
+var idPattern = regexp.MustCompile(`^GO-\d{4}-\d{4,}$`)
+
+// DB is a local copy of the Go vulnerability database in the v1 layout served
+// by vuln.go.dev: index/db.json, index/modules.json and ID/<id>.json. It can be
+// populated by unpacking https://vuln.go.dev/vulndb.zip and is reloaded when
+// the index changes on disk, so it may be re-synced while the server runs.
+type DB struct {
+	dir string
+
+	mu       sync.Mutex
+	loadedAt time.Time // mtime of the module index that was loaded
+	modified time.Time
+	modules  map[string][]string // module path -> vulnerability IDs
+	entries  map[string]*entry
+}
+
+type dbMeta struct {
+	Modified time.Time `json:"modified"`
+}
+
+type moduleIndex struct {
+	Path  string `json:"path"`
+	Vulns []struct {
+		ID string `json:"id"`
+	} `json:"vulns"`
+}
+
+// entry is the subset of an OSV record needed to match module versions
+type entry struct {
+	ID        string     `json:"id"`
+	Summary   string     `json:"summary"`
+	Aliases   []string   `json:"aliases"`
+	Withdrawn *time.Time `json:"withdrawn"`
+	Affected  []struct {
+		Package struct {
+			Name string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced string `json:"introduced"`
+				Fixed      string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+}
+
+// Open loads the database index from dir
+func Open(dir string) (*DB, error) {
+	db := &DB{dir: dir}
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Modified returns when the loaded copy of the database was last updated upstream
+func (db *DB) Modified() time.Time {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.modified
+}
+
+// load reads the module index, unless it is unchanged since the last load
+func (db *DB) load() error {
+	indexPath := filepath.Join(db.dir, "index", "modules.json")
+	info, err := os.Stat(indexPath)
+	if err != nil {
+		return fmt.Errorf("vulnerability database: %w", err)
+	}
+	if info.ModTime().Equal(db.loadedAt) {
+		return nil
+	}
+
+	var index []moduleIndex
+	if err := readJSON(indexPath, &index); err != nil {
+		return err
+	}
+	var meta dbMeta
+	if err := readJSON(filepath.Join(db.dir, "index", "db.json"), &meta); err != nil {
+		return err
+	}
+
+	modules := make(map[string][]string, len(index))
+	for _, m := range index {
+		for _, v := range m.Vulns {
+			modules[m.Path] = append(modules[m.Path], v.ID)
+		}
+	}
+	db.modules = modules
+	db.entries = make(map[string]*entry)
+	db.modified = meta.Modified
+	db.loadedAt = info.ModTime()
+	return nil
+}
+
+// Check reports the known vulnerabilities of every module linked into an
+// executable, including the standard library. Matching is by module version;
+// whether the vulnerable symbols are actually reachable is not analysed.
+func (db *DB) Check(info *debug.BuildInfo) (*models.VulnReport, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+
+	report := &models.VulnReport{
+		DatabaseModified: db.modified,
+		ScannedAt:        time.Now(),
+		Vulnerabilities:  []models.Vulnerability{},
+	}
+
+	if v := stdlibVersion(info.GoVersion); v != "" {
+		if err := db.match(report, stdlibModule, v); err != nil {
+			return nil, err
+		}
+	}
+	for _, dep := range info.Deps {
+		mod := dep
+		if dep.Replace != nil {
+			mod = dep.Replace
+		}
+		if mod.Version == "" {
+			// Directory replacements cannot be matched against versions
+			continue
+		}
+		if err := db.match(report, mod.Path, mod.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(report.Vulnerabilities, func(i, j int) bool {
+		return report.Vulnerabilities[i].ID < report.Vulnerabilities[j].ID
+	})
+	return report, nil
+}
+
+// match appends the vulnerabilities affecting a module version to report
+func (db *DB) match(report *models.VulnReport, module, version string) error {
+	v := strings.TrimPrefix(version, "v")
+	for _, id := range db.modules[module] {
+		e, err := db.entry(id)
+		if err != nil {
+			return err
+		}
+		if e.Withdrawn != nil {
+			continue
+		}
+		affected, fixed := e.affects(module, v)
+		if !affected {
+			continue
+		}
+		vuln := models.Vulnerability{
+			ID:      e.ID,
+			Aliases: e.Aliases,
+			Summary: e.Summary,
+			Module:  module,
+			Version: version,
+		}
+		if fixed != "" {
+			vuln.Fixed = fixed
+			if module != stdlibModule {
+				vuln.Fixed = "v" + fixed
+			}
+		}
+		report.Vulnerabilities = append(report.Vulnerabilities, vuln)
+	}
+	return nil
+}
+
+// entry loads and caches a single OSV record
+func (db *DB) entry(id string) (*entry, error) {
+	if e, ok := db.entries[id]; ok {
+		return e, nil
+	}
+	// IDs come from the index on disk; do not let them name other files
+	if !idPattern.MatchString(id) {
+		return nil, fmt.Errorf("vulnerability database: invalid id %q", id)
+	}
+	var e entry
+	if err := readJSON(filepath.Join(db.dir, "ID", id+".json"), &e); err != nil {
+		return nil, err
+	}
+	db.entries[id] = &e
+	return &e, nil
+}
+
+// affects reports whether version v of module falls in an affected range, and
+// the version that fixes it
+func (e *entry) affects(module, v string) (bool, string) {
+	for _, a := range e.Affected {
+		if a.Package.Name != module {
+			continue
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			// Events are ordered by version; the last one at or below v decides
+			affected, fixed := false, ""
+			for _, ev := range r.Events {
+				switch {
+				case ev.Introduced != "":
+					if ev.Introduced == "0" || compareSemver(v, ev.Introduced) >= 0 {
+						affected = true
+					}
+				case ev.Fixed != "":
+					if compareSemver(v, ev.Fixed) >= 0 {
+						affected = false
+					} else if affected && fixed == "" {
+						fixed = ev.Fixed
+					}
+				}
+			}
+			if affected {
+				return true, fixed
+			}
+		}
+	}
+	return false, ""
+}
+
+// stdlibVersion converts a Go release such as go1.22rc1 to the semver form
+// used by the database (1.22.0-rc.1). Development builds are not matched.
+func stdlibVersion(goVersion string) string {
+	v, _, _ := strings.Cut(goVersion, " ")
+	v, ok := strings.CutPrefix(v, "go")
+	if !ok || v == "" {
+		return ""
+	}
+
+	var pre string
+	for _, tag := range []string{"rc", "beta"} {
+		if i := strings.Index(v, tag); i > 0 {
+			v, pre = v[:i], "-"+tag+"."+v[i+len(tag):]
+			break
+		}
+	}
+	switch strings.Count(v, ".") {
+	case 1:
+		v += ".0"
+	case 2:
+	default:
+		return ""
+	}
+	return v + pre
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("vulnerability database: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("vulnerability database: %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
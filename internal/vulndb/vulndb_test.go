@@ -0,0 +1,163 @@
+package vulndb
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+}
+
+func osv(id, module string, events ...map[string]string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      id,
+		"summary": "Problem in " + module,
+		"affected": []interface{}{map[string]interface{}{
+			"package": map[string]string{"name": module, "ecosystem": "Go"},
+			"ranges":  []interface{}{map[string]interface{}{"type": "SEMVER", "events": events}},
+		}},
+	}
+}
+
+// newTestDB writes a small database with one stdlib and two module entries
+func newTestDB(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeJSON(t, filepath.Join(dir, "index", "db.json"), map[string]string{"modified": "2024-05-01T00:00:00Z"})
+	writeJSON(t, filepath.Join(dir, "index", "modules.json"), []interface{}{
+		map[string]interface{}{"path": "stdlib", "vulns": []interface{}{map[string]string{"id": "GO-2024-0001"}}},
+		map[string]interface{}{"path": "example.com/lib", "vulns": []interface{}{
+			map[string]string{"id": "GO-2024-0002"},
+			map[string]string{"id": "GO-2024-0003"},
+		}},
+	})
+	writeJSON(t, filepath.Join(dir, "ID", "GO-2024-0001.json"), osv("GO-2024-0001", "stdlib",
+		map[string]string{"introduced": "0"}, map[string]string{"fixed": "1.21.9"},
+		map[string]string{"introduced": "1.22.0-0"}, map[string]string{"fixed": "1.22.2"}))
+	writeJSON(t, filepath.Join(dir, "ID", "GO-2024-0002.json"), osv("GO-2024-0002", "example.com/lib",
+		map[string]string{"introduced": "1.1.0"}, map[string]string{"fixed": "1.4.1"}))
+	withdrawn := osv("GO-2024-0003", "example.com/lib", map[string]string{"introduced": "0"})
+	withdrawn["withdrawn"] = "2024-04-01T00:00:00Z"
+	writeJSON(t, filepath.Join(dir, "ID", "GO-2024-0003.json"), withdrawn)
+	return dir
+}
+
+func TestCheck(t *testing.T) {
+	db, err := Open(newTestDB(t))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), db.Modified())
+
+	cases := []struct {
+		name      string
+		goVersion string
+		lib       string
+		want      []string
+	}{
+		{"patched", "go1.22.2", "v1.4.1", nil},
+		{"stdlib", "go1.22.1", "v1.0.0", []string{"GO-2024-0001"}},
+		{"stdlib prerelease", "go1.22rc1", "v1.0.0", []string{"GO-2024-0001"}},
+		{"between ranges", "go1.21.10", "v1.0.0", nil},
+		{"module", "go1.22.2", "v1.2.0", []string{"GO-2024-0002"}},
+		{"pseudo-version", "go1.22.2", "v1.4.1-0.20240101000000-abcdefabcdef", []string{"GO-2024-0002"}},
+		{"both", "go1.20", "v1.1.0", []string{"GO-2024-0001", "GO-2024-0002"}},
+		{"devel toolchain", "devel go1.23-abcdef", "v1.0.0", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := &debug.BuildInfo{
+				GoVersion: tc.goVersion,
+				Deps:      []*debug.Module{{Path: "example.com/lib", Version: tc.lib}},
+			}
+			report, err := db.Check(info)
+			require.NoError(t, err)
+
+			var got []string
+			for _, v := range report.Vulnerabilities {
+				got = append(got, v.ID)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCheck_FixedVersionAndReplace(t *testing.T) {
+	db, err := Open(newTestDB(t))
+	require.NoError(t, err)
+
+	info := &debug.BuildInfo{
+		GoVersion: "go1.22.2",
+		Deps: []*debug.Module{
+			// The replacement is what gets linked
+			{Path: "example.com/fork", Version: "v9.0.0", Replace: &debug.Module{Path: "example.com/lib", Version: "v1.3.0"}},
+			{Path: "example.com/lib", Version: "v1.2.0", Replace: &debug.Module{Path: "../lib"}},
+		},
+	}
+	report, err := db.Check(info)
+	require.NoError(t, err)
+	require.Len(t, report.Vulnerabilities, 1)
+	assert.Equal(t, "example.com/lib", report.Vulnerabilities[0].Module)
+	assert.Equal(t, "v1.3.0", report.Vulnerabilities[0].Version)
+	assert.Equal(t, "v1.4.1", report.Vulnerabilities[0].Fixed)
+}
+
+func TestCheck_ReloadsIndex(t *testing.T) {
+	dir := newTestDB(t)
+	db, err := Open(dir)
+	require.NoError(t, err)
+
+	info := &debug.BuildInfo{GoVersion: "go1.22.2", Deps: []*debug.Module{{Path: "example.com/other", Version: "v0.1.0"}}}
+	report, err := db.Check(info)
+	require.NoError(t, err)
+	assert.Empty(t, report.Vulnerabilities)
+
+	// A re-sync adds an entry for the module
+	writeJSON(t, filepath.Join(dir, "ID", "GO-2024-0004.json"), osv("GO-2024-0004", "example.com/other", map[string]string{"introduced": "0"}))
+	index := filepath.Join(dir, "index", "modules.json")
+	writeJSON(t, index, []interface{}{
+		map[string]interface{}{"path": "example.com/other", "vulns": []interface{}{map[string]string{"id": "GO-2024-0004"}}},
+	})
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(index, later, later))
+
+	report, err = db.Check(info)
+	require.NoError(t, err)
+	require.Len(t, report.Vulnerabilities, 1)
+	assert.Equal(t, "GO-2024-0004", report.Vulnerabilities[0].ID)
+	assert.Empty(t, report.Vulnerabilities[0].Fixed)
+}
+
+func TestOpen_Missing(t *testing.T) {
+	_, err := Open(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestCompareSemver(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-alpha", "1.0.0-1", 1},
+		{"1.0.0-0.20240101000000-abcdef", "1.0.0", -1},
+		{"1.0.0+build", "1.0.0", 0},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, compareSemver(tc.a, tc.b), "%s vs %s", tc.a, tc.b)
+	}
+}
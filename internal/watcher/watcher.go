@@ -0,0 +1,251 @@
+// internal/watcher/watcher.go
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+)
+
+// tickInterval is how often the watcher looks for binaries that are due a poll
+const tickInterval = 5 * time.Second
+
+// maxDoublings bounds the backoff when no MaxBackoff is configured
+const maxDoublings = 10
+
+// jitter spreads polls of binaries with the same interval by up to ±10%
+const jitter = 0.1
+
+// Store lists the binaries to watch
+type Store interface {
+	ListBinaries() ([]*models.Binary, error)
+}
+
+// Remote resolves the commit a remote branch points to
+type Remote interface {
+	RemoteHead(ctx context.Context, repoURL, branch string, cred *models.Credential) (string, error)
+}
+
+// Credentials looks up the credential a binary clones with
+type Credentials interface {
+	Get(id string) (*models.Credential, error)
+}
+
+// EnqueueFunc starts a build of the binary
+type EnqueueFunc func(binary *models.Binary) bool
+
+// Watcher polls the upstream branch of every binary with auto_build enabled and
+// enqueues a build when it no longer matches the built commit
+type Watcher struct {
+	cfg     config.WatcherConfig
+	store   Store
+	remote  Remote
+	creds   Credentials
+	enqueue EnqueueFunc
+
+	mu    sync.Mutex
+	state map[string]*pollState
+	sem   chan struct{}
+}
+
+// pollState tracks when a binary is next polled
+type pollState struct {
+	next     time.Time
+	failures int
+	polling  bool
+}
+
+// NewWatcher creates a watcher; creds may be nil when no credential store is configured
+func NewWatcher(cfg config.WatcherConfig, store Store, remote Remote, creds Credentials, enqueue EnqueueFunc) *Watcher {
+	if cfg.MaxConcurrent < 1 {
+		cfg.MaxConcurrent = 1
+	}
+	return &Watcher{
+		cfg:     cfg,
+		store:   store,
+		remote:  remote,
+		creds:   creds,
+		enqueue: enqueue,
+		state:   make(map[string]*pollState),
+		sem:     make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// Run polls until ctx is canceled
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		w.Poll(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks every binary that is due at now, at most MaxConcurrent at a
+// time, and returns once those checks have finished
+func (w *Watcher) Poll(ctx context.Context, now time.Time) {
+	binaries, err := w.store.ListBinaries()
+	if err != nil {
+		slog.Error("Watcher failed to list binaries", slog.String("error", err.Error()))
+		return
+	}
+
+	var wg sync.WaitGroup
+	seen := make(map[string]bool, len(binaries))
+	for _, binary := range binaries {
+		if binary.AutoBuild == nil || !binary.AutoBuild.Enabled || !binary.IsGit() {
+			continue
+		}
+		seen[binary.ID] = true
+
+		st := w.due(binary, now)
+		if st == nil {
+			continue
+		}
+
+		select {
+		case w.sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(binary *models.Binary) {
+			defer wg.Done()
+			defer func() { <-w.sem }()
+			w.check(ctx, binary, st, now)
+		}(binary)
+	}
+	wg.Wait()
+
+	// Forget binaries that were deleted or had auto_build turned off
+	w.mu.Lock()
+	for id := range w.state {
+		if !seen[id] {
+			delete(w.state, id)
+		}
+	}
+	w.mu.Unlock()
+}
+
+// due returns the poll state of binary if it should be polled at now
+func (w *Watcher) due(binary *models.Binary, now time.Time) *pollState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	st, ok := w.state[binary.ID]
+	if !ok {
+		// Spread the first poll of each binary over one interval
+		st = &pollState{next: now.Add(time.Duration(rand.Int64N(int64(w.interval(binary)) + 1)))}
+		w.state[binary.ID] = st
+	}
+	if st.polling || now.Before(st.next) || binary.Status == "building" {
+		return nil
+	}
+	st.polling = true
+	return st
+}
+
+// check compares the remote branch with the built commit and schedules the next poll
+func (w *Watcher) check(ctx context.Context, binary *models.Binary, st *pollState, now time.Time) {
+	head, err := w.remoteHead(ctx, binary)
+
+	w.mu.Lock()
+	st.polling = false
+	if err != nil {
+		st.failures++
+		st.next = now.Add(w.backoff(binary, st.failures))
+	} else {
+		st.failures = 0
+		st.next = now.Add(w.jittered(w.interval(binary)))
+	}
+	failures, next := st.failures, st.next
+	w.mu.Unlock()
+
+	if err != nil {
+		slog.Warn("Auto-build poll failed",
+			slog.String("id", binary.ID),
+			slog.Int("failures", failures),
+			slog.Time("next_poll", next),
+			slog.String("error", err.Error()))
+		return
+	}
+
+	if head == binary.Commit {
+		return
+	}
+	// Do not keep rebuilding a commit whose build failed
+	if binary.LastBuild != nil && binary.LastBuild.Commit == head {
+		return
+	}
+
+	slog.Info("Upstream branch moved, rebuilding",
+		slog.String("id", binary.ID),
+		slog.String("branch", binary.Branch),
+		slog.String("commit", head))
+	w.enqueue(binary)
+}
+
+func (w *Watcher) remoteHead(ctx context.Context, binary *models.Binary) (string, error) {
+	var cred *models.Credential
+	if binary.CredentialID != "" && w.creds != nil {
+		c, err := w.creds.Get(binary.CredentialID)
+		if err != nil {
+			return "", err
+		}
+		cred = c
+	}
+
+	if w.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.cfg.Timeout)
+		defer cancel()
+	}
+	return w.remote.RemoteHead(ctx, binary.RepoURL, binary.Branch, cred)
+}
+
+// interval returns the poll interval of binary, clamped to MinInterval
+func (w *Watcher) interval(binary *models.Binary) time.Duration {
+	d := w.cfg.DefaultInterval
+	if binary.AutoBuild.Interval > 0 {
+		d = time.Duration(binary.AutoBuild.Interval) * time.Second
+	}
+	if d < w.cfg.MinInterval {
+		d = w.cfg.MinInterval
+	}
+	if d <= 0 {
+		d = tickInterval
+	}
+	return d
+}
+
+// backoff doubles the interval for each consecutive failure, up to MaxBackoff
+func (w *Watcher) backoff(binary *models.Binary, failures int) time.Duration {
+	d := w.interval(binary)
+	for i := 0; i < failures && i < maxDoublings; i++ {
+		d *= 2
+		if w.cfg.MaxBackoff > 0 && d >= w.cfg.MaxBackoff {
+			d = w.cfg.MaxBackoff
+			break
+		}
+	}
+	return w.jittered(d)
+}
+
+func (w *Watcher) jittered(d time.Duration) time.Duration {
+	spread := int64(float64(d) * jitter)
+	if spread <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Int64N(2*spread+1)-spread)
+}
@@ -0,0 +1,169 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go_runner/internal/config"
+	"go_runner/internal/models"
+)
+
+type fakeStore []*models.Binary
+
+func (s fakeStore) ListBinaries() ([]*models.Binary, error) {
+	return s, nil
+}
+
+type fakeRemote struct {
+	head string
+	err  error
+
+	delay   time.Duration
+	calls   atomic.Int32
+	active  atomic.Int32
+	maxSeen atomic.Int32
+}
+
+func (r *fakeRemote) RemoteHead(ctx context.Context, repoURL, branch string, cred *models.Credential) (string, error) {
+	r.calls.Add(1)
+	n := r.active.Add(1)
+	defer r.active.Add(-1)
+	for {
+		max := r.maxSeen.Load()
+		if n <= max || r.maxSeen.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	time.Sleep(r.delay)
+	return r.head, r.err
+}
+
+type enqueued struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (e *enqueued) enqueue(binary *models.Binary) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ids = append(e.ids, binary.ID)
+	return true
+}
+
+var testConfig = config.WatcherConfig{
+	DefaultInterval: time.Minute,
+	MinInterval:     time.Second,
+	MaxBackoff:      10 * time.Minute,
+	MaxConcurrent:   2,
+}
+
+func autoBinary(id, commit string) *models.Binary {
+	return &models.Binary{
+		ID:        id,
+		RepoURL:   "https://example.com/" + id + ".git",
+		Branch:    "main",
+		Commit:    commit,
+		AutoBuild: &models.AutoBuild{Enabled: true},
+	}
+}
+
+// pollTwice registers the binaries and then polls once every first poll is due
+func pollTwice(w *Watcher, now time.Time) time.Time {
+	w.Poll(context.Background(), now)
+	later := now.Add(testConfig.DefaultInterval + time.Second)
+	w.Poll(context.Background(), later)
+	return later
+}
+
+func TestPoll_EnqueuesWhenBranchMoves(t *testing.T) {
+	moved := autoBinary("moved", "aaaa")
+	current := autoBinary("current", "bbbb")
+	failed := autoBinary("failed", "aaaa")
+	failed.LastBuild = &models.BuildRecord{Commit: "bbbb", Status: "failed"}
+	disabled := autoBinary("disabled", "aaaa")
+	disabled.AutoBuild.Enabled = false
+
+	remote := &fakeRemote{head: "bbbb"}
+	var builds enqueued
+	w := NewWatcher(testConfig, fakeStore{moved, current, failed, disabled}, remote, nil, builds.enqueue)
+
+	pollTwice(w, time.Now())
+
+	assert.Equal(t, []string{"moved"}, builds.ids)
+	assert.Equal(t, int32(3), remote.calls.Load())
+}
+
+func TestPoll_BacksOffOnFailure(t *testing.T) {
+	binary := autoBinary("flaky", "aaaa")
+	remote := &fakeRemote{err: errors.New("connection refused")}
+	var builds enqueued
+	w := NewWatcher(testConfig, fakeStore{binary}, remote, nil, builds.enqueue)
+
+	now := pollTwice(w, time.Now())
+	for failures := 1; failures <= 5; failures++ {
+		st := w.state[binary.ID]
+		require.Equal(t, failures, st.failures)
+
+		want := testConfig.DefaultInterval << failures
+		if want > testConfig.MaxBackoff {
+			want = testConfig.MaxBackoff
+		}
+		delay := st.next.Sub(now)
+		assert.InDelta(t, float64(want), float64(delay), float64(want)*jitter, "after %d failures", failures)
+
+		// Nothing happens before the backoff has elapsed
+		w.Poll(context.Background(), st.next.Add(-time.Second))
+		assert.Equal(t, failures, w.state[binary.ID].failures)
+
+		now = st.next
+		w.Poll(context.Background(), now)
+	}
+
+	remote.err = nil
+	remote.head = "bbbb"
+	w.Poll(context.Background(), w.state[binary.ID].next)
+	assert.Equal(t, 0, w.state[binary.ID].failures)
+	assert.Equal(t, []string{"flaky"}, builds.ids)
+}
+
+func TestPoll_ConcurrencyCap(t *testing.T) {
+	var store fakeStore
+	for i := 0; i < 10; i++ {
+		store = append(store, autoBinary(fmt.Sprintf("bin-%d", i), "aaaa"))
+	}
+	remote := &fakeRemote{head: "aaaa", delay: 20 * time.Millisecond}
+	var builds enqueued
+	w := NewWatcher(testConfig, store, remote, nil, builds.enqueue)
+
+	pollTwice(w, time.Now())
+
+	assert.Equal(t, int32(10), remote.calls.Load())
+	assert.LessOrEqual(t, remote.maxSeen.Load(), int32(testConfig.MaxConcurrent))
+	assert.Empty(t, builds.ids)
+}
+
+func TestPoll_SpreadsFirstPolls(t *testing.T) {
+	var store fakeStore
+	for i := 0; i < 50; i++ {
+		store = append(store, autoBinary(fmt.Sprintf("bin-%d", i), "aaaa"))
+	}
+	w := NewWatcher(testConfig, store, &fakeRemote{head: "aaaa"}, nil, (&enqueued{}).enqueue)
+
+	now := time.Now()
+	w.Poll(context.Background(), now)
+
+	distinct := make(map[time.Time]bool)
+	for _, st := range w.state {
+		assert.False(t, st.next.Before(now))
+		assert.False(t, st.next.After(now.Add(testConfig.DefaultInterval)))
+		distinct[st.next] = true
+	}
+	assert.Greater(t, len(distinct), 1)
+}
@@ -0,0 +1,230 @@
+// internal/webhook/webhook.go
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Supported providers
+const (
+	ProviderGitHub  = "github"
+	ProviderGitLab  = "gitlab"
+	ProviderGitea   = "gitea"
+	ProviderGeneric = "generic"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("unknown webhook provider")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrIgnoredEvent     = errors.New("event does not trigger builds")
+	ErrInvalidPayload   = errors.New("invalid webhook payload")
+)
+
+// zeroCommit is sent as the new commit when a branch is deleted
+const zeroCommit = "0000000000000000000000000000000000000000"
+
+// Push is a branch update reported by a provider
+type Push struct {
+	Provider string
+	RepoURLs []string // every URL the provider reports for the repository
+	Branch   string
+	Commit   string
+}
+
+// Parse verifies the signature of a webhook request and extracts the push it
+// describes. Events other than branch pushes return ErrIgnoredEvent.
+func Parse(provider string, header http.Header, body []byte, secret string) (*Push, error) {
+	switch provider {
+	case ProviderGitHub:
+		if err := verifyHMAC(body, secret, strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")); err != nil {
+			return nil, err
+		}
+		if event := header.Get("X-GitHub-Event"); event != "push" {
+			return nil, fmt.Errorf("%w: %s", ErrIgnoredEvent, event)
+		}
+		return parseGitHubStyle(provider, body)
+
+	case ProviderGitea:
+		if err := verifyHMAC(body, secret, header.Get("X-Gitea-Signature")); err != nil {
+			return nil, err
+		}
+		if event := header.Get("X-Gitea-Event"); event != "push" {
+			return nil, fmt.Errorf("%w: %s", ErrIgnoredEvent, event)
+		}
+		return parseGitHubStyle(provider, body)
+
+	case ProviderGitLab:
+		// GitLab does not sign payloads; it sends the shared secret as a token
+		token := header.Get("X-Gitlab-Token")
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return nil, ErrInvalidSignature
+		}
+		if event := header.Get("X-Gitlab-Event"); event != "Push Hook" {
+			return nil, fmt.Errorf("%w: %s", ErrIgnoredEvent, event)
+		}
+		return parseGitLab(body)
+
+	case ProviderGeneric:
+		if err := verifyHMAC(body, secret, strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")); err != nil {
+			return nil, err
+		}
+		return parseGeneric(body)
+
+	default:
+		return nil, ErrUnknownProvider
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of body, as sent by GitHub, Gitea and generic senders
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyHMAC(body []byte, secret, signature string) error {
+	if secret == "" || signature == "" {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	want, _ := hex.DecodeString(Sign(body, secret))
+	if !hmac.Equal(got, want) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// parseGitHubStyle handles GitHub and Gitea push payloads, which share a layout
+func parseGitHubStyle(provider string, body []byte) (*Push, error) {
+	var payload struct {
+		Ref        string `json:"ref"`
+		After      string `json:"after"`
+		Deleted    bool   `json:"deleted"`
+		Repository struct {
+			CloneURL string `json:"clone_url"`
+			SSHURL   string `json:"ssh_url"`
+			HTMLURL  string `json:"html_url"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if payload.Deleted {
+		return nil, fmt.Errorf("%w: branch deleted", ErrIgnoredEvent)
+	}
+	r := payload.Repository
+	return newPush(provider, payload.Ref, payload.After, r.CloneURL, r.SSHURL, r.HTMLURL)
+}
+
+func parseGitLab(body []byte) (*Push, error) {
+	var payload struct {
+		Ref     string `json:"ref"`
+		After   string `json:"after"`
+		Project struct {
+			HTTPURL string `json:"git_http_url"`
+			SSHURL  string `json:"git_ssh_url"`
+			WebURL  string `json:"web_url"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	p := payload.Project
+	return newPush(ProviderGitLab, payload.Ref, payload.After, p.HTTPURL, p.SSHURL, p.WebURL)
+}
+
+// parseGeneric handles {"repo_url": "...", "ref": "refs/heads/main", "commit": "..."};
+// "branch" may be given instead of "ref"
+func parseGeneric(body []byte) (*Push, error) {
+	var payload struct {
+		RepoURL string `json:"repo_url"`
+		Ref     string `json:"ref"`
+		Branch  string `json:"branch"`
+		Commit  string `json:"commit"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	ref := payload.Ref
+	if ref == "" && payload.Branch != "" {
+		ref = "refs/heads/" + payload.Branch
+	}
+	return newPush(ProviderGeneric, ref, payload.Commit, payload.RepoURL)
+}
+
+func newPush(provider, ref, commit string, urls ...string) (*Push, error) {
+	branch, ok := strings.CutPrefix(ref, "refs/heads/")
+	if !ok || branch == "" {
+		return nil, fmt.Errorf("%w: ref %q is not a branch", ErrIgnoredEvent, ref)
+	}
+	if commit == zeroCommit {
+		return nil, fmt.Errorf("%w: branch deleted", ErrIgnoredEvent)
+	}
+
+	push := &Push{Provider: provider, Branch: branch, Commit: commit}
+	for _, u := range urls {
+		if u != "" {
+			push.RepoURLs = append(push.RepoURLs, u)
+		}
+	}
+	if len(push.RepoURLs) == 0 {
+		return nil, fmt.Errorf("%w: no repository URL", ErrInvalidPayload)
+	}
+	return push, nil
+}
+
+// Matches reports whether the push updates branch of repoURL
+func (p *Push) Matches(repoURL, branch string) bool {
+	if p.Branch != branch {
+		return false
+	}
+	want := NormalizeRepoURL(repoURL)
+	for _, u := range p.RepoURLs {
+		if NormalizeRepoURL(u) == want {
+			return true
+		}
+	}
+	return false
+}
+
+var scpLikeURL = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// NormalizeRepoURL reduces the HTTPS, SSH and scp-like forms of a repository
+// URL to host/path, so that https://github.com/org/repo.git,
+// git@github.com:org/repo and ssh://git@github.com/org/repo compare equal.
+// Local paths and file:// URLs reduce to the cleaned path.
+func NormalizeRepoURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+
+	var host, p string
+	if u, err := url.Parse(raw); err == nil && u.Scheme != "" && u.Host != "" {
+		host, p = u.Hostname(), u.Path
+	} else if u != nil && u.Scheme == "file" {
+		p = u.Path
+	} else if m := scpLikeURL.FindStringSubmatch(raw); m != nil && !filepath.IsAbs(raw) {
+		host, p = m[1], m[2]
+	} else {
+		p = raw
+	}
+
+	p = strings.TrimSuffix(strings.TrimSuffix(p, "/"), ".git")
+	if host == "" {
+		return path.Clean(filepath.ToSlash(p))
+	}
+	return strings.ToLower(host) + "/" + strings.ToLower(strings.Trim(path.Clean("/"+p), "/"))
+}